@@ -0,0 +1,409 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Componentes de una dirección con las abreviaturas de USPS Publication 28.
+// Normalized los une en un texto canónico ("12 N MAIN ST APT 4B") que se usa
+// para buscar coincidencias exactas y duplicados; vacío si no hay dirección.
+type AddressParts struct {
+	HouseNumber   string `json:"house_number,omitempty"`
+	PreDirection  string `json:"pre_direction,omitempty"`
+	StreetName    string `json:"street_name,omitempty"`
+	StreetSuffix  string `json:"street_suffix,omitempty"`
+	PostDirection string `json:"post_direction,omitempty"`
+	UnitType      string `json:"unit_type,omitempty"`
+	Unit          string `json:"unit,omitempty"`
+	Normalized    string `json:"normalized"`
+}
+
+// Columnas de properties con los componentes normalizados, en el orden de addressValues
+const addressColumns = "address_number, address_pre_direction, address_street, address_suffix, address_post_direction, address_unit_type, address_unit, address_normalized"
+
+// Límites de la búsqueda exacta por dirección
+const maxAddressMatches = 100
+
+// Tipos de vía y sus variantes, con la abreviatura estándar
+var streetSuffixes = standardForms(map[string][]string{
+	"ALY":  {"ALLEY", "ALLEE", "ALLY"},
+	"AVE":  {"AVENUE", "AV", "AVEN", "AVENU", "AVN", "AVNUE"},
+	"BLVD": {"BOULEVARD", "BOUL", "BOULV"},
+	"BR":   {"BRANCH", "BRNCH"},
+	"BRK":  {"BROOK"},
+	"CIR":  {"CIRCLE", "CIRC", "CIRCL", "CRCL", "CRCLE"},
+	"CT":   {"COURT", "CRT"},
+	"CTR":  {"CENTER", "CENT", "CENTR", "CENTRE", "CNTER", "CNTR"},
+	"CV":   {"COVE"},
+	"CRES": {"CRESCENT", "CRSENT", "CRSNT"},
+	"DR":   {"DRIVE", "DRIV", "DRV"},
+	"EST":  {"ESTATE"},
+	"ESTS": {"ESTATES"},
+	"EXT":  {"EXTENSION", "EXTN", "EXTNSN"},
+	"GLN":  {"GLEN"},
+	"GRN":  {"GREEN"},
+	"GRV":  {"GROVE", "GROV"},
+	"HL":   {"HILL"},
+	"HTS":  {"HEIGHTS", "HT"},
+	"HOLW": {"HOLLOW", "HLLW", "HOLLOWS", "HOLWS"},
+	"HWY":  {"HIGHWAY", "HIGHWY", "HIWAY", "HIWY", "HWAY"},
+	"KNL":  {"KNOLL", "KNOL"},
+	"LN":   {"LANE"},
+	"LNDG": {"LANDING", "LNDNG"},
+	"LOOP": {"LOOPS"},
+	"MDW":  {"MEADOW"},
+	"MDWS": {"MEADOWS", "MEDOWS"},
+	"PATH": {"PATHS"},
+	"PIKE": {"PIKES"},
+	"PKWY": {"PARKWAY", "PARKWY", "PKWAY", "PKY"},
+	"PL":   {"PLACE"},
+	"PLZ":  {"PLAZA", "PLZA"},
+	"PT":   {"POINT"},
+	"RD":   {"ROAD"},
+	"RDG":  {"RIDGE", "RDGE"},
+	"ROW":  {},
+	"RUN":  {},
+	"SQ":   {"SQUARE", "SQR", "SQRE", "SQU"},
+	"ST":   {"STREET", "STR", "STRT"},
+	"TER":  {"TERRACE", "TERR"},
+	"TPKE": {"TURNPIKE", "TRNPK", "TURNPK"},
+	"TRL":  {"TRAIL", "TRAILS", "TRLS"},
+	"VW":   {"VIEW"},
+	"WALK": {"WALKS"},
+	"WAY":  {"WY"},
+	"XING": {"CROSSING", "CRSSNG"},
+})
+
+// Puntos cardinales
+var directions = standardForms(map[string][]string{
+	"N":  {"NORTH"},
+	"S":  {"SOUTH"},
+	"E":  {"EAST"},
+	"W":  {"WEST"},
+	"NE": {"NORTHEAST"},
+	"NW": {"NORTHWEST"},
+	"SE": {"SOUTHEAST"},
+	"SW": {"SOUTHWEST"},
+})
+
+// Designadores de unidad que llevan un identificador detrás
+var unitDesignators = standardForms(map[string][]string{
+	"APT":  {"APARTMENT"},
+	"BLDG": {"BUILDING"},
+	"FL":   {"FLOOR"},
+	"LOT":  {},
+	"RM":   {"ROOM"},
+	"SPC":  {"SPACE"},
+	"STE":  {"SUITE"},
+	"UNIT": {"UNT"},
+})
+
+// Designadores de unidad sin identificador, solo al final de la dirección
+var unitLocators = standardForms(map[string][]string{
+	"BSMT": {"BASEMENT"},
+	"FRNT": {"FRONT"},
+	"LOWR": {"LOWER"},
+	"PH":   {"PENTHOUSE"},
+	"REAR": {},
+	"UPPR": {"UPPER"},
+})
+
+// Nombres de calle ordinales escritos con letras
+var ordinalNames = map[string]string{
+	"FIRST": "1ST", "SECOND": "2ND", "THIRD": "3RD", "FOURTH": "4TH", "FIFTH": "5TH",
+	"SIXTH": "6TH", "SEVENTH": "7TH", "EIGHTH": "8TH", "NINTH": "9TH", "TENTH": "10TH",
+}
+
+// Número de portal: 12, 12A, 12-A, 12-14
+var houseNumberPattern = regexp.MustCompile(`^[0-9]+(-[0-9]+)?-?[A-Z]?$`)
+
+var fractionPattern = regexp.MustCompile(`^[0-9]+/[0-9]+$`)
+
+// Identificador de unidad: con algún dígito o una sola letra
+var unitIdentifierPattern = regexp.MustCompile(`^([A-Z0-9-]*[0-9][A-Z0-9-]*|[A-Z])$`)
+
+// Variante -> forma estándar, incluida la propia forma estándar
+func standardForms(variants map[string][]string) map[string]string {
+	forms := make(map[string]string)
+	for standard, names := range variants {
+		forms[standard] = standard
+		for _, name := range names {
+			forms[name] = standard
+		}
+	}
+	return forms
+}
+
+// Palabras de una dirección en mayúsculas, sin puntos ni comas y con '#' aparte
+func addressTokens(raw string) []string {
+	cleaned := strings.NewReplacer(".", "", ",", " ", ";", " ", "#", " # ").Replace(strings.ToUpper(raw))
+	return strings.Fields(cleaned)
+}
+
+// Descomponer y normalizar una dirección libre. Nunca falla: lo que no se
+// reconoce queda en el nombre de la calle.
+func normalizeAddress(raw string) AddressParts {
+	var parts AddressParts
+	tokens := addressTokens(raw)
+	if len(tokens) == 0 || (len(tokens) == 1 && tokens[0] == strings.ToUpper(missingValue)) {
+		return parts
+	}
+
+	// Unidad: desde el primer designador hasta el final ("APT 4B", "# 4", "UNIT # 2")
+	for i := 1; i < len(tokens); i++ {
+		token := tokens[i]
+		if locator, ok := unitLocators[token]; ok && i == len(tokens)-1 && i >= 2 {
+			parts.UnitType, tokens = locator, tokens[:i]
+			break
+		}
+		designator, ok := unitDesignators[token]
+		if token == "#" {
+			designator, ok = "UNIT", true
+		}
+		if !ok || (token != "#" && i < 2) {
+			continue
+		}
+		// "12 OLD LOT RD" no tiene unidad: el identificador lleva dígitos o es una letra
+		var unit []string
+		for _, t := range tokens[i+1:] {
+			if t != "#" {
+				unit = append(unit, t)
+			}
+		}
+		if slices.ContainsFunc(unit, func(t string) bool { return !unitIdentifierPattern.MatchString(t) }) {
+			continue
+		}
+		if len(unit) == 0 {
+			if token == "#" {
+				tokens = tokens[:i]
+				break
+			}
+			continue
+		}
+		parts.UnitType, parts.Unit, tokens = designator, strings.Join(unit, " "), tokens[:i]
+		break
+	}
+
+	// Número de portal, con fracción opcional ("12 1/2")
+	if len(tokens) > 1 && houseNumberPattern.MatchString(tokens[0]) {
+		number := tokens[0]
+		if digits := strings.TrimLeft(number, "0"); digits != "" && digits[0] >= '0' && digits[0] <= '9' {
+			number = digits
+		}
+		if n := len(number); n >= 2 && number[n-2] == '-' && number[n-1] >= 'A' {
+			number = number[:n-2] + number[n-1:]
+		}
+		parts.HouseNumber, tokens = number, tokens[1:]
+		if len(tokens) > 1 && fractionPattern.MatchString(tokens[0]) {
+			parts.HouseNumber += " " + tokens[0]
+			tokens = tokens[1:]
+		}
+	}
+
+	// Dirección posterior, tipo de vía y dirección anterior; siempre queda al
+	// menos una palabra como nombre ("NORTH ST" es la calle North)
+	if n := len(tokens); n >= 2 {
+		if direction, ok := directions[tokens[n-1]]; ok {
+			parts.PostDirection, tokens = direction, tokens[:n-1]
+		}
+	}
+	if n := len(tokens); n >= 2 {
+		if suffix, ok := streetSuffixes[tokens[n-1]]; ok {
+			parts.StreetSuffix, tokens = suffix, tokens[:n-1]
+		}
+	}
+	if len(tokens) >= 2 {
+		if direction, ok := directions[tokens[0]]; ok {
+			parts.PreDirection, tokens = direction, tokens[1:]
+		}
+	}
+	for i, token := range tokens {
+		if ordinal, ok := ordinalNames[token]; ok {
+			tokens[i] = ordinal
+		}
+	}
+	parts.StreetName = strings.Join(tokens, " ")
+
+	var normalized []string
+	for _, value := range []string{parts.HouseNumber, parts.PreDirection, parts.StreetName, parts.StreetSuffix, parts.PostDirection, parts.UnitType, parts.Unit} {
+		if value != "" {
+			normalized = append(normalized, value)
+		}
+	}
+	parts.Normalized = strings.Join(normalized, " ")
+	return parts
+}
+
+// NULL para los componentes vacíos
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// Valores de addressColumns para una dirección; sin dirección address_normalized
+// es el texto vacío, para distinguirla de las filas aún sin normalizar (NULL)
+func addressValues(address string) []interface{} {
+	parts := normalizeAddress(address)
+	return []interface{}{
+		nullIfEmpty(parts.HouseNumber), nullIfEmpty(parts.PreDirection), nullIfEmpty(parts.StreetName), nullIfEmpty(parts.StreetSuffix),
+		nullIfEmpty(parts.PostDirection), nullIfEmpty(parts.UnitType), nullIfEmpty(parts.Unit), parts.Normalized,
+	}
+}
+
+// Grupo de propiedades vigentes con la misma dirección normalizada en la misma ciudad
+type AddressDuplicate struct {
+	Town          string  `json:"town"`
+	Address       string  `json:"address"`
+	Count         int     `json:"count"`
+	SerialNumbers []int64 `json:"serial_numbers"`
+}
+
+// Resultado de GET /properties/lookup
+type AddressLookup struct {
+	Query   AddressParts `json:"query"`
+	Town    string       `json:"town,omitempty"`
+	Matches []Property   `json:"matches"`
+}
+
+// Leer town con el nombre canónico de la tabla de referencia; vacío si no se envió.
+// Si devuelve false ya se respondió con el error.
+func (app *App) townParam(c *gin.Context) (string, bool) {
+	town := strings.TrimSpace(c.Query("town"))
+	if town == "" {
+		return "", true
+	}
+	ref, err := app.properties.Reference(c.Request.Context())
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to load reference data")
+		return "", false
+	}
+	canonical, ok := lookupReference(canonicalNames(ref.Towns), town)
+	if !ok {
+		abortProblem(c, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("Unknown town %q", town))
+		return "", false
+	}
+	return canonical, true
+}
+
+// Propiedades cuya dirección normalizada coincide con la de address (y town si se indica)
+func (app *App) lookupPropertyAddress(c *gin.Context) {
+	address := strings.TrimSpace(c.Query("address"))
+	query := normalizeAddress(address)
+	if query.Normalized == "" || len(address) > maxSearchLength {
+		abortProblem(c, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("Parameter 'address' is required (max %d characters)", maxSearchLength))
+		return
+	}
+
+	town, ok := app.townParam(c)
+	if !ok {
+		return
+	}
+
+	matches, err := app.properties.LookupAddress(c.Request.Context(), query.Normalized, town, maxAddressMatches)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to look up address")
+		return
+	}
+	if matches == nil {
+		matches = []Property{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    AddressLookup{Query: query, Town: town, Matches: matches},
+	})
+}
+
+// Direcciones repetidas dentro de una ciudad (admin)
+func (app *App) getAddressDuplicates(c *gin.Context) {
+	page, limit := trashPage(c)
+	offset := (page - 1) * limit
+	town, ok := app.townParam(c)
+	if !ok {
+		return
+	}
+
+	duplicates, totalCount, err := app.properties.AddressDuplicates(c.Request.Context(), town, limit, offset)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to query duplicate addresses")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    duplicates,
+		"pagination": gin.H{
+			"current_page": page,
+			"total_pages":  (totalCount + limit - 1) / limit,
+			"total_count":  totalCount,
+			"limit":        limit,
+			"offset":       offset,
+		},
+	})
+}
+
+// Calcular los componentes de las filas sin normalizar, o de todas con all, por
+// lotes de batch filas. Las escrituras normalizan por sí mismas; esto completa
+// las filas anteriores a la migración 0011 o las recalcula si cambian las reglas.
+// Solo cambian columnas derivadas, así que no crea revisiones ni cambia versiones.
+func normalizeStoredAddresses(ctx context.Context, db *pgxpool.Pool, all bool, batch int) (int, error) {
+	condition := "address_normalized IS NULL AND serial_number > $1"
+	if all {
+		condition = "serial_number > $1"
+	}
+
+	total := 0
+	var last int64
+	for {
+		rows, err := db.Query(ctx, "SELECT serial_number, coalesce(address, '') FROM properties WHERE "+condition+" ORDER BY serial_number LIMIT $2", last, batch)
+		if err != nil {
+			return total, err
+		}
+		var ids []int64
+		var addresses []string
+		for rows.Next() {
+			var id int64
+			var address string
+			if err := rows.Scan(&id, &address); err != nil {
+				rows.Close()
+				return total, err
+			}
+			ids = append(ids, id)
+			addresses = append(addresses, address)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+
+		b := &pgx.Batch{}
+		for i, id := range ids {
+			b.Queue("UPDATE properties SET ("+addressColumns+") = ("+placeholders(addressColumns)+") WHERE serial_number = $9",
+				append(addressValues(addresses[i]), id)...)
+		}
+		if err := db.SendBatch(ctx, b).Close(); err != nil {
+			return total, err
+		}
+		total += len(ids)
+		last = ids[len(ids)-1]
+		log.Printf("Normalized %d addresses (up to serial number %d)", total, last)
+	}
+}
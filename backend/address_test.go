@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestNormalizeAddress(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want AddressParts
+	}{
+		{"61 Maple Avenue", AddressParts{HouseNumber: "61", StreetName: "MAPLE", StreetSuffix: "AVE", Normalized: "61 MAPLE AVE"}},
+		{"61  maple ave.", AddressParts{HouseNumber: "61", StreetName: "MAPLE", StreetSuffix: "AVE", Normalized: "61 MAPLE AVE"}},
+		{"12 North Main Street", AddressParts{HouseNumber: "12", PreDirection: "N", StreetName: "MAIN", StreetSuffix: "ST", Normalized: "12 N MAIN ST"}},
+		{"300 State St. West", AddressParts{HouseNumber: "300", StreetName: "STATE", StreetSuffix: "ST", PostDirection: "W", Normalized: "300 STATE ST W"}},
+		{"5 North St", AddressParts{HouseNumber: "5", StreetName: "NORTH", StreetSuffix: "ST", Normalized: "5 NORTH ST"}},
+		{"19 Hill St Apartment 4B", AddressParts{HouseNumber: "19", StreetName: "HILL", StreetSuffix: "ST", UnitType: "APT", Unit: "4B", Normalized: "19 HILL ST APT 4B"}},
+		{"19 HILL ST #4B", AddressParts{HouseNumber: "19", StreetName: "HILL", StreetSuffix: "ST", UnitType: "UNIT", Unit: "4B", Normalized: "19 HILL ST UNIT 4B"}},
+		{"19 hill st, unit # 4b", AddressParts{HouseNumber: "19", StreetName: "HILL", StreetSuffix: "ST", UnitType: "UNIT", Unit: "4B", Normalized: "19 HILL ST UNIT 4B"}},
+		{"88 Park Ave Suite 200", AddressParts{HouseNumber: "88", StreetName: "PARK", StreetSuffix: "AVE", UnitType: "STE", Unit: "200", Normalized: "88 PARK AVE STE 200"}},
+		{"7 Oak Rd Rear", AddressParts{HouseNumber: "7", StreetName: "OAK", StreetSuffix: "RD", UnitType: "REAR", Normalized: "7 OAK RD REAR"}},
+		{"12 Old Lot Road", AddressParts{HouseNumber: "12", StreetName: "OLD LOT", StreetSuffix: "RD", Normalized: "12 OLD LOT RD"}},
+		{"012-A First Street", AddressParts{HouseNumber: "12A", StreetName: "1ST", StreetSuffix: "ST", Normalized: "12A 1ST ST"}},
+		{"12-14 Elm St", AddressParts{HouseNumber: "12-14", StreetName: "ELM", StreetSuffix: "ST", Normalized: "12-14 ELM ST"}},
+		{"40 1/2 Elm St", AddressParts{HouseNumber: "40 1/2", StreetName: "ELM", StreetSuffix: "ST", Normalized: "40 1/2 ELM ST"}},
+		{"Route 6", AddressParts{StreetName: "ROUTE 6", Normalized: "ROUTE 6"}},
+		{"Nan", AddressParts{}},
+		{"  ", AddressParts{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			if got := normalizeAddress(tt.raw); got != tt.want {
+				t.Errorf("normalizeAddress(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
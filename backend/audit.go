@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Acciones registradas en la auditoría
+const (
+	auditCreate  = "create"
+	auditUpdate  = "update"
+	auditDelete  = "delete"
+	auditImport  = "import"
+	auditRestore = "restore"
+	auditPurge   = "purge"
+)
+
+// Entrada del registro de auditoría
+type AuditEntry struct {
+	ID            int64           `json:"id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	ActorID       *int            `json:"actor_id"`
+	ActorUsername *string         `json:"actor_username"`
+	Action        string          `json:"action"`
+	Entity        string          `json:"entity"`
+	EntityID      string          `json:"entity_id"`
+	Before        json.RawMessage `json:"before"`
+	After         json.RawMessage `json:"after"`
+	Diff          json.RawMessage `json:"diff"`
+	RequestID     string          `json:"request_id"`
+	ClientIP      string          `json:"client_ip"`
+}
+
+// Convertir un valor a mapa JSON para comparar campos
+func toJSONMap(value interface{}) (map[string]interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Diferencia campo a campo entre dos estados: {"campo": {"from": x, "to": y}}
+func auditDiff(before, after map[string]interface{}) map[string]interface{} {
+	diff := make(map[string]interface{})
+	for key, to := range after {
+		from, ok := before[key]
+		if !ok || !reflect.DeepEqual(from, to) {
+			diff[key] = gin.H{"from": from, "to": to}
+		}
+	}
+	for key, from := range before {
+		if _, ok := after[key]; !ok {
+			diff[key] = gin.H{"from": from, "to": nil}
+		}
+	}
+	return diff
+}
+
+// Registrar una mutación dentro de la misma transacción que la modifica
+func recordAudit(ctx context.Context, tx pgx.Tx, actor *Actor, action, entity string, entityID interface{}, before, after interface{}) error {
+	beforeMap, err := toJSONMap(before)
+	if err != nil {
+		return err
+	}
+	afterMap, err := toJSONMap(after)
+	if err != nil {
+		return err
+	}
+
+	// Los estados inexistentes se guardan como NULL
+	var beforeArg, afterArg interface{}
+	if beforeMap != nil {
+		beforeArg = beforeMap
+	}
+	if afterMap != nil {
+		afterArg = afterMap
+	}
+
+	// Sin actor (p. ej. tareas de consola) no hay usuario ni petición
+	var actorID *int
+	var actorUsername *string
+	var requestID, clientIP string
+	if actor != nil {
+		actorID = actor.UserID
+		if actor.Username != "" {
+			actorUsername = &actor.Username
+		}
+		requestID, clientIP = actor.RequestID, actor.ClientIP
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO audit_log (actor_id, actor_username, action, entity, entity_id, before, after, diff, request_id, client_ip)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, actorID, actorUsername, action, entity, fmt.Sprint(entityID), beforeArg, afterArg, auditDiff(beforeMap, afterMap), requestID, clientIP)
+	return err
+}
+
+// Parsear límite temporal como RFC3339 o fecha YYYY-MM-DD
+func parseTimeParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// Consultar el registro de auditoría (admin)
+func (app *App) getAuditLog(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+	offset := (page - 1) * limit
+
+	filter := AuditFilter{
+		Entity:   c.Query("entity"),
+		EntityID: c.Query("entity_id"),
+		Action:   c.Query("action"),
+	}
+	// Filtro por actor: id numérico o nombre de usuario
+	if actor := c.Query("actor"); actor != "" {
+		if id, err := strconv.Atoi(actor); err == nil {
+			filter.ActorID = &id
+		} else {
+			filter.ActorUsername = actor
+		}
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := parseTimeParam(from)
+		if err != nil {
+			abortProblem(c, http.StatusBadRequest, codeInvalidParameter, "Invalid 'from' time. Use RFC3339 or YYYY-MM-DD")
+			return
+		}
+		filter.From = &t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := parseTimeParam(to)
+		if err != nil {
+			abortProblem(c, http.StatusBadRequest, codeInvalidParameter, "Invalid 'to' time. Use RFC3339 or YYYY-MM-DD")
+			return
+		}
+		filter.To = &t
+	}
+
+	entries, totalCount, err := app.audit.List(c.Request.Context(), filter, limit, offset)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to query audit log")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    entries,
+		"pagination": gin.H{
+			"current_page": page,
+			"total_pages":  (totalCount + limit - 1) / limit,
+			"total_count":  totalCount,
+			"limit":        limit,
+			"offset":       offset,
+		},
+	})
+}
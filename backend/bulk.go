@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Límites de una escritura masiva
+const (
+	maxBulkBytes      = 32 << 20
+	maxBulkOperations = 10000
+	maxBulkRows       = 50000 // filas tocadas, incluidas las que seleccionan los filtros
+)
+
+// Modos de ejecución: todo o nada, o confirmar las operaciones que tienen éxito
+const (
+	bulkAtomic     = "atomic"
+	bulkBestEffort = "best_effort"
+)
+
+// Cuerpo de POST /admin/properties/bulk
+type BulkPropertyRequest struct {
+	Mode       string                  `json:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	Operations []BulkPropertyOperation `json:"operations" binding:"required,dive"`
+}
+
+// create lleva la propiedad en data; update un merge patch en data y id o filter; delete solo id
+type BulkPropertyOperation struct {
+	Op      string              `json:"op" binding:"required,oneof=create update delete"`
+	ID      int64               `json:"id,omitempty"`
+	Version int64               `json:"version,omitempty"` // como If-Match; solo con id
+	Filter  *BulkPropertyFilter `json:"filter,omitempty"`
+	Data    json.RawMessage     `json:"data,omitempty"`
+}
+
+// Filas de un update masivo; los criterios se combinan con AND
+type BulkPropertyFilter struct {
+	Towns           []string `json:"towns,omitempty"`
+	PropertyType    string   `json:"property_type,omitempty"`
+	ResidentialType string   `json:"residential_type,omitempty"`
+	ListYear        *int     `json:"list_year,omitempty"`
+	Status          string   `json:"status,omitempty" binding:"omitempty,oneof=sold available"`
+	MinPrice        *float64 `json:"min_price,omitempty"`
+	MaxPrice        *float64 `json:"max_price,omitempty"`
+}
+
+// Resultado de una escritura masiva
+type bulkReport struct {
+	Mode      string           `json:"mode"`
+	Committed bool             `json:"committed"`
+	Total     int              `json:"total"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []bulkItemResult `json:"results"`
+}
+
+// Resultado de una fila; index es la posición de la operación en el cuerpo
+type bulkItemResult struct {
+	Index   int            `json:"index"`
+	Op      string         `json:"op"`
+	ID      int64          `json:"id"`
+	Status  int            `json:"status"`
+	Version int64          `json:"version,omitempty"`
+	Error   *bulkItemError `json:"error,omitempty"`
+}
+
+type bulkItemError struct {
+	Code   string       `json:"code"`
+	Detail string       `json:"detail"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// Filtro del store; sin criterios devuelve false para no tocar la tabla completa
+func (f *BulkPropertyFilter) propertyFilter() (PropertyFilter, bool) {
+	filter := PropertyFilter{
+		Towns:           f.Towns,
+		PropertyType:    f.PropertyType,
+		ResidentialType: f.ResidentialType,
+		ListYear:        f.ListYear,
+		Status:          f.Status,
+		MinPrice:        f.MinPrice,
+		MaxPrice:        f.MaxPrice,
+	}
+	empty := len(f.Towns) == 0 && f.PropertyType == "" && f.ResidentialType == "" && f.ListYear == nil &&
+		f.Status == "" && f.MinPrice == nil && f.MaxPrice == nil
+	return filter, !empty
+}
+
+// Convertir las operaciones del cuerpo en operaciones del store; los errores de
+// forma de cualquier operación rechazan la petición completa
+func (op *BulkPropertyOperation) storeOperation(index int, rules *propertyValidator) (PropertyOperation, []FieldError) {
+	var errs []FieldError
+	fail := func(field, rule, message string) {
+		errs = append(errs, FieldError{Field: fmt.Sprintf("operations[%d].%s", index, field), Rule: rule, Message: message})
+	}
+	check := func(p *Property) error {
+		if fields := rules.validate(p); len(fields) > 0 {
+			return validationFailed(fields...)
+		}
+		return nil
+	}
+	checkPatch := func(before Property, p *Property) error {
+		if fields := rules.validatePatch(before, p); len(fields) > 0 {
+			return validationFailed(fields...)
+		}
+		return nil
+	}
+
+	result := PropertyOperation{Kind: op.Op, ID: op.ID, Version: op.Version}
+	switch op.Op {
+	case bulkCreate:
+		if op.ID != 0 || op.Version != 0 || op.Filter != nil {
+			fail("data", "excluded_with", "create takes only data")
+		}
+		if len(op.Data) == 0 {
+			fail("data", "required", "is required")
+		} else if err := decodeResource(op.Data, &result.Property); err != nil {
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || len(apiErr.Fields) == 0 {
+				fail("data", "type", "must be a property object")
+			} else {
+				for _, fe := range apiErr.Fields {
+					fail("data."+fe.Field, fe.Rule, fe.Message)
+				}
+			}
+		}
+		result.Apply = check
+
+	case bulkUpdate:
+		switch {
+		case (op.ID != 0) == (op.Filter != nil):
+			fail("id", "required_without", "exactly one of id or filter is required")
+		case op.Filter != nil && op.Version != 0:
+			fail("version", "excluded_with", "cannot be used with filter")
+		case op.Filter != nil:
+			filter, ok := op.Filter.propertyFilter()
+			if !ok {
+				fail("filter", "required", "must set at least one criterion")
+			}
+			for i, town := range filter.Towns {
+				canonical, known := lookupReference(rules.towns, town)
+				if !known {
+					fail(fmt.Sprintf("filter.towns[%d]", i), "exists", fmt.Sprintf("unknown town %q", town))
+				}
+				filter.Towns[i] = canonical
+			}
+			result.Filter = &filter
+		}
+		var fields map[string]json.RawMessage
+		if len(op.Data) == 0 {
+			fail("data", "required", "is required")
+		} else if err := json.Unmarshal(op.Data, &fields); err != nil {
+			fail("data", "type", "must be a merge patch object")
+		}
+		result.Apply = patchPropertyWith(&resourcePatch{merge: op.Data}, checkPatch)
+
+	case bulkDelete:
+		if op.ID == 0 {
+			fail("id", "required", "is required")
+		}
+		if op.Filter != nil || len(op.Data) > 0 {
+			fail("filter", "excluded_with", "delete takes only id and version")
+		}
+	}
+
+	if op.ID < 0 {
+		fail("id", "gt", "must be a positive integer")
+	}
+	return result, errs
+}
+
+// Traducir el error de una fila a su estado y detalle
+func bulkItemFailure(err error) (int, *bulkItemError) {
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr):
+	case errors.Is(err, errNotFound):
+		apiErr = newAPIError(http.StatusNotFound, codePropertyNotFound, "Property not found")
+	case errors.Is(err, errConflict):
+		apiErr = newAPIError(http.StatusConflict, codePropertyExists, "Property already exists")
+	case errors.Is(err, errVersionMismatch):
+		apiErr = versionMismatch()
+	default:
+		log.Printf("Database error: %v", err)
+		apiErr = newAPIError(http.StatusInternalServerError, codeInternalError, "Failed to write property")
+	}
+	return apiErr.Status, &bulkItemError{Code: apiErr.Code, Detail: apiErr.Detail, Errors: apiErr.Fields}
+}
+
+// Crear, modificar y eliminar propiedades en una sola transacción (admin)
+func (app *App) bulkProperties(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBulkBytes)
+	var req BulkPropertyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			abortProblem(c, http.StatusRequestEntityTooLarge, codeUploadTooLarge, "Request body too large")
+			return
+		}
+		abortWithError(c, bindingProblem(err))
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = bulkAtomic
+	}
+	switch {
+	case len(req.Operations) == 0:
+		abortWithError(c, validationFailed(FieldError{Field: "operations", Rule: "min", Message: "must contain at least 1 item"}))
+		return
+	case len(req.Operations) > maxBulkOperations:
+		abortWithError(c, validationFailed(FieldError{Field: "operations", Rule: "max", Message: fmt.Sprintf("must contain at most %d items", maxBulkOperations)}))
+		return
+	}
+
+	ctx := c.Request.Context()
+	ref, err := app.properties.Reference(ctx)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to load reference data")
+		return
+	}
+	rules := newPropertyValidator(ref, time.Now())
+
+	ops := make([]PropertyOperation, len(req.Operations))
+	var errs []FieldError
+	rows := 0
+	for i := range req.Operations {
+		op, opErrs := req.Operations[i].storeOperation(i, rules)
+		ops[i] = op
+		errs = append(errs, opErrs...)
+		if op.Filter == nil || len(opErrs) > 0 {
+			rows++
+			continue
+		}
+		// El recuento es orientativo: las filas se vuelven a seleccionar en la transacción
+		matched, err := app.properties.Count(ctx, *op.Filter)
+		if err != nil {
+			log.Printf("Database error: %v", err)
+			internalError(c, "Failed to count properties")
+			return
+		}
+		rows += matched
+	}
+	if len(errs) == 0 && rows > maxBulkRows {
+		errs = append(errs, FieldError{Field: "operations", Rule: "max", Message: fmt.Sprintf("affect %d properties; at most %d per request", rows, maxBulkRows)})
+	}
+	if len(errs) > 0 {
+		abortWithError(c, validationFailed(errs...))
+		return
+	}
+
+	results, committed, err := app.properties.Bulk(ctx, actorFromContext(c), ops, req.Mode == bulkAtomic)
+	if err != nil {
+		log.Printf("Bulk error: %v", err)
+		internalError(c, "Failed to apply bulk operations")
+		return
+	}
+
+	report := bulkReport{Mode: req.Mode, Committed: committed, Total: len(results), Results: make([]bulkItemResult, len(results))}
+	for i, r := range results {
+		item := bulkItemResult{Index: r.Index, Op: ops[r.Index].Kind, ID: r.ID, Status: http.StatusOK, Version: r.Version}
+		switch {
+		case r.Err != nil:
+			item.Status, item.Error = bulkItemFailure(r.Err)
+			item.Version = 0
+			report.Failed++
+		case item.Op == bulkCreate:
+			item.Status = http.StatusCreated
+			report.Succeeded++
+		default:
+			report.Succeeded++
+		}
+		if !committed {
+			item.Version = 0
+		}
+		report.Results[i] = item
+	}
+	log.Printf("Bulk finished: mode %s, %d rows, %d succeeded, %d failed, committed %t", report.Mode, report.Total, report.Succeeded, report.Failed, committed)
+
+	if !committed {
+		abortWithError(c, &APIError{
+			Status: http.StatusUnprocessableEntity,
+			Code:   codeBulkRolledBack,
+			Detail: fmt.Sprintf("%d of %d operations failed; no changes were applied", report.Failed, report.Total),
+			Extra: gin.H{
+				"mode":      report.Mode,
+				"committed": false,
+				"total":     report.Total,
+				"succeeded": report.Succeeded,
+				"failed":    report.Failed,
+				"results":   report.Results,
+			},
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": report.Failed == 0,
+		"data":    report,
+	})
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	mathrand "math/rand"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const cliUsage = `Usage: backend <command> [options]
+
+Commands:
+  serve [--config FILE] [--KEY VALUE]   Start the HTTP server (default)
+  migrate up|down [N]|status            Apply, revert or list schema migrations
+  seed --synthetic N [--seed S]         Insert N synthetic properties
+  create-admin --username U --email E   Create an administrator account
+  import properties FILE [options]      Import properties from CSV or NDJSON
+  export [options]                      Export properties as CSV, NDJSON or Parquet
+  normalize-addresses [--all]           Fill the normalized address columns
+  check-config [--config FILE] [...]    Validate configuration and connectivity
+
+Run 'backend <command> -h' for the options of a command.
+Configuration precedence: defaults < config file < environment < flags.
+`
+
+// Valores usados por seed cuando las tablas de referencia están vacías
+var syntheticTowns = []string{"Bridgeport", "Danbury", "Greenwich", "Hartford", "New Haven", "Norwalk", "Stamford", "Waterbury"}
+
+var syntheticStreets = []string{"Main", "Oak", "Maple", "Elm", "Park", "Church", "Washington", "Prospect", "Highland", "Ridge"}
+
+var syntheticSuffixes = []string{"ST", "AVE", "RD", "LN", "DR", "CT"}
+
+var syntheticPropertyTypes = []string{"Residential", "Single Family", "Condo", "Two Family", "Three Family", "Commercial", "Vacant Land"}
+
+var syntheticResidentialTypes = []string{"Single Family", "Condo", "Two Family", "Three Family", "Four Family"}
+
+// Ejecutar el subcomando indicado en los argumentos
+func runCLI(args []string) error {
+	err := dispatchCommand(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return err
+}
+
+func dispatchCommand(args []string) error {
+	command := "serve"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "help", "-h", "--help":
+		fmt.Print(cliUsage)
+		return nil
+	case "check-config":
+		return runCheckConfig(args)
+	case "serve", "migrate", "seed", "create-admin", "import", "export", "normalize-addresses":
+	default:
+		fmt.Fprint(os.Stderr, cliUsage)
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	// Todos los demás comandos comparten configuración y pool con el servidor;
+	// sólo serve acepta flags de configuración, el resto usa CONFIG_FILE y el entorno
+	var configArgs []string
+	if command == "serve" {
+		configArgs, args = args, nil
+	}
+	config, err := loadConfig(command, configArgs)
+	if err != nil {
+		return err
+	}
+	config.setupLogging()
+
+	app := NewApp(config)
+	if err := app.connectDB(); err != nil {
+		return err
+	}
+	defer app.db.Close()
+
+	switch command {
+	case "serve":
+		return app.serve()
+	case "migrate":
+		return app.runMigrateCommand(args)
+	case "seed":
+		return app.runSeedCommand(args)
+	case "create-admin":
+		return app.runCreateAdminCommand(args)
+	case "import":
+		return app.runImportCommand(args)
+	case "normalize-addresses":
+		return app.runNormalizeAddressesCommand(args)
+	default:
+		return app.runExportCommand(args)
+	}
+}
+
+// Parsear flags admitiendo argumentos posicionales intercalados
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// seed --synthetic N: insertar propiedades sintéticas para desarrollo
+func (app *App) runSeedCommand(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	count := fs.Int("synthetic", 0, "number of synthetic properties to insert")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random seed for reproducible data")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *count <= 0 {
+		return errors.New("seed requires --synthetic N with N > 0")
+	}
+
+	ctx := context.Background()
+	ref, err := app.properties.Reference(ctx)
+	if err != nil {
+		return err
+	}
+	if len(ref.Towns) == 0 {
+		ref.Towns = syntheticTowns
+	}
+	if len(ref.PropertyTypes) == 0 {
+		ref.PropertyTypes = syntheticPropertyTypes
+	}
+	if len(ref.ResidentialTypes) == 0 {
+		ref.ResidentialTypes = syntheticResidentialTypes
+	}
+
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := setChangeActor(ctx, tx, nil); err != nil {
+		return err
+	}
+
+	// Continuar a partir del mayor serial existente, incluidos los eliminados
+	var next int64
+	if err := tx.QueryRow(ctx, "SELECT COALESCE(MAX(serial_number), 0) + 1 FROM properties").Scan(&next); err != nil {
+		return err
+	}
+
+	rng := mathrand.New(mathrand.NewSource(*seed))
+	columns := strings.Split(propertyRowColumns, ", ")
+	generated := 0
+	inserted, err := tx.CopyFrom(ctx, pgx.Identifier{"properties"}, columns, pgx.CopyFromFunc(func() ([]any, error) {
+		if generated == *count {
+			return nil, nil
+		}
+		p := syntheticProperty(rng, next+int64(generated), ref)
+		generated++
+		return propertyRowValues(p), nil
+	}))
+	if err != nil {
+		return err
+	}
+
+	summary := gin.H{"source": "synthetic", "seed": *seed, "inserted": inserted}
+	if err := recordAudit(ctx, tx, nil, auditImport, "property", "", nil, summary); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	fmt.Printf("Inserted %d synthetic properties (serial numbers %d-%d, seed %d)\n", inserted, next, next+inserted-1, *seed)
+	return nil
+}
+
+// Generar una propiedad con valores plausibles
+func syntheticProperty(rng *mathrand.Rand, serial int64, ref PropertyReference) Property {
+	listYear := 2001 + rng.Intn(22)
+	recorded := time.Date(listYear+rng.Intn(4), time.Month(1+rng.Intn(12)), 1+rng.Intn(28), 0, 0, 0, 0, time.UTC)
+	assessed := float64(50_000 + rng.Intn(85_000)*10)
+	sale := float64(int(assessed*(1.1+rng.Float64()*0.8)/100) * 100)
+
+	// Solo los tipos residenciales llevan residential_type; un tipo que ya es residencial se repite
+	propertyType := ref.PropertyTypes[rng.Intn(len(ref.PropertyTypes))]
+	residentialType := missingValue
+	if slices.Contains(ref.ResidentialTypes, propertyType) {
+		residentialType = propertyType
+	} else if propertyType == "Residential" {
+		residentialType = ref.ResidentialTypes[rng.Intn(len(ref.ResidentialTypes))]
+	}
+
+	return Property{
+		SerialNumber:    serial,
+		ListYear:        listYear,
+		DateRecorded:    recorded.Format("2006-01-02"),
+		Town:            ref.Towns[rng.Intn(len(ref.Towns))],
+		Address:         fmt.Sprintf("%d %s %s", 1+rng.Intn(999), syntheticStreets[rng.Intn(len(syntheticStreets))], syntheticSuffixes[rng.Intn(len(syntheticSuffixes))]),
+		AssessedValue:   assessed,
+		SaleAmount:      sale,
+		SalesRatio:      assessed / sale,
+		PropertyType:    propertyType,
+		ResidentialType: residentialType,
+		YearsUntilSold:  recorded.Year() - listYear,
+	}
+}
+
+// create-admin --username U --email E: crear la cuenta de administrador
+func (app *App) runCreateAdminCommand(args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	username := fs.String("username", "", "administrator username (required)")
+	email := fs.String("email", "", "administrator email (default <username>@urbanytics.local)")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from standard input")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return errors.New("create-admin requires --username")
+	}
+	if *email == "" {
+		*email = *username + "@urbanytics.local"
+	}
+
+	// Contraseña: stdin, ADMIN_PASSWORD o una aleatoria que se muestra una sola vez
+	password := os.Getenv("ADMIN_PASSWORD")
+	generated := false
+	if *passwordStdin {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		bytes := make([]byte, 18)
+		if _, err := rand.Read(bytes); err != nil {
+			return err
+		}
+		password = base64.RawURLEncoding.EncodeToString(bytes)
+		generated = true
+	}
+	if len(password) < 6 {
+		return errors.New("password must be at least 6 characters")
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	created, err := app.users.Create(context.Background(), nil, NewUser{
+		Username:     *username,
+		Email:        *email,
+		PasswordHash: string(hashedPassword),
+		Role:         "admin",
+	})
+	if errors.Is(err, errConflict) {
+		return fmt.Errorf("username %q or email %q already exists", *username, *email)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created admin %q (id %d)\n", created.Username, created.ID)
+	if generated {
+		fmt.Printf("Generated password: %s\n", password)
+	}
+	return nil
+}
+
+// import properties FILE: importar propiedades con las mismas reglas que la API
+func (app *App) runImportCommand(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	mode := fs.String("mode", "upsert", "upsert or insert")
+	format := fs.String("format", "", "csv or ndjson (default from file extension)")
+	dryRun := fs.Bool("dry-run", false, "validate and report without writing")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 || positional[0] != "properties" {
+		return errors.New("usage: import properties FILE [--mode upsert|insert] [--format csv|ndjson] [--dry-run]")
+	}
+	if *mode != "upsert" && *mode != "insert" {
+		return errors.New("invalid mode, must be 'upsert' or 'insert'")
+	}
+
+	path := positional[1]
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, err := newPropertyRecordReader(detectImportFormat(*format, filepath.Base(path), ""), file)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	validator, err := app.newPropertyRecordValidator(ctx)
+	if err != nil {
+		return err
+	}
+	report, err := app.runPropertyImport(ctx, nil, reader, validator, *mode, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, rowErr := range report.Errors {
+		fmt.Fprintf(os.Stderr, "line %d: %s %s\n", rowErr.Line, rowErr.Field, rowErr.Message)
+	}
+	if report.ErrorsTruncated {
+		fmt.Fprintln(os.Stderr, "(error list truncated)")
+	}
+	fmt.Printf("%d rows: %d inserted, %d updated, %d skipped", report.TotalRows, report.Inserted, report.Updated, report.Skipped)
+	if report.DryRun {
+		fmt.Print(" (dry run, nothing written)")
+	}
+	fmt.Println()
+	return nil
+}
+
+// normalize-addresses: calcular los componentes de dirección de las filas existentes
+func (app *App) runNormalizeAddressesCommand(args []string) error {
+	fs := flag.NewFlagSet("normalize-addresses", flag.ContinueOnError)
+	all := fs.Bool("all", false, "recompute every row, not only those never normalized")
+	batch := fs.Int("batch", 5000, "rows per batch")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *batch < 1 || *batch > maxBulkRows {
+		return fmt.Errorf("--batch must be between 1 and %d", maxBulkRows)
+	}
+
+	updated, err := normalizeStoredAddresses(context.Background(), app.db, *all, *batch)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Normalized %d addresses\n", updated)
+	return nil
+}
+
+// Flags repetibles --filter clave=valor
+type filterFlags url.Values
+
+func (f filterFlags) String() string { return url.Values(f).Encode() }
+
+func (f filterFlags) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("filter %q must be key=value", value)
+	}
+	url.Values(f).Add(key, val)
+	return nil
+}
+
+// export: exportar propiedades con los mismos filtros que la API
+func (app *App) runExportCommand(args []string) error {
+	query := url.Values{}
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "csv", "csv, ndjson or parquet")
+	output := fs.String("output", "", "output file (default stdout)")
+	sort := fs.String("sort", "", "sort specification, e.g. -sale_amount,town")
+	fs.Var(filterFlags(query), "filter", "filter as key=value, e.g. town=Hartford (repeatable)")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *sort != "" {
+		query.Set("sort", *sort)
+	}
+
+	sortFields, err := propertySortFromQuery(query)
+	if err != nil {
+		return err
+	}
+	filter, err := propertyFilterFromQuery(query)
+	if err != nil {
+		return err
+	}
+	conditions, queryArgs := propertyFilterConditions(filter)
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+	buffered := bufio.NewWriter(out)
+
+	writer, err := newPropertyExportWriter(*format, buffered)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	tx, err := app.openPropertyExport(ctx, propertyExportQuery(conditions, sortFields), queryArgs)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	total, err := streamPropertyCursor(ctx, tx, writer, flushFunc(func() { buffered.Flush() }))
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("export aborted after %d rows: %v", total, err)
+	}
+	log.Printf("Exported %d properties as %s", total, *format)
+	return nil
+}
+
+// Adaptador de una función a la interfaz Flush()
+type flushFunc func()
+
+func (f flushFunc) Flush() { f() }
+
+// check-config: mostrar la configuración efectiva y verificar dependencias
+func runCheckConfig(args []string) error {
+	config, err := loadConfig("check-config", args)
+	if err != nil {
+		return err
+	}
+
+	if config.file != "" {
+		fmt.Printf("Config file: %s\n\n", config.file)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE\tENV")
+	for _, entry := range config.redacted() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Key, entry.Value, entry.Source, entry.Env)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	failed := false
+	check := func(name string, err error) {
+		if err != nil {
+			failed = true
+			fmt.Printf("✗ %s: %v\n", name, err)
+			return
+		}
+		fmt.Printf("✓ %s\n", name)
+	}
+
+	app := NewApp(config)
+	if config.Auth.KeysDir != "" {
+		_, err := loadKeyRing(config.Auth.KeysDir, config.Auth.AccessTokenTTL)
+		check("JWT signing keys", err)
+	}
+
+	err = app.connectDB()
+	if err == nil {
+		defer app.db.Close()
+		err = app.db.Ping(context.Background())
+	}
+	check("database connection", err)
+	if err == nil {
+		m, err := newMigrator(app.db)
+		if err == nil {
+			var pending []migration
+			if pending, err = m.Pending(context.Background()); err == nil && len(pending) > 0 {
+				err = fmt.Errorf("%d pending", len(pending))
+			}
+		}
+		check("schema migrations", err)
+	}
+
+	if failed {
+		return errors.New("configuration check failed")
+	}
+	return nil
+}
@@ -0,0 +1,561 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Formatos de log admitidos
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// Origen de cada valor, de menor a mayor precedencia
+const (
+	configSourceDefault = "default"
+	configSourceFile    = "file"
+	configSourceEnv     = "env"
+	configSourceFlag    = "flag"
+)
+
+// Configuración de la aplicación
+type Config struct {
+	Server   ServerConfig
+	Database DatabaseConfig
+	Auth     AuthConfig
+	CORS     CORSConfig
+	Timeouts TimeoutsConfig
+	Log      LogConfig
+	Purge    PurgeConfig
+	Features FeaturesConfig
+
+	// Archivo leído, origen de cada clave y si el secreto JWT se generó al iniciar
+	file               string
+	sources            map[string]string
+	jwtSecretGenerated bool
+}
+
+type ServerConfig struct {
+	Port            int
+	AutoMigrate     bool
+	ShutdownTimeout time.Duration
+	ShutdownDelay   time.Duration
+}
+
+type DatabaseConfig struct {
+	URL             string
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+}
+
+type AuthConfig struct {
+	JWTSecret       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	KeysDir         string
+	KeysReload      time.Duration
+}
+
+type CORSConfig struct {
+	AllowedOrigins []string
+	MaxAge         time.Duration
+}
+
+type TimeoutsConfig struct {
+	Lookup    time.Duration
+	Analytics time.Duration
+	Bulk      time.Duration
+}
+
+type LogConfig struct {
+	Format string
+}
+
+type PurgeConfig struct {
+	Retention time.Duration
+	Interval  time.Duration
+}
+
+type FeaturesConfig struct {
+	Registration   bool
+	Import         bool
+	Export         bool
+	RequireIfMatch bool
+}
+
+// Valores por defecto; la conexión a la base de datos no tiene default
+func defaultConfig() *Config {
+	return &Config{
+		Server: ServerConfig{Port: 8080, ShutdownTimeout: 30 * time.Second},
+		Database: DatabaseConfig{
+			MaxConns:        10,
+			MaxConnLifetime: time.Hour,
+			MaxConnIdleTime: 30 * time.Minute,
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 30 * 24 * time.Hour,
+			KeysReload:      time.Minute,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"http://localhost:5173", "http://localhost:3001"},
+			MaxAge:         12 * time.Hour,
+		},
+		Timeouts: TimeoutsConfig{
+			Lookup:    5 * time.Second,
+			Analytics: 30 * time.Second,
+			Bulk:      10 * time.Minute,
+		},
+		Log:      LogConfig{Format: logFormatText},
+		Purge:    PurgeConfig{Retention: 30 * 24 * time.Hour, Interval: time.Hour},
+		Features: FeaturesConfig{Registration: true, Import: true, Export: true},
+		sources:  make(map[string]string),
+	}
+}
+
+// Opción de configuración: clave del archivo, variable de entorno y flag
+type configSetting struct {
+	Key    string
+	Env    string
+	Usage  string
+	Secret bool
+	value  interface{}
+}
+
+// Nombre del flag: la clave con guiones en lugar de guiones bajos
+func (s configSetting) Flag() string {
+	return strings.ReplaceAll(s.Key, "_", "-")
+}
+
+// Todas las opciones, apuntando a los campos de cfg
+func (cfg *Config) settings() []configSetting {
+	return []configSetting{
+		{"server.port", "PORT", "HTTP listen port", false, &cfg.Server.Port},
+		{"server.auto_migrate", "AUTO_MIGRATE", "apply pending schema migrations at startup", false, &cfg.Server.AutoMigrate},
+		{"server.shutdown_timeout", "SHUTDOWN_TIMEOUT", "maximum time to drain in-flight requests on shutdown", false, &cfg.Server.ShutdownTimeout},
+		{"server.shutdown_delay", "SHUTDOWN_DELAY", "time to report not ready before draining", false, &cfg.Server.ShutdownDelay},
+		{"database.url", "DB_CONN_STR", "PostgreSQL connection string (required)", true, &cfg.Database.URL},
+		{"database.max_conns", "DB_MAX_CONNS", "maximum connections in the pool", false, &cfg.Database.MaxConns},
+		{"database.min_conns", "DB_MIN_CONNS", "connections kept open when idle", false, &cfg.Database.MinConns},
+		{"database.max_conn_lifetime", "DB_MAX_CONN_LIFETIME", "close connections older than this", false, &cfg.Database.MaxConnLifetime},
+		{"database.max_conn_idle_time", "DB_MAX_CONN_IDLE_TIME", "close connections idle longer than this", false, &cfg.Database.MaxConnIdleTime},
+		{"auth.jwt_secret", "JWT_SECRET", "HS256 secret, at least 32 bytes (random per process if unset)", true, &cfg.Auth.JWTSecret},
+		{"auth.access_token_ttl", "ACCESS_TOKEN_TTL", "lifetime of access tokens", false, &cfg.Auth.AccessTokenTTL},
+		{"auth.refresh_token_ttl", "REFRESH_TOKEN_TTL", "lifetime of refresh tokens", false, &cfg.Auth.RefreshTokenTTL},
+		{"auth.keys_dir", "JWT_KEYS_DIR", "directory with asymmetric signing keys", false, &cfg.Auth.KeysDir},
+		{"auth.keys_reload", "JWT_KEYS_RELOAD", "interval to reload signing keys (0 disables)", false, &cfg.Auth.KeysReload},
+		{"cors.allowed_origins", "CORS_ALLOWED_ORIGINS", "comma-separated origins allowed by CORS", false, &cfg.CORS.AllowedOrigins},
+		{"cors.max_age", "CORS_MAX_AGE", "how long browsers cache preflight responses", false, &cfg.CORS.MaxAge},
+		{"timeouts.lookup", "QUERY_TIMEOUT_LOOKUP", "deadline for lookups, listings and admin writes", false, &cfg.Timeouts.Lookup},
+		{"timeouts.analytics", "QUERY_TIMEOUT_ANALYTICS", "deadline for /analytics endpoints", false, &cfg.Timeouts.Analytics},
+		{"timeouts.bulk", "QUERY_TIMEOUT_BULK", "deadline for property import and export", false, &cfg.Timeouts.Bulk},
+		{"log.format", "LOG_FORMAT", "log format: text or json", false, &cfg.Log.Format},
+		{"purge.retention", "PURGE_RETENTION", "keep deleted records this long before purging (0 disables)", false, &cfg.Purge.Retention},
+		{"purge.interval", "PURGE_INTERVAL", "how often to purge deleted records (0 disables)", false, &cfg.Purge.Interval},
+		{"features.registration", "FEATURE_REGISTRATION", "allow self-registration of users", false, &cfg.Features.Registration},
+		{"features.import", "FEATURE_IMPORT", "enable the property import endpoint", false, &cfg.Features.Import},
+		{"features.export", "FEATURE_EXPORT", "enable the property export endpoint", false, &cfg.Features.Export},
+		{"features.require_if_match", "FEATURE_REQUIRE_IF_MATCH", "reject property writes without an If-Match header (428)", false, &cfg.Features.RequireIfMatch},
+	}
+}
+
+// Asignar un valor textual (env o flag) al campo apuntado
+func setConfigValue(ptr interface{}, value string) error {
+	switch p := ptr.(type) {
+	case *string:
+		*p = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		*p = n
+	case *int32:
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		*p = int32(n)
+	case *float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		*p = f
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		*p = b
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.New("must be a duration such as 90s, 15m or 720h")
+		}
+		*p = d
+	case *[]string:
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*p = items
+	default:
+		return fmt.Errorf("unsupported setting type %T", ptr)
+	}
+	return nil
+}
+
+// Representación textual del campo apuntado
+func formatConfigValue(ptr interface{}) string {
+	switch p := ptr.(type) {
+	case *string:
+		return *p
+	case *int:
+		return strconv.Itoa(*p)
+	case *int32:
+		return strconv.FormatInt(int64(*p), 10)
+	case *float64:
+		return strconv.FormatFloat(*p, 'g', -1, 64)
+	case *bool:
+		return strconv.FormatBool(*p)
+	case *time.Duration:
+		return p.String()
+	case *[]string:
+		return strings.Join(*p, ",")
+	default:
+		return fmt.Sprint(ptr)
+	}
+}
+
+// Cargar la configuración. Precedencia, de menor a mayor:
+// valores por defecto < archivo (--config o CONFIG_FILE) < variables de entorno < flags.
+func loadConfig(command string, args []string) (*Config, error) {
+	cfg := defaultConfig()
+	settings := cfg.settings()
+
+	// Los flags se aplican al final; aquí sólo se recogen
+	type override struct {
+		setting configSetting
+		value   string
+	}
+	var overrides []override
+
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML configuration file (env CONFIG_FILE)")
+	for _, s := range settings {
+		s := s
+		record := func(value string) error {
+			overrides = append(overrides, override{s, value})
+			return nil
+		}
+		usage := fmt.Sprintf("%s (env %s)", s.Usage, s.Env)
+		if _, ok := s.value.(*bool); ok {
+			fs.BoolFunc(s.Flag(), usage, record)
+		} else {
+			fs.Func(s.Flag(), usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	var problems []string
+	apply := func(s configSetting, value, source, origin string) {
+		if err := setConfigValue(s.value, value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: invalid value %s: %v", s.Key, origin, err))
+			return
+		}
+		cfg.sources[s.Key] = source
+	}
+
+	if *configFile != "" {
+		values, err := readConfigFile(*configFile)
+		if err != nil {
+			return nil, err
+		}
+		known := make(map[string]bool, len(settings))
+		for _, s := range settings {
+			known[s.Key] = true
+			if value, ok := values[s.Key]; ok {
+				apply(s, value, configSourceFile, "in config file")
+			}
+		}
+		for key := range values {
+			if !known[key] {
+				problems = append(problems, fmt.Sprintf("%s: unknown key in config file", key))
+			}
+		}
+		cfg.file = *configFile
+	}
+
+	for _, s := range settings {
+		if value := os.Getenv(s.Env); value != "" {
+			apply(s, value, configSourceEnv, "in "+s.Env)
+		}
+	}
+	for _, o := range overrides {
+		apply(o.setting, o.value, configSourceFlag, "for --"+o.setting.Flag())
+	}
+
+	if cfg.Auth.JWTSecret == "" {
+		cfg.Auth.JWTSecret = generateRandomSecret()
+		cfg.jwtSecretGenerated = true
+	}
+	problems = append(problems, cfg.validate()...)
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, configError(problems)
+	}
+	return cfg, nil
+}
+
+// Leer un archivo YAML o TOML según su extensión como claves con notación de punto
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config file: %v", err)
+	}
+
+	document := make(map[string]interface{})
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	case ".toml":
+		err = toml.Unmarshal(data, &document)
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q (use .yaml, .yml or .toml)", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	values := make(map[string]string)
+	flattenConfig("", document, values)
+	return values, nil
+}
+
+// Aplanar un documento anidado; las listas se unen con comas como en el entorno
+func flattenConfig(prefix string, document map[string]interface{}, values map[string]string) {
+	for name, value := range document {
+		key := prefix + name
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flattenConfig(key+".", v, values)
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+// Lista de problemas de configuración
+type configError []string
+
+func (e configError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e, "\n  - ")
+}
+
+// Validar la configuración completa; devuelve todos los problemas encontrados
+func (cfg *Config) validate() []string {
+	var problems []string
+	add := func(key, format string, args ...interface{}) {
+		problems = append(problems, key+": "+fmt.Sprintf(format, args...))
+	}
+
+	if cfg.Server.Port < 1 || cfg.Server.Port > 65535 {
+		add("server.port", "must be between 1 and 65535")
+	}
+	if cfg.Server.ShutdownTimeout <= 0 {
+		add("server.shutdown_timeout", "must be positive")
+	}
+	if cfg.Server.ShutdownDelay < 0 {
+		add("server.shutdown_delay", "must not be negative")
+	}
+
+	if cfg.Database.URL == "" {
+		add("database.url", "is required, set DB_CONN_STR, --database.url or database.url in the config file")
+	} else if _, err := pgxpool.ParseConfig(cfg.Database.URL); err != nil {
+		add("database.url", "is not a valid connection string")
+	}
+	if cfg.Database.MaxConns < 1 {
+		add("database.max_conns", "must be at least 1")
+	}
+	if cfg.Database.MinConns < 0 || cfg.Database.MinConns > cfg.Database.MaxConns {
+		add("database.min_conns", "must be between 0 and database.max_conns")
+	}
+	if cfg.Database.MaxConnLifetime <= 0 {
+		add("database.max_conn_lifetime", "must be positive")
+	}
+	if cfg.Database.MaxConnIdleTime <= 0 {
+		add("database.max_conn_idle_time", "must be positive")
+	}
+
+	if !cfg.jwtSecretGenerated && len(cfg.Auth.JWTSecret) < 32 {
+		add("auth.jwt_secret", "must be at least 32 bytes long")
+	}
+	if cfg.Auth.AccessTokenTTL <= 0 {
+		add("auth.access_token_ttl", "must be positive")
+	}
+	if cfg.Auth.RefreshTokenTTL <= cfg.Auth.AccessTokenTTL {
+		add("auth.refresh_token_ttl", "must be longer than auth.access_token_ttl")
+	}
+	if cfg.Auth.KeysReload < 0 {
+		add("auth.keys_reload", "must not be negative")
+	}
+
+	if len(cfg.CORS.AllowedOrigins) == 0 {
+		add("cors.allowed_origins", "must list at least one origin")
+	}
+	for _, origin := range cfg.CORS.AllowedOrigins {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			add("cors.allowed_origins", "%q is not an origin like https://example.com (wildcards are not allowed with credentials)", origin)
+		}
+	}
+	if cfg.CORS.MaxAge < 0 {
+		add("cors.max_age", "must not be negative")
+	}
+
+	if cfg.Timeouts.Lookup <= 0 {
+		add("timeouts.lookup", "must be positive")
+	}
+	if cfg.Timeouts.Analytics <= 0 {
+		add("timeouts.analytics", "must be positive")
+	}
+	if cfg.Timeouts.Bulk <= 0 {
+		add("timeouts.bulk", "must be positive")
+	}
+
+	if cfg.Log.Format != logFormatText && cfg.Log.Format != logFormatJSON {
+		add("log.format", "must be %q or %q", logFormatText, logFormatJSON)
+	}
+
+	if cfg.Purge.Retention < 0 {
+		add("purge.retention", "must not be negative")
+	}
+	if cfg.Purge.Interval < 0 {
+		add("purge.interval", "must not be negative")
+	}
+
+	return problems
+}
+
+// Valor efectivo de una opción con su origen
+type ConfigEntry struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Env    string `json:"env"`
+	Flag   string `json:"flag"`
+}
+
+var dsnPasswordPattern = regexp.MustCompile(`(password\s*=\s*)('[^']*'|\S+)`)
+
+// Ocultar la contraseña de una cadena de conexión (URL o clave=valor)
+func redactConnString(connStr string) string {
+	if u, err := url.Parse(connStr); err == nil && u.Scheme != "" {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			u.User = url.UserPassword(u.User.Username(), "REDACTED")
+		}
+		query := u.Query()
+		if query.Has("password") {
+			query.Set("password", "REDACTED")
+			u.RawQuery = query.Encode()
+		}
+		return u.String()
+	}
+	return dsnPasswordPattern.ReplaceAllString(connStr, "${1}REDACTED")
+}
+
+// Configuración efectiva con los secretos ocultos
+func (cfg *Config) redacted() []ConfigEntry {
+	settings := cfg.settings()
+	entries := make([]ConfigEntry, 0, len(settings))
+	for _, s := range settings {
+		value := formatConfigValue(s.value)
+		switch {
+		case s.value == &cfg.Auth.JWTSecret && cfg.jwtSecretGenerated:
+			value = "(generated)"
+		case s.value == &cfg.Database.URL:
+			value = redactConnString(value)
+		case s.Secret && value != "":
+			value = "REDACTED"
+		}
+
+		source := cfg.sources[s.Key]
+		if source == "" {
+			source = configSourceDefault
+		}
+		entries = append(entries, ConfigEntry{Key: s.Key, Value: value, Source: source, Env: s.Env, Flag: "--" + s.Flag()})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries
+}
+
+// Configurar el logger global según log.format
+func (cfg *Config) setupLogging() {
+	if cfg.Log.Format == logFormatJSON {
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+	}
+}
+
+// Generar secreto aleatorio para JWT
+func generateRandomSecret() string {
+	bytes := make([]byte, 32)
+	rand.Read(bytes)
+	return base64.StdEncoding.EncodeToString(bytes)
+}
+
+// Middleware que rechaza las rutas de una funcionalidad desactivada
+func requireFeature(enabled bool, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled {
+			abortProblem(c, http.StatusNotFound, codeFeatureDisabled, name+" is disabled")
+			return
+		}
+		c.Next()
+	}
+}
+
+// Mostrar la configuración efectiva sin secretos (admin)
+func (app *App) getConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"file":     app.config.file,
+			"settings": app.config.redacted(),
+		},
+	})
+}
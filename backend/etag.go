@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag fuerte de una versión de fila
+func versionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// Versión de un ETag fuerte; las etiquetas débiles no sirven para If-Match
+func parseVersionETag(tag string) (int64, bool) {
+	tag = strings.TrimSpace(tag)
+	if len(tag) < 3 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	return version, err == nil && version > 0
+}
+
+// Versión exigida por If-Match para la propiedad id; 0 si no hay condición o es "*".
+// El valor es una lista de ETags (RFC 9110 §13.1.1) que se comparan de forma fuerte:
+// las débiles y las que no son de esta API se ignoran y, si no queda ninguna, responde 412.
+// Con varias versiones se elige la actual si está en la lista; el store vuelve a
+// comprobarla al escribir, así que un cambio concurrente sigue respondiendo 412.
+func (app *App) ifMatchVersion(c *gin.Context, id int64) (int64, *APIError) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	switch header {
+	case "":
+		if app.config.Features.RequireIfMatch {
+			return 0, newAPIError(http.StatusPreconditionRequired, codePreconditionRequired, "If-Match header is required")
+		}
+		return 0, nil
+	case "*":
+		return 0, nil
+	}
+
+	var versions []int64
+	for _, tag := range strings.Split(header, ",") {
+		if version, ok := parseVersionETag(tag); ok && !slices.Contains(versions, version) {
+			versions = append(versions, version)
+		}
+	}
+	switch len(versions) {
+	case 0:
+		return 0, versionMismatch()
+	case 1:
+		return versions[0], nil
+	}
+
+	_, current, err := app.properties.GetWithVersion(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, errNotFound):
+		// La escritura responderá 404
+		return versions[0], nil
+	case err != nil:
+		log.Printf("Database error: %v", err)
+		return 0, newAPIError(http.StatusInternalServerError, codeInternalError, "Failed to load property version")
+	case !slices.Contains(versions, current):
+		return 0, versionMismatch()
+	}
+	return current, nil
+}
+
+// La fila cambió desde que el cliente obtuvo su ETag
+func versionMismatch() *APIError {
+	return newAPIError(http.StatusPreconditionFailed, codePreconditionFailed, "The resource was modified by another request")
+}
+
+// Indica si If-None-Match incluye el ETag actual (comparación débil)
+func ifNoneMatch(c *gin.Context, etag string) bool {
+	header := c.GetHeader("If-None-Match")
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Filas leídas por cada FETCH del cursor de exportación
+const exportFetchSize = 1000
+
+// Filas por row group en Parquet
+const parquetRowGroupSize = 64 * 1024
+
+// Formatos de exportación soportados
+var exportContentTypes = map[string]string{
+	"csv":     "text/csv; charset=utf-8",
+	"ndjson":  "application/x-ndjson",
+	"parquet": "application/vnd.apache.parquet",
+}
+
+// Escritor de propiedades en un formato de exportación
+type propertyExportWriter interface {
+	Write(p *Property) error
+	// Flush envía al cliente las filas acumuladas
+	Flush() error
+	Close() error
+}
+
+// Exportación CSV con la misma cabecera que acepta la importación
+type csvExportWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+func newCSVExportWriter(w io.Writer) (*csvExportWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(strings.Split(propertyColumns, ", ")); err != nil {
+		return nil, err
+	}
+	return &csvExportWriter{writer: writer, record: make([]string, 11)}, nil
+}
+
+func (w *csvExportWriter) Write(p *Property) error {
+	w.record[0] = strconv.FormatInt(p.SerialNumber, 10)
+	w.record[1] = strconv.Itoa(p.ListYear)
+	w.record[2] = p.DateRecorded
+	w.record[3] = p.Town
+	w.record[4] = p.Address
+	w.record[5] = strconv.FormatFloat(p.AssessedValue, 'f', -1, 64)
+	w.record[6] = strconv.FormatFloat(p.SaleAmount, 'f', -1, 64)
+	w.record[7] = strconv.FormatFloat(p.SalesRatio, 'f', -1, 64)
+	w.record[8] = p.PropertyType
+	w.record[9] = p.ResidentialType
+	w.record[10] = strconv.Itoa(p.YearsUntilSold)
+	return w.writer.Write(w.record)
+}
+
+func (w *csvExportWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *csvExportWriter) Close() error {
+	return w.Flush()
+}
+
+// Exportación NDJSON: un objeto Property por línea
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonExportWriter) Write(p *Property) error {
+	return w.encoder.Encode(p)
+}
+
+func (w *ndjsonExportWriter) Flush() error { return nil }
+
+func (w *ndjsonExportWriter) Close() error { return nil }
+
+// Fila Parquet de una propiedad
+type propertyParquetRow struct {
+	SerialNumber    int64   `parquet:"serial_number"`
+	ListYear        int32   `parquet:"list_year"`
+	DateRecorded    string  `parquet:"date_recorded"`
+	Town            string  `parquet:"town,dict"`
+	Address         string  `parquet:"address"`
+	AssessedValue   float64 `parquet:"assessed_value"`
+	SaleAmount      float64 `parquet:"sale_amount"`
+	SalesRatio      float64 `parquet:"sales_ratio"`
+	PropertyType    string  `parquet:"property_type,dict"`
+	ResidentialType string  `parquet:"residential_type,dict"`
+	YearsUntilSold  int32   `parquet:"years_until_sold"`
+}
+
+// Exportación Parquet; las filas se agrupan por lote de FETCH
+type parquetExportWriter struct {
+	writer *parquet.GenericWriter[propertyParquetRow]
+	batch  []propertyParquetRow
+}
+
+func newParquetExportWriter(w io.Writer) *parquetExportWriter {
+	return &parquetExportWriter{
+		writer: parquet.NewGenericWriter[propertyParquetRow](w, parquet.MaxRowsPerRowGroup(parquetRowGroupSize)),
+		batch:  make([]propertyParquetRow, 0, exportFetchSize),
+	}
+}
+
+func (w *parquetExportWriter) Write(p *Property) error {
+	w.batch = append(w.batch, propertyParquetRow{
+		SerialNumber:    p.SerialNumber,
+		ListYear:        int32(p.ListYear),
+		DateRecorded:    p.DateRecorded,
+		Town:            p.Town,
+		Address:         p.Address,
+		AssessedValue:   p.AssessedValue,
+		SaleAmount:      p.SaleAmount,
+		SalesRatio:      p.SalesRatio,
+		PropertyType:    p.PropertyType,
+		ResidentialType: p.ResidentialType,
+		YearsUntilSold:  int32(p.YearsUntilSold),
+	})
+	return nil
+}
+
+func (w *parquetExportWriter) Flush() error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+	_, err := w.writer.Write(w.batch)
+	w.batch = w.batch[:0]
+	return err
+}
+
+func (w *parquetExportWriter) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return w.writer.Close()
+}
+
+// Crear el escritor para un formato de exportación
+func newPropertyExportWriter(format string, w io.Writer) (propertyExportWriter, error) {
+	switch format {
+	case "csv":
+		return newCSVExportWriter(w)
+	case "ndjson":
+		return &ndjsonExportWriter{encoder: json.NewEncoder(w)}, nil
+	case "parquet":
+		return newParquetExportWriter(w), nil
+	default:
+		return nil, fmt.Errorf("invalid format %q", format)
+	}
+}
+
+// Consulta de exportación con filtros y orden ya validados
+func propertyExportQuery(conditions []string, sortFields []sortField) string {
+	query := "SELECT " + propertyColumns + " FROM properties"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	return query + buildOrderBy(withTiebreaker(sortFields))
+}
+
+// Abrir una transacción de solo lectura con el cursor de exportación declarado
+func (app *App) openPropertyExport(ctx context.Context, query string, args []interface{}) (pgx.Tx, error) {
+	tx, err := app.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, "DECLARE property_export NO SCROLL CURSOR FOR "+query, args...); err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Elegir formato por parámetro format= o por cabecera Accept
+func negotiateExportFormat(c *gin.Context) (string, bool) {
+	if format := strings.ToLower(c.Query("format")); format != "" {
+		_, ok := exportContentTypes[format]
+		return format, ok
+	}
+
+	accept := c.GetHeader("Accept")
+	switch {
+	case strings.Contains(accept, "parquet"):
+		return "parquet", true
+	case strings.Contains(accept, "ndjson"), strings.Contains(accept, "jsonlines"):
+		return "ndjson", true
+	default:
+		return "csv", true
+	}
+}
+
+// Exportar propiedades filtradas sin paginación
+func (app *App) exportProperties(c *gin.Context) {
+	format, ok := negotiateExportFormat(c)
+	if !ok {
+		abortProblem(c, http.StatusBadRequest, codeInvalidParameter, "Invalid format. Must be 'csv', 'ndjson' or 'parquet'")
+		return
+	}
+
+	sortFields, err := propertySortFromQuery(c.Request.URL.Query())
+	if err != nil {
+		if specErr, ok := err.(*sortSpecError); ok {
+			abortWithError(c, specErr.problem())
+			return
+		}
+		abortProblem(c, http.StatusBadRequest, codeInvalidSort, err.Error())
+		return
+	}
+
+	filter, err := propertyFilterFromQuery(c.Request.URL.Query())
+	if err != nil {
+		abortProblem(c, http.StatusBadRequest, codeInvalidParameter, err.Error())
+		return
+	}
+	conditions, args := propertyFilterConditions(filter)
+
+	// Cursor de servidor dentro de una transacción de solo lectura
+	ctx := c.Request.Context()
+	tx, err := app.openPropertyExport(ctx, propertyExportQuery(conditions, sortFields), args)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to export properties")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	c.Header("Content-Type", exportContentTypes[format])
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"properties.%s\"", format))
+	c.Status(http.StatusOK)
+
+	writer, err := newPropertyExportWriter(format, c.Writer)
+	if err != nil {
+		log.Printf("Export error: %v", err)
+		return
+	}
+
+	total, err := streamPropertyCursor(ctx, tx, writer, c.Writer)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		// La respuesta ya comenzó: solo se puede cortar el stream
+		log.Printf("Export aborted after %d rows: %v", total, err)
+		c.Abort()
+		return
+	}
+	log.Printf("Exported %d properties as %s", total, format)
+}
+
+// Leer el cursor por lotes y escribir cada fila
+func streamPropertyCursor(ctx context.Context, tx pgx.Tx, writer propertyExportWriter, flusher interface{ Flush() }) (int, error) {
+	total := 0
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM property_export", exportFetchSize)
+	for {
+		rows, err := tx.Query(ctx, fetch)
+		if err != nil {
+			return total, err
+		}
+
+		fetched := 0
+		var p Property
+		for rows.Next() {
+			if err := scanProperty(rows, &p); err != nil {
+				rows.Close()
+				return total, err
+			}
+			if err := writer.Write(&p); err != nil {
+				rows.Close()
+				return total, err
+			}
+			fetched++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return total, err
+		}
+
+		total += fetched
+		if fetched == 0 {
+			return total, nil
+		}
+		if err := writer.Flush(); err != nil {
+			return total, err
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"slices"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Contraseña de los usuarios de las pruebas con stores en memoria
+const memoryPassword = "memory-password"
+
+// Roles de la migración 0002 con sus permisos
+var memoryRoles = []Role{
+	{Name: "admin", Description: "Acceso completo", Permissions: []string{
+		permPropertiesWrite, permUsersManage, permRolesManage, permAnalyticsExport, permAuditRead, permConfigRead,
+	}},
+	{Name: "user", Description: "Acceso de lectura", Permissions: []string{}},
+	{Name: "analyst", Description: "Puede exportar datos", Permissions: []string{permAnalyticsExport}},
+	{Name: "data_steward", Description: "Puede editar propiedades", Permissions: []string{permPropertiesWrite}},
+}
+
+// Propiedades de las pruebas con stores en memoria
+var memoryProperties = []Property{
+	{SerialNumber: 1, ListYear: 2020, DateRecorded: "2021-03-01", Town: "Hartford", Address: "12 MAPLE ST", AssessedValue: 120000, SaleAmount: 180000, SalesRatio: 0.6667, PropertyType: "Residential", ResidentialType: "Single Family", YearsUntilSold: 1},
+	{SerialNumber: 2, ListYear: 2020, DateRecorded: "2020-07-15", Town: "Hartford", Address: "40 ELM AVE", AssessedValue: 95000, SaleAmount: 150000, SalesRatio: 0.6333, PropertyType: "Residential", ResidentialType: "Condo", YearsUntilSold: 0},
+	{SerialNumber: 3, ListYear: 2021, DateRecorded: "2023-01-20", Town: "New Haven", Address: "7 CHAPEL ST", AssessedValue: 210000, SaleAmount: 250000, SalesRatio: 0.84, PropertyType: "Commercial", ResidentialType: missingValue, YearsUntilSold: 2},
+	{SerialNumber: 4, ListYear: 2021, DateRecorded: "2021-11-02", Town: "Cheshire", Address: "88 MAPLETON RD", AssessedValue: 150000, SaleAmount: 210000, SalesRatio: 0.7143, PropertyType: "Residential", ResidentialType: "Two Family", YearsUntilSold: 0},
+	{SerialNumber: 5, ListYear: 2022, DateRecorded: "2022-05-09", Town: "Stamford", Address: "3 HIGH RIDGE RD", AssessedValue: 400000, SaleAmount: 520000, SalesRatio: 0.7692, PropertyType: "Residential", ResidentialType: "Single Family", YearsUntilSold: 0},
+}
+
+// Aplicación sobre los stores en memoria con un usuario admin, analyst y viewer
+func newMemoryApp(t *testing.T, properties ...Property) (*App, http.Handler) {
+	t.Helper()
+
+	config := defaultConfig()
+	config.Auth.JWTSecret = "memory-test-secret-0123456789abcdef"
+	app := NewApp(config)
+	app.useMemoryStores(properties, memoryRoles...)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(memoryPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []NewUser{
+		{Username: "admin", Email: "admin@urbanytics.test", Role: "admin"},
+		{Username: "analyst", Email: "analyst@urbanytics.test", Role: "analyst"},
+		{Username: "viewer", Email: "viewer@urbanytics.test", Role: "user"},
+	} {
+		u.PasswordHash = string(hash)
+		if _, err := app.users.Register(t.Context(), u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return app, app.setupRoutes()
+}
+
+// Iniciar sesión y devolver el token de acceso y el refresh token
+func memoryLogin(t *testing.T, router http.Handler, username string) (string, string) {
+	t.Helper()
+	w := doRequest(t, router, "login", apiRequest{Method: http.MethodPost, Path: "/api/v1/auth/login",
+		Body: map[string]string{"username": username, "password": memoryPassword}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("login as %s: %d %s", username, w.Code, w.Body.String())
+	}
+	return responseField(t, w, "token").(string), responseField(t, w, "refresh_token").(string)
+}
+
+// Comprobar status y, si se indica, el código de error problem+json
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d: %s", w.Code, status, w.Body.String())
+	}
+	if code != "" {
+		if got := responseField(t, w, "code"); got != code {
+			t.Fatalf("code = %v, want %s", got, code)
+		}
+	}
+}
+
+func TestHandlersSessions(t *testing.T) {
+	_, router := newMemoryApp(t)
+	profile := func(token string) *httptest.ResponseRecorder {
+		return doRequest(t, router, "profile", apiRequest{Method: http.MethodGet, Path: "/api/v1/profile", Token: token})
+	}
+
+	w := doRequest(t, router, "login_invalid", apiRequest{Method: http.MethodPost, Path: "/api/v1/auth/login",
+		Body: map[string]string{"username": "admin", "password": "wrong-password"}})
+	expectStatus(t, w, http.StatusUnauthorized, "")
+
+	token, refresh := memoryLogin(t, router, "viewer")
+	expectStatus(t, profile(token), http.StatusOK, "")
+	expectStatus(t, profile("not-a-token"), http.StatusUnauthorized, codeInvalidToken)
+
+	// La rotación emite un refresh token nuevo y mantiene la sesión
+	w = doRequest(t, router, "refresh", apiRequest{Method: http.MethodPost, Path: "/api/v1/auth/refresh",
+		Body: map[string]string{"refresh_token": refresh}})
+	expectStatus(t, w, http.StatusOK, "")
+	rotated := responseField(t, w, "refresh_token").(string)
+	if rotated == refresh {
+		t.Fatal("refresh token was not rotated")
+	}
+	expectStatus(t, profile(responseField(t, w, "token").(string)), http.StatusOK, "")
+
+	// Reutilizar el token rotado revoca la sesión completa
+	w = doRequest(t, router, "refresh_reused", apiRequest{Method: http.MethodPost, Path: "/api/v1/auth/refresh",
+		Body: map[string]string{"refresh_token": refresh}})
+	expectStatus(t, w, http.StatusUnauthorized, codeRefreshTokenReused)
+	expectStatus(t, profile(token), http.StatusUnauthorized, codeSessionRevoked)
+	w = doRequest(t, router, "refresh_revoked", apiRequest{Method: http.MethodPost, Path: "/api/v1/auth/refresh",
+		Body: map[string]string{"refresh_token": rotated}})
+	expectStatus(t, w, http.StatusUnauthorized, codeInvalidRefreshToken)
+
+	// logout con el token de acceso cierra solo esa sesión
+	first, _ := memoryLogin(t, router, "analyst")
+	second, _ := memoryLogin(t, router, "analyst")
+	w = doRequest(t, router, "logout", apiRequest{Method: http.MethodPost, Path: "/api/v1/auth/logout", Token: first})
+	expectStatus(t, w, http.StatusOK, "")
+	if revoked := responseField(t, w, "revoked"); revoked != 1.0 {
+		t.Fatalf("revoked = %v, want 1", revoked)
+	}
+	expectStatus(t, profile(first), http.StatusUnauthorized, codeSessionRevoked)
+	expectStatus(t, profile(second), http.StatusOK, "")
+
+	// logout-all cierra las demás
+	third, _ := memoryLogin(t, router, "analyst")
+	w = doRequest(t, router, "logout_all", apiRequest{Method: http.MethodPost, Path: "/api/v1/auth/logout-all", Token: third})
+	expectStatus(t, w, http.StatusOK, "")
+	if revoked := responseField(t, w, "revoked"); revoked != 2.0 {
+		t.Fatalf("revoked = %v, want 2", revoked)
+	}
+	expectStatus(t, profile(second), http.StatusUnauthorized, codeSessionRevoked)
+}
+
+func TestHandlersDeletedUserLosesSession(t *testing.T) {
+	_, router := newMemoryApp(t)
+	admin, _ := memoryLogin(t, router, "admin")
+	viewer, refresh := memoryLogin(t, router, "viewer")
+
+	w := doRequest(t, router, "delete_user", apiRequest{Method: http.MethodDelete, Path: "/api/v1/admin/users/3", Token: admin})
+	expectStatus(t, w, http.StatusOK, "")
+
+	w = doRequest(t, router, "profile", apiRequest{Method: http.MethodGet, Path: "/api/v1/profile", Token: viewer})
+	expectStatus(t, w, http.StatusUnauthorized, codeSessionRevoked)
+	w = doRequest(t, router, "refresh", apiRequest{Method: http.MethodPost, Path: "/api/v1/auth/refresh",
+		Body: map[string]string{"refresh_token": refresh}})
+	expectStatus(t, w, http.StatusUnauthorized, codeInvalidRefreshToken)
+}
+
+func TestHandlersRoles(t *testing.T) {
+	_, router := newMemoryApp(t)
+	admin, _ := memoryLogin(t, router, "admin")
+	analyst, _ := memoryLogin(t, router, "analyst")
+	call := func(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+		return doRequest(t, router, "roles", apiRequest{Method: method, Path: path, Token: token, Body: body})
+	}
+
+	expectStatus(t, call(http.MethodGet, "/api/v1/admin/roles", analyst, nil), http.StatusForbidden, codePermissionDenied)
+
+	w := call(http.MethodGet, "/api/v1/admin/roles", admin, nil)
+	expectStatus(t, w, http.StatusOK, "")
+	if roles := responseField(t, w, "data").([]interface{}); len(roles) != len(memoryRoles) {
+		t.Fatalf("listed %d roles, want %d", len(roles), len(memoryRoles))
+	}
+
+	auditor := map[string]interface{}{"name": " Auditor ", "description": "Lee la auditoría", "permissions": []string{permAuditRead}}
+	expectStatus(t, call(http.MethodPost, "/api/v1/admin/roles", admin, auditor), http.StatusCreated, "")
+	expectStatus(t, call(http.MethodPost, "/api/v1/admin/roles", admin, auditor), http.StatusConflict, codeRoleExists)
+	expectStatus(t, call(http.MethodPost, "/api/v1/admin/roles", admin,
+		map[string]interface{}{"name": "bad", "permissions": []string{"root"}}), http.StatusUnprocessableEntity, codeValidationFailed)
+
+	w = call(http.MethodPut, "/api/v1/admin/roles/auditor", admin,
+		map[string]interface{}{"description": "Auditoría y exportación", "permissions": []string{permAuditRead, permAnalyticsExport}})
+	expectStatus(t, w, http.StatusOK, "")
+	if permissions := responseField(t, w, "data", "permissions").([]interface{}); len(permissions) != 2 {
+		t.Fatalf("permissions = %v", permissions)
+	}
+	expectStatus(t, call(http.MethodPut, "/api/v1/admin/roles/ghost", admin,
+		map[string]interface{}{"permissions": []string{}}), http.StatusNotFound, codeRoleNotFound)
+	expectStatus(t, call(http.MethodPut, "/api/v1/admin/roles/admin", admin,
+		map[string]interface{}{"permissions": []string{}}), http.StatusBadRequest, codeRoleProtected)
+
+	// Los permisos del rol se leen en cada petición: el cambio aplica sin nuevo login
+	expectStatus(t, call(http.MethodPatch, "/api/v1/admin/users/2", admin, map[string]string{"role": "auditor"}), http.StatusOK, "")
+	expectStatus(t, call(http.MethodGet, "/api/v1/admin/audit", analyst, nil), http.StatusOK, "")
+
+	w = call(http.MethodDelete, "/api/v1/admin/roles/auditor", admin, nil)
+	expectStatus(t, w, http.StatusConflict, codeRoleInUse)
+	if users := responseField(t, w, "users"); users != 1.0 {
+		t.Fatalf("users = %v, want 1", users)
+	}
+	expectStatus(t, call(http.MethodPatch, "/api/v1/admin/users/2", admin, map[string]string{"role": "analyst"}), http.StatusOK, "")
+	expectStatus(t, call(http.MethodDelete, "/api/v1/admin/roles/auditor", admin, nil), http.StatusOK, "")
+	expectStatus(t, call(http.MethodDelete, "/api/v1/admin/roles/auditor", admin, nil), http.StatusNotFound, codeRoleNotFound)
+	expectStatus(t, call(http.MethodDelete, "/api/v1/admin/roles/user", admin, nil), http.StatusBadRequest, codeRoleProtected)
+}
+
+func TestHandlersAuditLog(t *testing.T) {
+	_, router := newMemoryApp(t, memoryProperties...)
+	admin, _ := memoryLogin(t, router, "admin")
+	call := func(method, path string, body interface{}, headers map[string]string) *httptest.ResponseRecorder {
+		return doRequest(t, router, "audit-request", apiRequest{Method: method, Path: path, Token: admin, Body: body, Headers: headers})
+	}
+
+	expectStatus(t, call(http.MethodPatch, "/api/v1/admin/properties/1", map[string]interface{}{"address": "14 MAPLE ST"},
+		map[string]string{"If-Match": `"1"`}), http.StatusOK, "")
+	expectStatus(t, call(http.MethodDelete, "/api/v1/admin/properties/2", nil, map[string]string{"If-Match": `"1"`}), http.StatusOK, "")
+	expectStatus(t, call(http.MethodPost, "/api/v1/admin/roles", map[string]interface{}{"name": "auditor", "permissions": []string{permAuditRead}}, nil), http.StatusCreated, "")
+
+	// Una escritura masiva que se deshace no deja entradas
+	w := call(http.MethodPost, "/api/v1/admin/properties/bulk", map[string]interface{}{"mode": "atomic", "operations": []map[string]interface{}{
+		{"op": "delete", "id": 3},
+		{"op": "delete", "id": 999},
+	}}, nil)
+	if w.Code == http.StatusOK || responseField(t, w, "committed") == true {
+		t.Fatalf("atomic bulk with a missing row was committed: %s", w.Body.String())
+	}
+
+	w = call(http.MethodGet, "/api/v1/admin/audit", nil, nil)
+	expectStatus(t, w, http.StatusOK, "")
+	entries := responseField(t, w, "data").([]interface{})
+	if len(entries) != 3 {
+		t.Fatalf("got %d audit entries, want 3: %s", len(entries), w.Body.String())
+	}
+	latest := entries[0].(map[string]interface{})
+	if latest["entity"] != "role" || latest["action"] != auditCreate || latest["actor_username"] != "admin" || latest["request_id"] != "audit-request" {
+		t.Errorf("latest entry = %v", latest)
+	}
+	update := entries[2].(map[string]interface{})
+	diff := update["diff"].(map[string]interface{})
+	if update["entity_id"] != "1" || len(diff) != 1 || diff["address"] == nil {
+		t.Errorf("update entry = %v", update)
+	}
+
+	w = call(http.MethodGet, "/api/v1/admin/audit?entity=property&action=delete&actor=admin", nil, nil)
+	expectStatus(t, w, http.StatusOK, "")
+	if entries := responseField(t, w, "data").([]interface{}); len(entries) != 1 || entries[0].(map[string]interface{})["entity_id"] != "2" {
+		t.Errorf("filtered entries = %v", entries)
+	}
+	w = call(http.MethodGet, "/api/v1/admin/audit?actor=1&from=2000-01-01&limit=1&page=2", nil, nil)
+	expectStatus(t, w, http.StatusOK, "")
+	if total := responseField(t, w, "pagination", "total_count"); total != 3.0 {
+		t.Errorf("total_count = %v, want 3", total)
+	}
+	expectStatus(t, call(http.MethodGet, "/api/v1/admin/audit?to=yesterday", nil, nil), http.StatusBadRequest, codeInvalidParameter)
+}
+
+// PATCH sobre filas heredadas que no cumplen las reglas actuales
+func TestHandlersPatchLegacyProperty(t *testing.T) {
+	legacy := Property{SerialNumber: 6, ListYear: 2019, DateRecorded: "2020-02-10", Town: missingValue, Address: "1 MAIN ST",
+		AssessedValue: 70000, SaleAmount: 100000, SalesRatio: 0.9, PropertyType: "Residential", ResidentialType: "Condo", YearsUntilSold: 1}
+	_, router := newMemoryApp(t, append(slices.Clone(memoryProperties), legacy)...)
+	admin, _ := memoryLogin(t, router, "admin")
+	patch := func(body interface{}) *httptest.ResponseRecorder {
+		return doRequest(t, router, "patch", apiRequest{Method: http.MethodPatch, Path: "/api/v1/admin/properties/6", Token: admin, Body: body})
+	}
+
+	w := patch(map[string]interface{}{"address": "3 MAIN ST"})
+	expectStatus(t, w, http.StatusOK, "")
+	if town, ratio := responseField(t, w, "data", "town"), responseField(t, w, "data", "sales_ratio"); town != missingValue || ratio != 0.9 {
+		t.Errorf("untouched values changed: town %v, sales_ratio %v", town, ratio)
+	}
+
+	// Los campos modificados y las reglas que dependen de ellos sí se validan
+	w = patch(map[string]interface{}{"sale_amount": 140000})
+	expectStatus(t, w, http.StatusUnprocessableEntity, codeValidationFailed)
+	if field := responseField(t, w, "errors").([]interface{})[0].(map[string]interface{})["field"]; field != "sales_ratio" {
+		t.Errorf("error field = %v, want sales_ratio", field)
+	}
+	expectStatus(t, patch(map[string]interface{}{"town": "Springfield"}), http.StatusUnprocessableEntity, codeValidationFailed)
+
+	w = patch(map[string]interface{}{"sale_amount": 140000, "sales_ratio": nil, "town": "hartford"})
+	expectStatus(t, w, http.StatusOK, "")
+	if town, ratio := responseField(t, w, "data", "town"), responseField(t, w, "data", "sales_ratio"); town != "Hartford" || ratio != 0.5 {
+		t.Errorf("patched town %v, sales_ratio %v", town, ratio)
+	}
+}
+
+// If-Match con listas de ETags, etiquetas débiles y "*"
+func TestHandlersIfMatch(t *testing.T) {
+	_, router := newMemoryApp(t, memoryProperties...)
+	admin, _ := memoryLogin(t, router, "admin")
+	patch := func(path, ifMatch string) *httptest.ResponseRecorder {
+		return doRequest(t, router, "if-match", apiRequest{Method: http.MethodPatch, Path: path, Token: admin,
+			Body: map[string]string{"address": ifMatch}, Headers: map[string]string{"If-Match": ifMatch}})
+	}
+
+	tests := []struct {
+		ifMatch string
+		status  int
+		etag    string
+	}{
+		{`"1"`, http.StatusOK, `"2"`},
+		{`"1"`, http.StatusPreconditionFailed, ""},
+		{`"1", "2"`, http.StatusOK, `"3"`},
+		{`"4","3" , "5"`, http.StatusOK, `"4"`},
+		{`W/"4"`, http.StatusPreconditionFailed, ""},
+		{`W/"4", "4"`, http.StatusOK, `"5"`},
+		{`"6", "7"`, http.StatusPreconditionFailed, ""},
+		{`"abc", 5`, http.StatusPreconditionFailed, ""},
+		{`*`, http.StatusOK, `"6"`},
+	}
+	for _, tt := range tests {
+		w := patch("/api/v1/admin/properties/1", tt.ifMatch)
+		if w.Code != tt.status || w.Header().Get("ETag") != tt.etag {
+			t.Errorf("If-Match %s: status %d ETag %q, want %d %q: %s", tt.ifMatch, w.Code, w.Header().Get("ETag"), tt.status, tt.etag, w.Body.String())
+		}
+	}
+
+	expectStatus(t, patch("/api/v1/admin/properties/999", `"1", "2"`), http.StatusNotFound, codePropertyNotFound)
+}
+
+// Búsqueda y autocompletado con la semántica de pg_trgm de los stores en memoria
+func TestHandlersSearch(t *testing.T) {
+	_, router := newMemoryApp(t, memoryProperties...)
+	get := func(path string) *httptest.ResponseRecorder {
+		return doRequest(t, router, "search", apiRequest{Method: http.MethodGet, Path: path})
+	}
+
+	for q, want := range map[string][]string{
+		"maple":      {"1:12 <mark>MAPLE</mark> ST, Hartford", "4:88 <mark>MAPLETON</mark> RD, Cheshire"},
+		"hartfrod":   {"1:12 MAPLE ST, <mark>Hartford</mark>", "2:40 ELM AVE, <mark>Hartford</mark>"},
+		"chapl":      {"3:7 <mark>CHAPEL</mark> ST, New Haven"},
+		"elm avenue": {"2:40 <mark>ELM</mark> <mark>AVE</mark>, Hartford"},
+	} {
+		w := get("/api/v1/properties?q=" + url.QueryEscape(q))
+		expectStatus(t, w, http.StatusOK, "")
+		var got []string
+		for _, item := range responseField(t, w, "data").([]interface{}) {
+			match := item.(map[string]interface{})
+			got = append(got, fmt.Sprintf("%v:%v", match["serial_number"], match["highlight"]))
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("q=%s: got %q, want %q", q, got, want)
+		}
+	}
+	expectStatus(t, get("/api/v1/properties?q=%21%3F"), http.StatusBadRequest, "")
+
+	for q, want := range map[string]string{
+		"hart":     "town:Hartford:2",
+		"stamfrod": "town:Stamford:1",
+		"chapl":    "address:7 CHAPEL ST:1",
+	} {
+		w := get("/api/v1/properties/suggest?q=" + q)
+		expectStatus(t, w, http.StatusOK, "")
+		suggestions := responseField(t, w, "data").([]interface{})
+		if len(suggestions) == 0 {
+			t.Errorf("suggest q=%s: no suggestions, want %s", q, want)
+			continue
+		}
+		first := suggestions[0].(map[string]interface{})
+		if got := fmt.Sprintf("%v:%v:%v", first["type"], first["value"], first["count"]); got != want {
+			t.Errorf("suggest q=%s: first = %s, want %s", q, got, want)
+		}
+	}
+	expectStatus(t, get("/api/v1/properties/suggest"), http.StatusBadRequest, codeInvalidParameter)
+	expectStatus(t, get("/api/v1/properties/suggest?q=hart&limit=0"), http.StatusBadRequest, codeInvalidParameter)
+}
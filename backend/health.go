@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Información de compilación; se inyecta con
+// go build -ldflags "-X main.buildVersion=... -X main.buildCommit=... -X main.buildTime=..."
+var (
+	buildVersion = "dev"
+	buildCommit  = ""
+	buildTime    = ""
+)
+
+// Tiempo máximo de cada comprobación de dependencias en /readyz
+const readinessTimeout = 2 * time.Second
+
+// Versión y origen del binario en ejecución
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Resultado de una comprobación de dependencia
+type DependencyCheck struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Completar con los datos VCS de go build cuando no se usaron ldflags
+func currentBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   buildVersion,
+		Commit:    buildCommit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
+
+// Comprobar base de datos y migraciones; ready indica si se puede recibir tráfico
+func (app *App) readiness(ctx context.Context) (bool, gin.H) {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	start := time.Now()
+	database := DependencyCheck{Status: "ok"}
+	if err := app.db.Ping(ctx); err != nil {
+		database.Status = "error"
+		database.Error = err.Error()
+	}
+	database.LatencyMS = time.Since(start).Milliseconds()
+
+	migrations := gin.H{"status": "ok"}
+	if database.Status != "ok" {
+		migrations["status"] = "unknown"
+	} else if pending, err := app.migrator.Pending(ctx); err != nil {
+		migrations["status"] = "error"
+		migrations["error"] = err.Error()
+	} else {
+		migrations["pending"] = len(pending)
+		if len(pending) > 0 {
+			migrations["status"] = "pending"
+		}
+	}
+
+	ready := database.Status == "ok" && migrations["status"] == "ok" && !app.draining.Load()
+	return ready, gin.H{
+		"database":   database,
+		"migrations": migrations,
+	}
+}
+
+// Liveness: el proceso atiende peticiones; no depende de servicios externos
+func (app *App) livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "alive",
+		"timestamp": time.Now().UTC(),
+	})
+}
+
+// Readiness: 503 si la base de datos no responde, hay migraciones pendientes
+// o el servidor se está apagando
+func (app *App) readyz(c *gin.Context) {
+	ready, checks := app.readiness(c.Request.Context())
+
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not_ready", http.StatusServiceUnavailable
+	}
+	if app.draining.Load() {
+		status = "draining"
+	}
+
+	c.JSON(code, gin.H{
+		"status":    status,
+		"checks":    checks,
+		"build":     currentBuildInfo(),
+		"timestamp": time.Now().UTC(),
+	})
+}
+
+// Endpoint de salud (compatibilidad): mismo criterio que /readyz
+func (app *App) healthCheck(c *gin.Context) {
+	ready, checks := app.readiness(c.Request.Context())
+
+	status, code := "healthy", http.StatusOK
+	if !ready {
+		status, code = "unhealthy", http.StatusServiceUnavailable
+	}
+
+	build := currentBuildInfo()
+	c.JSON(code, gin.H{
+		"status":    status,
+		"service":   "urbanytics-backend",
+		"version":   build.Version,
+		"build":     build,
+		"checks":    checks,
+		"timestamp": time.Now().UTC(),
+	})
+}
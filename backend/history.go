@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Operación que originó cada revisión del historial
+const (
+	revisionBaseline = "baseline"
+	revisionInsert   = "insert"
+	revisionUpdate   = "update"
+	revisionDelete   = "delete"
+	revisionRestore  = "restore"
+)
+
+// Revisión de una propiedad con su intervalo de vigencia
+type PropertyRevision struct {
+	RevisionID int64                  `json:"revision_id"`
+	Operation  string                 `json:"operation"`
+	ValidFrom  time.Time              `json:"valid_from"`
+	ValidTo    *time.Time             `json:"valid_to"`
+	ChangedBy  *string                `json:"changed_by"`
+	Data       *Property              `json:"data"`
+	Diff       map[string]interface{} `json:"diff"`
+}
+
+// Subconsulta con las propiedades vigentes en el instante del parámetro $argID.
+// Expone deleted_at para admitir los mismos filtros que la tabla actual.
+func propertiesAsOf(argID int) string {
+	return fmt.Sprintf(`(SELECT serial_number, list_year, date_recorded, town, address, assessed_value, sale_amount,
+		sales_ratio, property_type, residential_type, years_until_sold, NULL::timestamptz AS deleted_at
+		FROM property_history
+		WHERE operation <> 'delete' AND valid_from <= $%[1]d AND (valid_to IS NULL OR valid_to > $%[1]d)) AS properties`, argID)
+}
+
+// Leer el parámetro as_of; nil si no se envió
+func asOfFromQuery(c *gin.Context) (*time.Time, error) {
+	value := c.Query("as_of")
+	if value == "" {
+		return nil, nil
+	}
+	t, err := parseTimeParam(value)
+	if err != nil {
+		return nil, errors.New("Invalid 'as_of' time. Use RFC3339 or YYYY-MM-DD")
+	}
+	return &t, nil
+}
+
+// Identificar al autor de los cambios para los triggers de historial
+func setChangeActor(ctx context.Context, tx pgx.Tx, actor *Actor) error {
+	username := ""
+	if actor != nil {
+		username = actor.Username
+	}
+	_, err := tx.Exec(ctx, "SELECT set_config('urbanytics.actor', $1, true)", username)
+	return err
+}
+
+// Calcular el diff de cada revisión respecto a la anterior
+func withRevisionDiffs(revisions []PropertyRevision) []PropertyRevision {
+	var previous map[string]interface{}
+	for i := range revisions {
+		var current map[string]interface{}
+		if revisions[i].Data != nil {
+			current, _ = toJSONMap(revisions[i].Data)
+		}
+		revisions[i].Diff = auditDiff(previous, current)
+		previous = current
+	}
+	return revisions
+}
+
+// Listar revisiones de una propiedad con el diff respecto a la anterior
+func (app *App) getPropertyHistory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		abortProblem(c, http.StatusBadRequest, codeInvalidParameter, "Invalid property ID")
+		return
+	}
+
+	revisions, err := app.properties.History(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to query property history")
+		return
+	}
+	if len(revisions) == 0 {
+		abortProblem(c, http.StatusNotFound, codePropertyNotFound, "Property not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    revisions,
+	})
+}
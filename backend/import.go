@@ -0,0 +1,482 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Límites de la importación masiva
+const (
+	maxImportBytes        = 512 << 20
+	maxImportErrorsListed = 1000
+)
+
+// Valor usado en el dataset para campos de texto vacíos
+const missingValue = "Nan"
+
+// Formatos de fecha aceptados en date_recorded
+var dateRecordedLayouts = []string{"2006-01-02", "01/02/2006", "1/2/2006", "2006-01-02T15:04:05Z07:00", "2006-01-02 15:04:05"}
+
+// Error de validación de una fila importada
+type importRowError struct {
+	Line         int    `json:"line"`
+	SerialNumber string `json:"serial_number,omitempty"`
+	Field        string `json:"field,omitempty"`
+	Message      string `json:"message"`
+}
+
+func (e *importRowError) Error() string {
+	return e.Message
+}
+
+// Resultado de una importación masiva
+type importReport struct {
+	TotalRows       int              `json:"total_rows"`
+	Inserted        int              `json:"inserted"`
+	Updated         int              `json:"updated"`
+	Skipped         int              `json:"skipped"`
+	DryRun          bool             `json:"dry_run"`
+	Errors          []importRowError `json:"errors"`
+	ErrorsTruncated bool             `json:"errors_truncated"`
+}
+
+// Registrar error de fila respetando el límite de errores listados
+func (r *importReport) addError(e importRowError) {
+	if len(r.Errors) >= maxImportErrorsListed {
+		r.ErrorsTruncated = true
+		return
+	}
+	r.Errors = append(r.Errors, e)
+}
+
+// Lector de registros de propiedades (CSV o NDJSON)
+type propertyRecordReader interface {
+	// Devuelve el número de línea y el registro normalizado; io.EOF al terminar
+	Next() (int, map[string]string, error)
+}
+
+// Normalizar nombre de columna: "Serial Number" -> "serial_number"
+func normalizeFieldName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+	return name
+}
+
+// Lector CSV con cabecera en el formato del dataset de Connecticut
+type csvRecordReader struct {
+	reader *csv.Reader
+	header []string
+}
+
+func newCSVRecordReader(r io.Reader) (*csvRecordReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+
+	normalized := make([]string, len(header))
+	for i, name := range header {
+		normalized[i] = normalizeFieldName(name)
+	}
+	return &csvRecordReader{reader: reader, header: normalized}, nil
+}
+
+func (r *csvRecordReader) Next() (int, map[string]string, error) {
+	values, err := r.reader.Read()
+	if err != nil {
+		return 0, nil, err
+	}
+	line, _ := r.reader.FieldPos(0)
+
+	record := make(map[string]string, len(r.header))
+	for i, name := range r.header {
+		if i < len(values) {
+			record[name] = strings.TrimSpace(values[i])
+		}
+	}
+	return line, record, nil
+}
+
+// Lector NDJSON: un objeto Property por línea
+type ndjsonRecordReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONRecordReader(r io.Reader) *ndjsonRecordReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	return &ndjsonRecordReader{scanner: scanner}
+}
+
+func (r *ndjsonRecordReader) Next() (int, map[string]string, error) {
+	for r.scanner.Scan() {
+		r.line++
+		text := strings.TrimSpace(r.scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var raw map[string]interface{}
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.UseNumber()
+		if err := decoder.Decode(&raw); err != nil {
+			return r.line, nil, &importRowError{Line: r.line, Message: "Invalid JSON: " + err.Error()}
+		}
+
+		record := make(map[string]string, len(raw))
+		for key, value := range raw {
+			if value == nil {
+				continue
+			}
+			record[normalizeFieldName(key)] = strings.TrimSpace(fmt.Sprint(value))
+		}
+		return r.line, record, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return r.line, nil, err
+	}
+	return r.line, nil, io.EOF
+}
+
+// Validador de filas importadas: convierte los campos y aplica las reglas de dominio
+type propertyRecordValidator struct {
+	rules *propertyValidator
+}
+
+// Cargar los valores de referencia para validar las filas
+func (app *App) newPropertyRecordValidator(ctx context.Context) (*propertyRecordValidator, error) {
+	ref, err := app.properties.Reference(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &propertyRecordValidator{rules: newPropertyValidator(ref, time.Now())}, nil
+}
+
+// Convertir un registro en Property y devolver todos los errores encontrados
+func (v *propertyRecordValidator) validate(line int, record map[string]string) (Property, []importRowError) {
+	var p Property
+	var errs []importRowError
+	serial := record["serial_number"]
+
+	fail := func(field, message string) {
+		errs = append(errs, importRowError{Line: line, SerialNumber: serial, Field: field, Message: message})
+	}
+
+	parseFloat := func(field string, required bool) float64 {
+		raw := strings.NewReplacer("$", "", ",", "").Replace(record[field])
+		if raw == "" {
+			if required {
+				fail(field, "is required")
+			}
+			return 0
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			fail(field, "must be a number")
+			return 0
+		}
+		return value
+	}
+
+	parseInt := func(field string, required bool, message string) int {
+		raw := record[field]
+		if raw == "" {
+			if required {
+				fail(field, "is required")
+			}
+			return 0
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			fail(field, message)
+		}
+		return value
+	}
+
+	if serial == "" {
+		fail("serial_number", "is required")
+	} else if n, err := strconv.ParseInt(serial, 10, 64); err != nil {
+		fail("serial_number", "must be a positive integer")
+	} else {
+		p.SerialNumber = n
+	}
+
+	p.ListYear = parseInt("list_year", true, "must be an integer")
+	p.DateRecorded = record["date_recorded"]
+	p.Town = record["town"]
+	p.Address = record["address"]
+	p.PropertyType = record["property_type"]
+	p.ResidentialType = record["residential_type"]
+	p.AssessedValue = parseFloat("assessed_value", true)
+	p.SaleAmount = parseFloat("sale_amount", true)
+	// sales_ratio y years_until_sold se calculan si no vienen en el archivo
+	p.SalesRatio = parseFloat("sales_ratio", false)
+	p.YearsUntilSold = parseInt("years_until_sold", false, "must be a non-negative integer")
+
+	// Las reglas de dominio solo tienen sentido sobre campos bien formados
+	if len(errs) > 0 {
+		return p, errs
+	}
+	for _, e := range v.rules.validate(&p) {
+		fail(e.Field, e.Message)
+	}
+	return p, errs
+}
+
+// Campos de texto vacíos se guardan como en el dataset original
+func textOrMissing(value string) string {
+	if value == "" {
+		return missingValue
+	}
+	return value
+}
+
+// Detectar formato de la carga a partir del formato explícito, Content-Type o extensión
+func detectImportFormat(format, filename, contentType string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	switch {
+	case strings.Contains(contentType, "ndjson"), strings.Contains(contentType, "jsonlines"):
+		return "ndjson"
+	case strings.Contains(contentType, "csv"):
+		return "csv"
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	}
+	return "csv"
+}
+
+// Crear el lector de registros para el formato indicado
+func newPropertyRecordReader(format string, r io.Reader) (propertyRecordReader, error) {
+	switch format {
+	case "csv":
+		return newCSVRecordReader(r)
+	case "ndjson":
+		return newNDJSONRecordReader(r), nil
+	default:
+		return nil, errors.New("Invalid format. Must be 'csv' or 'ndjson'")
+	}
+}
+
+// Importar propiedades masivamente desde CSV o NDJSON (admin)
+func (app *App) importProperties(c *gin.Context) {
+	mode := c.DefaultQuery("mode", "upsert")
+	if mode != "upsert" && mode != "insert" {
+		abortProblem(c, http.StatusBadRequest, codeInvalidParameter, "Invalid mode. Must be 'upsert' or 'insert'")
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	// Aceptar multipart (campo "file") o el cuerpo crudo
+	body := io.Reader(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes))
+	filename := ""
+	contentType := c.ContentType()
+	if strings.HasPrefix(contentType, "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			abortProblem(c, http.StatusBadRequest, codeInvalidUpload, "File field 'file' is required")
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			abortProblem(c, http.StatusBadRequest, codeInvalidUpload, "Failed to read uploaded file")
+			return
+		}
+		defer file.Close()
+		body = file
+		filename = fileHeader.Filename
+		contentType = fileHeader.Header.Get("Content-Type")
+	}
+
+	reader, err := newPropertyRecordReader(detectImportFormat(c.Query("format"), filename, contentType), body)
+	if err != nil {
+		abortProblem(c, http.StatusBadRequest, codeInvalidParameter, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+	validator, err := app.newPropertyRecordValidator(ctx)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to load reference data")
+		return
+	}
+
+	report, err := app.runPropertyImport(ctx, actorFromContext(c), reader, validator, mode, dryRun)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			abortProblem(c, http.StatusRequestEntityTooLarge, codeUploadTooLarge, "Upload too large")
+			return
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			abortProblem(c, http.StatusBadRequest, codeInvalidUpload, "Malformed CSV: "+parseErr.Error())
+			return
+		}
+		log.Printf("Import error: %v", err)
+		internalError(c, "Failed to import properties")
+		return
+	}
+
+	log.Printf("Import finished: %d rows, %d inserted, %d updated, %d skipped", report.TotalRows, report.Inserted, report.Updated, report.Skipped)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// Ejecutar la importación en una transacción usando COPY sobre una tabla temporal
+func (app *App) runPropertyImport(ctx context.Context, actor *Actor, reader propertyRecordReader, validator *propertyRecordValidator, mode string, dryRun bool) (*importReport, error) {
+	report := &importReport{DryRun: dryRun, Errors: []importRowError{}}
+
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	if err := setChangeActor(ctx, tx, actor); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		CREATE TEMP TABLE properties_import (
+			line INTEGER NOT NULL,
+			serial_number BIGINT NOT NULL,
+			list_year INTEGER,
+			date_recorded TEXT,
+			town TEXT,
+			address TEXT,
+			assessed_value DOUBLE PRECISION,
+			sale_amount DOUBLE PRECISION,
+			sales_ratio DOUBLE PRECISION,
+			property_type TEXT,
+			residential_type TEXT,
+			years_until_sold INTEGER,
+			address_number TEXT,
+			address_pre_direction TEXT,
+			address_street TEXT,
+			address_suffix TEXT,
+			address_post_direction TEXT,
+			address_unit_type TEXT,
+			address_unit TEXT,
+			address_normalized TEXT
+		) ON COMMIT DROP
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	// Las filas válidas se envían a COPY a medida que se leen
+	staged := 0
+	source := pgx.CopyFromFunc(func() ([]any, error) {
+		for {
+			line, record, err := reader.Next()
+			if err == io.EOF {
+				return nil, nil
+			}
+			if err != nil {
+				var rowErr *importRowError
+				if errors.As(err, &rowErr) {
+					report.TotalRows++
+					report.Skipped++
+					report.addError(*rowErr)
+					continue
+				}
+				return nil, err
+			}
+
+			report.TotalRows++
+			p, errs := validator.validate(line, record)
+			if len(errs) > 0 {
+				report.Skipped++
+				for _, e := range errs {
+					report.addError(e)
+				}
+				continue
+			}
+
+			staged++
+			return append([]any{line}, propertyRowValues(p)...), nil
+		}
+	})
+
+	columns := append([]string{"line"}, strings.Split(propertyRowColumns, ", ")...)
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"properties_import"}, columns, source); err != nil {
+		return nil, err
+	}
+
+	// Si un serial_number se repite en el archivo gana la última aparición;
+	// reimportar una propiedad eliminada la restaura
+	conflict := "DO UPDATE SET list_year = EXCLUDED.list_year, date_recorded = EXCLUDED.date_recorded, town = EXCLUDED.town, address = EXCLUDED.address, assessed_value = EXCLUDED.assessed_value, sale_amount = EXCLUDED.sale_amount, sales_ratio = EXCLUDED.sales_ratio, property_type = EXCLUDED.property_type, residential_type = EXCLUDED.residential_type, years_until_sold = EXCLUDED.years_until_sold, (" + addressColumns + ") = (" + excludedColumns(addressColumns) + "), deleted_at = NULL, deleted_by = NULL"
+	if mode == "insert" {
+		conflict = "DO NOTHING"
+	}
+	rows, err := tx.Query(ctx, `
+		INSERT INTO properties (`+propertyRowColumns+`)
+		SELECT DISTINCT ON (serial_number) `+propertyRowColumns+`
+		FROM properties_import
+		ORDER BY serial_number, line DESC
+		ON CONFLICT (serial_number) `+conflict+`
+		RETURNING (xmax = 0) AS inserted
+	`)
+	if err != nil {
+		return nil, err
+	}
+	written := 0
+	for rows.Next() {
+		var inserted bool
+		if err := rows.Scan(&inserted); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		written++
+		if inserted {
+			report.Inserted++
+		} else {
+			report.Updated++
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Duplicados dentro del archivo y conflictos ignorados cuentan como omitidos
+	report.Skipped += staged - written
+
+	if dryRun {
+		return report, nil
+	}
+	summary := gin.H{"mode": mode, "total_rows": report.TotalRows, "inserted": report.Inserted, "updated": report.Updated, "skipped": report.Skipped}
+	if err := recordAudit(ctx, tx, actor, auditImport, "property", "", nil, summary); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
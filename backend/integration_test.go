@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Pruebas de integración contra un PostgreSQL efímero.
+//
+// El servidor se crea con initdb en un directorio temporal (PG_BIN indica dónde
+// están los binarios si no están en el PATH) o se usa la base vacía de
+// URBANYTICS_TEST_DATABASE_URL. Sin ninguno de los dos las pruebas se omiten.
+//
+//	go test -run Integration ./...          comparar con testdata/golden
+//	go test -run Integration ./... -update  regenerar los golden tras revisar el cambio
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata/golden")
+
+// Base de datos compartida por las pruebas de integración
+var integration struct {
+	url     string
+	skipped string
+}
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	gin.SetMode(gin.TestMode)
+
+	stop, err := startIntegrationDatabase()
+	if err != nil {
+		integration.skipped = err.Error()
+	}
+	code := m.Run()
+	if stop != nil {
+		stop()
+	}
+	os.Exit(code)
+}
+
+// Omitir la prueba si no hay PostgreSQL disponible
+func requireIntegration(t *testing.T) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("integration tests disabled with -short")
+	}
+	if integration.url == "" {
+		t.Skipf("no PostgreSQL for integration tests: %s", integration.skipped)
+	}
+}
+
+// Arrancar el servidor efímero (o usar la base indicada) y cargar esquema y fixtures
+func startIntegrationDatabase() (func(), error) {
+	if url := os.Getenv("URBANYTICS_TEST_DATABASE_URL"); url != "" {
+		if err := prepareIntegrationDatabase(url); err != nil {
+			return nil, err
+		}
+		integration.url = url
+		return nil, nil
+	}
+
+	initdb, err := findPostgresBinary("initdb")
+	if err != nil {
+		return nil, err
+	}
+	pgCtl := filepath.Join(filepath.Dir(initdb), "pg_ctl")
+
+	dir, err := os.MkdirTemp("", "urbanytics-pg-")
+	if err != nil {
+		return nil, err
+	}
+	data := filepath.Join(dir, "data")
+	cleanup := func() { os.RemoveAll(dir) }
+
+	out, err := exec.Command(initdb, "-D", data, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--locale=C", "--no-sync").CombinedOutput()
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("initdb failed: %v\n%s", err, out)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off -c synchronous_commit=off -c full_page_writes=off", port, dir)
+	out, err = exec.Command(pgCtl, "-D", data, "-o", options, "-l", filepath.Join(dir, "postgres.log"), "-w", "start").CombinedOutput()
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("pg_ctl start failed: %v\n%s", err, out)
+	}
+
+	stop := func() {
+		exec.Command(pgCtl, "-D", data, "-m", "immediate", "stop").Run()
+		cleanup()
+	}
+
+	integration.url = fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port)
+	if err := prepareIntegrationDatabase(integration.url); err != nil {
+		integration.url = ""
+		return stop, err
+	}
+	return stop, nil
+}
+
+// Localizar un binario de PostgreSQL en PG_BIN, el PATH o las rutas habituales
+func findPostgresBinary(name string) (string, error) {
+	if dir := os.Getenv("PG_BIN"); dir != "" {
+		return filepath.Join(dir, name), nil
+	}
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+	for _, pattern := range []string{"/usr/lib/postgresql/*/bin", "/usr/local/pgsql/bin", "/opt/homebrew/opt/postgresql*/bin", "/usr/pgsql-*/bin"} {
+		matches, _ := filepath.Glob(filepath.Join(pattern, name))
+		if len(matches) > 0 {
+			return matches[len(matches)-1], nil
+		}
+	}
+	return "", fmt.Errorf("%s not found (set PG_BIN or URBANYTICS_TEST_DATABASE_URL)", name)
+}
+
+// Puerto TCP libre en la interfaz local
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// Aplicar las migraciones embebidas y cargar testdata/fixtures.sql
+func prepareIntegrationDatabase(url string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	m, err := newMigrator(pool)
+	if err != nil {
+		return err
+	}
+	if _, err := m.Up(ctx); err != nil {
+		return fmt.Errorf("applying migrations: %v", err)
+	}
+
+	fixtures, err := os.ReadFile(filepath.Join("testdata", "fixtures.sql"))
+	if err != nil {
+		return err
+	}
+	if _, err := pool.Exec(ctx, string(fixtures)); err != nil {
+		return fmt.Errorf("loading fixtures: %v", err)
+	}
+	// Las fixtures se insertan sin normalizar, como las filas anteriores a la migración 0011
+	if _, err := normalizeStoredAddresses(ctx, pool, false, 1000); err != nil {
+		return fmt.Errorf("normalizing fixture addresses: %v", err)
+	}
+	return nil
+}
+
+// Aplicación completa conectada a la base de integración
+func newIntegrationApp(t *testing.T) *App {
+	t.Helper()
+
+	config := defaultConfig()
+	config.Database.URL = integration.url
+	config.Auth.JWTSecret = "integration-test-secret-0123456789abcdef"
+	config.Purge.Retention = 0
+
+	app := NewApp(config)
+	if err := app.connectDB(); err != nil {
+		t.Fatalf("connecting to integration database: %v", err)
+	}
+	t.Cleanup(app.db.Close)
+
+	migrator, err := newMigrator(app.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.migrator = migrator
+
+	// El log de peticiones solo ensucia la salida de las pruebas
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return app
+}
+
+// Petición de prueba contra el router
+type apiRequest struct {
+	Method      string
+	Path        string
+	Token       string
+	Body        interface{}
+	ContentType string
+	Headers     map[string]string
+}
+
+// Ejecutar una petición y devolver la respuesta grabada
+func doRequest(t *testing.T, router http.Handler, name string, req apiRequest) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body io.Reader
+	switch b := req.Body.(type) {
+	case nil:
+	case string:
+		body = strings.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body = bytes.NewReader(data)
+		if req.ContentType == "" {
+			req.ContentType = "application/json"
+		}
+	}
+
+	r := httptest.NewRequest(req.Method, req.Path, body)
+	r.Header.Set("X-Request-ID", name)
+	if req.ContentType != "" {
+		r.Header.Set("Content-Type", req.ContentType)
+	}
+	if req.Token != "" {
+		r.Header.Set("Authorization", "Bearer "+req.Token)
+	}
+	for key, value := range req.Headers {
+		r.Header.Set(key, value)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+// Campos que cambian en cada ejecución y se sustituyen antes de comparar
+var volatileFields = map[string]bool{
+	"token":         true,
+	"refresh_token": true,
+	"timestamp":     true,
+	"created_at":    true,
+	"updated_at":    true,
+	"deleted_at":    true,
+	"occurred_at":   true,
+	"valid_from":    true,
+	"valid_to":      true,
+	"latency_ms":    true,
+	"build":         true,
+}
+
+// Reemplazar los valores volátiles por marcadores estables
+func normalizeJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if volatileFields[key] && field != nil {
+				v[key] = "<" + key + ">"
+				continue
+			}
+			v[key] = normalizeJSON(field)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = normalizeJSON(v[i])
+		}
+	}
+	return value
+}
+
+// Comparar status y cuerpo con testdata/golden/<name>; con -update se crea o
+// reescribe y sin él un golden inexistente hace fallar la prueba.
+func assertGolden(t *testing.T, name string, w *httptest.ResponseRecorder) {
+	t.Helper()
+
+	// La configuración expuesta incluye la URL de la base, que cambia con el
+	// servidor de integración, y los errores pueden citar su host y puerto
+	body := w.Body.Bytes()
+	if integration.url != "" {
+		body = bytes.ReplaceAll(body, []byte(integration.url), []byte("<database-url>"))
+	}
+	if host := integrationHost(); host != "" {
+		body = bytes.ReplaceAll(body, []byte(host), []byte("<database-host>"))
+	}
+
+	ext := ".txt"
+	var got []byte
+	contentType, _, _ := strings.Cut(w.Header().Get("Content-Type"), ";")
+	if contentType == "application/json" || contentType == problemContentType {
+		ext = ".json"
+		var decoded interface{}
+		if err := json.Unmarshal(body, &decoded); err != nil {
+			t.Fatalf("%s: invalid JSON response: %v\n%s", name, err, body)
+		}
+		formatted, err := json.MarshalIndent(gin.H{"status": w.Code, "body": normalizeJSON(decoded)}, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(formatted, '\n')
+	} else {
+		got = append([]byte(fmt.Sprintf("status: %d\n\n", w.Code)), body...)
+	}
+
+	path := filepath.Join("testdata", "golden", name+ext)
+	want, err := os.ReadFile(path)
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if os.IsNotExist(err) {
+		t.Fatalf("%s: missing golden file %s (run with -update to create it)", name, path)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Errorf("%s: response differs from %s (run with -update to accept)\n--- want\n%s\n--- got\n%s", name, path, want, got)
+	}
+}
+
+// host:port del servidor de integración
+func integrationHost() string {
+	start := strings.Index(integration.url, "@")
+	if start < 0 {
+		return ""
+	}
+	rest := integration.url[start+1:]
+	if end := strings.IndexAny(rest, "/?"); end >= 0 {
+		rest = rest[:end]
+	}
+	return rest
+}
+
+// Indica si la ruta concreta corresponde a la plantilla registrada en gin
+func matchRoute(template, path string) bool {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	templateParts := strings.Split(strings.Trim(template, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(templateParts) != len(pathParts) {
+		return false
+	}
+	for i, part := range templateParts {
+		if !strings.HasPrefix(part, ":") && part != pathParts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Extraer un campo de primer nivel de una respuesta JSON
+func responseField(t *testing.T, w *httptest.ResponseRecorder, path ...string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &value); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	for _, key := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			t.Fatalf("field %q not found in %s", key, w.Body.String())
+		}
+		value = m[key]
+	}
+	return value
+}
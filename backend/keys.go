@@ -0,0 +1,280 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Archivo opcional con la programación de rotación de las claves
+const keyScheduleFile = "keys.json"
+
+// Clave de firma cargada desde disco e identificada por kid
+type signingKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	Private   crypto.Signer
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// Ventana de validez de una clave definida en keys.json
+type keySchedule struct {
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+// Conjunto de claves asimétricas con rotación programada
+type keyRing struct {
+	dir   string
+	grace time.Duration
+	mu    sync.RWMutex
+	keys  map[string]*signingKey
+}
+
+// Cargar el anillo de claves desde un directorio de archivos <kid>.pem
+func loadKeyRing(dir string, grace time.Duration) (*keyRing, error) {
+	kr := &keyRing{dir: dir, grace: grace}
+	if err := kr.reload(); err != nil {
+		return nil, err
+	}
+	return kr, nil
+}
+
+// Releer claves y programación desde disco
+func (kr *keyRing) reload() error {
+	schedule := make(map[string]keySchedule)
+	data, err := os.ReadFile(filepath.Join(kr.dir, keyScheduleFile))
+	if err == nil {
+		if err := json.Unmarshal(data, &schedule); err != nil {
+			return fmt.Errorf("invalid %s: %v", keyScheduleFile, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	files, err := filepath.Glob(filepath.Join(kr.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*signingKey, len(files))
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		key, err := readSigningKey(file)
+		if err != nil {
+			return fmt.Errorf("key %s: %v", kid, err)
+		}
+		key.ID = kid
+		key.NotBefore = schedule[kid].NotBefore
+		key.NotAfter = schedule[kid].NotAfter
+		keys[kid] = key
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no *.pem signing keys found in %s", kr.dir)
+	}
+
+	kr.mu.Lock()
+	kr.keys = keys
+	kr.mu.Unlock()
+	return nil
+}
+
+// Recargar periódicamente para aplicar rotaciones sin reiniciar
+func (kr *keyRing) watch(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		for range time.Tick(interval) {
+			if err := kr.reload(); err != nil {
+				log.Printf("Failed to reload signing keys: %v", err)
+			}
+		}
+	}()
+}
+
+// Leer clave privada PKCS#8 o PKCS#1 (RSA o Ed25519)
+func readSigningKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		return &signingKey{Method: jwt.SigningMethodRS256, Private: key}, nil
+	case ed25519.PrivateKey:
+		return &signingKey{Method: jwt.SigningMethodEdDSA, Private: key}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+}
+
+// Indica si la clave puede firmar en el instante dado
+func (k *signingKey) activeAt(now time.Time) bool {
+	return !now.Before(k.NotBefore) && (k.NotAfter.IsZero() || now.Before(k.NotAfter))
+}
+
+// Clave de firma vigente: la activada más recientemente
+func (kr *keyRing) signingKey(now time.Time) (*signingKey, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	var current *signingKey
+	for _, key := range kr.keys {
+		if !key.activeAt(now) {
+			continue
+		}
+		if current == nil || key.NotBefore.After(current.NotBefore) ||
+			(key.NotBefore.Equal(current.NotBefore) && key.ID > current.ID) {
+			current = key
+		}
+	}
+	if current == nil {
+		return nil, errors.New("no active signing key")
+	}
+	return current, nil
+}
+
+// Clave para verificar un token; las retiradas se aceptan durante la gracia
+func (kr *keyRing) verificationKey(kid string, now time.Time) (*signingKey, bool) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	key, ok := kr.keys[kid]
+	if !ok || (!key.NotAfter.IsZero() && now.After(key.NotAfter.Add(kr.grace))) {
+		return nil, false
+	}
+	return key, true
+}
+
+// Claves públicas en formato JWK, incluidas las programadas a futuro
+func (kr *keyRing) jwks(now time.Time) []gin.H {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	ids := make([]string, 0, len(kr.keys))
+	for kid, key := range kr.keys {
+		if key.NotAfter.IsZero() || now.Before(key.NotAfter.Add(kr.grace)) {
+			ids = append(ids, kid)
+		}
+	}
+	sort.Strings(ids)
+
+	keys := make([]gin.H, 0, len(ids))
+	for _, kid := range ids {
+		key := kr.keys[kid]
+		jwk := gin.H{"kid": kid, "use": "sig", "alg": key.Method.Alg()}
+		switch public := key.Private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk["kty"] = "RSA"
+			jwk["n"] = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk["kty"] = "OKP"
+			jwk["crv"] = "Ed25519"
+			jwk["x"] = base64.RawURLEncoding.EncodeToString(public)
+		}
+		keys = append(keys, jwk)
+	}
+	return keys
+}
+
+// Cargar claves de firma si se configuró un directorio
+func (app *App) loadSigningKeys() error {
+	if app.config.Auth.KeysDir == "" {
+		log.Printf("⚠️  JWT_KEYS_DIR not set, signing tokens with HS256 shared secret")
+		return nil
+	}
+
+	keys, err := loadKeyRing(app.config.Auth.KeysDir, app.config.Auth.AccessTokenTTL)
+	if err != nil {
+		return fmt.Errorf("unable to load signing keys: %v", err)
+	}
+	keys.watch(app.config.Auth.KeysReload)
+	app.keys = keys
+	return nil
+}
+
+// Firmar claims con la clave vigente (o el secreto HS256 si no hay claves)
+func (app *App) signToken(claims jwt.Claims) (string, error) {
+	if app.keys == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(app.config.Auth.JWTSecret))
+	}
+
+	key, err := app.keys.signingKey(time.Now())
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// Resolver la clave de verificación a partir del kid del token
+func (app *App) verificationKey(token *jwt.Token) (interface{}, error) {
+	if app.keys == nil {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, errInvalidToken
+		}
+		return []byte(app.config.Auth.JWTSecret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := app.keys.verificationKey(kid, time.Now())
+	if !ok || token.Method.Alg() != key.Method.Alg() {
+		return nil, errInvalidToken
+	}
+	return key.Private.Public(), nil
+}
+
+// Publicar claves públicas para verificación local de tokens
+func (app *App) getJWKS(c *gin.Context) {
+	keys := []gin.H{}
+	if app.keys != nil {
+		keys = app.keys.jwks(time.Now())
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
@@ -0,0 +1,1536 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Implementaciones en memoria de los stores para desarrollo y pruebas. Replican
+// la semántica de las consultas SQL (filtros, orden, papelera, historial,
+// sesiones y auditoría) sin PostgreSQL.
+
+// Tramos de sales_ratio en el orden del gráfico; el último no tiene límite
+var salesRatioBuckets = []struct {
+	Label string
+	Below float64
+}{
+	{"< 80%", 0.8},
+	{"80-90%", 0.9},
+	{"90-100%", 1.0},
+	{"100-110%", 1.1},
+	{"110-120%", 1.2},
+	{"> 120%", math.Inf(1)},
+}
+
+// Tramos de años hasta la venta; el último agrupa 5 o más
+var timeToSellBuckets = []string{"0 años", "1 año", "2 años", "3 años", "4 años", "5+ años"}
+
+// Comparar dos valores de columna del mismo tipo
+func compareValues(a, b interface{}) int {
+	switch x := a.(type) {
+	case string:
+		return strings.Compare(x, b.(string))
+	case int:
+		return compareOrdered(x, b.(int))
+	case int64:
+		return compareOrdered(x, b.(int64))
+	case float64:
+		return compareOrdered(x, b.(float64))
+	}
+	return 0
+}
+
+func compareOrdered[T int | int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Comparar una fila con los valores dados en el orden de fields
+func compareSortValues(fields []sortField, p *Property, values []interface{}) int {
+	for i, f := range fields {
+		cmp := compareValues(f.column().Value(p), values[i])
+		if f.Desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+// Redondear a dos decimales como ROUND(x, 2)
+func round2(x float64) float64 {
+	return math.Round(x*100) / 100
+}
+
+// Propiedad almacenada con su estado de papelera
+type memProperty struct {
+	Property
+	Version   int64
+	DeletedAt *time.Time
+	DeletedBy *string
+}
+
+// PropertyStore en memoria
+type memPropertyStore struct {
+	mu           sync.RWMutex
+	rows         map[int64]*memProperty
+	revisions    map[int64][]PropertyRevision
+	nextRevision int64
+	audit        *memAuditStore // nil no audita
+}
+
+func newMemPropertyStore(seed ...Property) *memPropertyStore {
+	s := &memPropertyStore{
+		rows:      make(map[int64]*memProperty),
+		revisions: make(map[int64][]PropertyRevision),
+	}
+	now := time.Now()
+	for _, p := range seed {
+		s.rows[p.SerialNumber] = &memProperty{Property: p, Version: 1}
+		s.appendRevision(p, revisionBaseline, now, nil)
+	}
+	return s
+}
+
+// Cerrar la revisión vigente y añadir una nueva, como el trigger de historial
+func (s *memPropertyStore) appendRevision(p Property, operation string, now time.Time, actor *Actor) {
+	revisions := s.revisions[p.SerialNumber]
+	for i := range revisions {
+		if revisions[i].ValidTo == nil {
+			revisions[i].ValidTo = &now
+		}
+	}
+
+	s.nextRevision++
+	r := PropertyRevision{
+		RevisionID: s.nextRevision,
+		Operation:  operation,
+		ValidFrom:  now,
+	}
+	if username := actorUsername(actor); username != "" {
+		r.ChangedBy = &username
+	}
+	data := p
+	r.Data = &data
+	if operation == revisionDelete {
+		r.ValidTo = &now
+	}
+	s.revisions[p.SerialNumber] = append(revisions, r)
+}
+
+// Estado de una propiedad vigente en el instante t
+func (s *memPropertyStore) propertyAsOf(id int64, t time.Time) (Property, bool) {
+	for _, r := range s.revisions[id] {
+		if r.Operation != revisionDelete && !r.ValidFrom.After(t) && (r.ValidTo == nil || r.ValidTo.After(t)) {
+			return *r.Data, true
+		}
+	}
+	return Property{}, false
+}
+
+// Propiedades visibles para un filtro: las actuales o las vigentes en AsOf
+func (s *memPropertyStore) source(filter PropertyFilter) []Property {
+	var properties []Property
+	if filter.AsOf != nil {
+		for id := range s.revisions {
+			if p, ok := s.propertyAsOf(id, *filter.AsOf); ok {
+				properties = append(properties, p)
+			}
+		}
+	} else {
+		for _, row := range s.rows {
+			if row.DeletedAt == nil {
+				properties = append(properties, row.Property)
+			}
+		}
+	}
+
+	matching := properties[:0]
+	for _, p := range properties {
+		if filter.matches(&p) {
+			matching = append(matching, p)
+		}
+	}
+	return matching
+}
+
+// Evaluar el filtro sobre una propiedad con la semántica de propertyFilterConditions
+func (f PropertyFilter) matches(p *Property) bool {
+	switch {
+	case f.Town != "" && !strings.Contains(strings.ToLower(p.Town), strings.ToLower(f.Town)):
+		return false
+	case len(f.Towns) > 0 && !slices.Contains(f.Towns, p.Town):
+		return false
+	case f.MinPrice != nil && p.SaleAmount < *f.MinPrice:
+		return false
+	case f.MaxPrice != nil && p.SaleAmount > *f.MaxPrice:
+		return false
+	case f.PropertyType != "" && p.PropertyType != f.PropertyType:
+		return false
+	case f.ResidentialType != "" && p.ResidentialType != f.ResidentialType:
+		return false
+	case f.ListYear != nil && p.ListYear != *f.ListYear:
+		return false
+	case f.Status == "sold" && !(p.SaleAmount > 0):
+		return false
+	case f.Status == "available" && p.SaleAmount != 0:
+		return false
+	case f.MinSalesRatio != nil && p.SalesRatio < *f.MinSalesRatio:
+		return false
+	case f.MaxSalesRatio != nil && p.SalesRatio > *f.MaxSalesRatio:
+		return false
+	case f.MinYearsUntilSold != nil && p.YearsUntilSold < *f.MinYearsUntilSold:
+		return false
+	case f.MaxYearsUntilSold != nil && p.YearsUntilSold > *f.MaxYearsUntilSold:
+		return false
+	case f.Search != "" && !searchMatches(f.Search, p):
+		return false
+	}
+	return true
+}
+
+// Búsqueda de texto con la semántica de la condición SQL: todas las palabras como
+// prefijo en dirección o ciudad, o parecido por trigramas con alguna de las dos
+func searchMatches(q string, p *Property) bool {
+	return prefixMatch(searchTerms(q), searchText(p)) ||
+		wordSimilarity(q, p.Address) >= wordSimilarityThreshold ||
+		townSimilarity(q, p.Town) >= similarityThreshold
+}
+
+// Aproximación de la relevancia SQL: ts_rank se sustituye por un valor fijo si
+// todas las palabras coinciden como prefijo
+func searchRelevance(q string, p *Property) float64 {
+	relevance := math.Max(wordSimilarity(q, p.Address), townSimilarity(q, p.Town))
+	if prefixMatch(searchTerms(q), searchText(p)) {
+		relevance += 0.1
+	}
+	return math.Round(relevance*10000) / 10000
+}
+
+func (s *memPropertyStore) List(ctx context.Context, q PropertyQuery) ([]Property, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	properties := s.source(q.Filter)
+	sort.SliceStable(properties, func(i, j int) bool {
+		values := make([]interface{}, len(q.Sort))
+		for k, f := range q.Sort {
+			values[k] = f.column().Value(&properties[j])
+		}
+		return compareSortValues(q.Sort, &properties[i], values) < 0
+	})
+
+	if q.After != nil {
+		after := properties[:0]
+		for _, p := range properties {
+			if compareSortValues(q.Sort, &p, q.After) > 0 {
+				after = append(after, p)
+			}
+		}
+		properties = after
+	}
+
+	if q.Offset >= len(properties) {
+		return nil, nil
+	}
+	properties = properties[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(properties) {
+		properties = properties[:q.Limit]
+	}
+	return properties, nil
+}
+
+func (s *memPropertyStore) Search(ctx context.Context, q PropertyQuery) ([]PropertyMatch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matches []PropertyMatch
+	for _, p := range s.source(q.Filter) {
+		matches = append(matches, PropertyMatch{Property: p, Relevance: searchRelevance(q.Filter.Search, &p)})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if len(q.Sort) == 0 {
+			if matches[i].Relevance != matches[j].Relevance {
+				return matches[i].Relevance > matches[j].Relevance
+			}
+			return matches[i].SerialNumber < matches[j].SerialNumber
+		}
+		values := make([]interface{}, len(q.Sort))
+		for k, f := range q.Sort {
+			values[k] = f.column().Value(&matches[j].Property)
+		}
+		return compareSortValues(q.Sort, &matches[i].Property, values) < 0
+	})
+
+	if q.Offset >= len(matches) {
+		return nil, nil
+	}
+	matches = matches[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(matches) {
+		matches = matches[:q.Limit]
+	}
+	return matches, nil
+}
+
+func (s *memPropertyStore) Suggest(ctx context.Context, q string, limit int) ([]PropertySuggestion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	terms := searchTerms(q)
+	type scored struct {
+		PropertySuggestion
+		score float64
+	}
+	groups := make(map[PropertySuggestion]*scored)
+	add := func(key PropertySuggestion, score float64) {
+		if g, ok := groups[key]; ok {
+			g.Count++
+			return
+		}
+		g := &scored{key, score}
+		g.Count = 1
+		groups[key] = g
+	}
+	for _, row := range s.rows {
+		if row.DeletedAt != nil {
+			continue
+		}
+		if town := row.Town; town != missingValue {
+			prefix := strings.HasPrefix(strings.ToLower(town), strings.ToLower(q))
+			if similarity := townSimilarity(q, town); prefix || similarity >= similarityThreshold {
+				add(PropertySuggestion{Type: suggestTown, Value: town}, similarity+boolScore(prefix))
+			}
+		}
+		if address := row.Address; address != missingValue {
+			prefix := prefixMatch(terms, address)
+			if similarity := wordSimilarity(q, address); prefix || similarity >= wordSimilarityThreshold {
+				add(PropertySuggestion{Type: suggestAddress, Value: address, Town: row.Town}, similarity+boolScore(prefix))
+			}
+		}
+	}
+
+	ranked := make([]*scored, 0, len(groups))
+	for _, g := range groups {
+		ranked = append(ranked, g)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		switch {
+		case a.score != b.score:
+			return a.score > b.score
+		case a.Count != b.Count:
+			return a.Count > b.Count
+		case a.Value != b.Value:
+			return a.Value < b.Value
+		}
+		return a.Town < b.Town
+	})
+
+	suggestions := []PropertySuggestion{}
+	for _, g := range paginate(ranked, limit, 0) {
+		suggestions = append(suggestions, g.PropertySuggestion)
+	}
+	return suggestions, nil
+}
+
+// 1 si se cumple, como (condición)::int
+func boolScore(ok bool) float64 {
+	if ok {
+		return 1
+	}
+	return 0
+}
+
+func (s *memPropertyStore) Count(ctx context.Context, filter PropertyFilter) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.source(filter)), nil
+}
+
+func (s *memPropertyStore) Get(ctx context.Context, id int64, asOf *time.Time) (Property, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if asOf != nil {
+		if p, ok := s.propertyAsOf(id, *asOf); ok {
+			return p, nil
+		}
+		return Property{}, errNotFound
+	}
+	row, ok := s.rows[id]
+	if !ok || row.DeletedAt != nil {
+		return Property{}, errNotFound
+	}
+	return row.Property, nil
+}
+
+func (s *memPropertyStore) GetWithVersion(ctx context.Context, id int64) (Property, int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	row, err := s.current(id, 0)
+	if err != nil {
+		return Property{}, 0, err
+	}
+	return row.Property, row.Version, nil
+}
+
+// Valores distintos y ordenados de un campo, sin los registros 'Nan'
+func (s *memPropertyStore) distinct(value func(p *Property) string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var values []string
+	for _, row := range s.rows {
+		v := value(&row.Property)
+		if row.DeletedAt != nil || v == "Nan" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
+
+func (s *memPropertyStore) Towns(ctx context.Context) ([]string, error) {
+	return s.distinct(func(p *Property) string { return p.Town }), nil
+}
+
+func (s *memPropertyStore) PropertyTypes(ctx context.Context) ([]string, error) {
+	return s.distinct(func(p *Property) string { return p.PropertyType }), nil
+}
+
+func (s *memPropertyStore) ResidentialTypes(ctx context.Context) ([]string, error) {
+	return s.distinct(func(p *Property) string { return p.ResidentialType }), nil
+}
+
+// Sin tablas de referencia se aceptan los valores ya presentes en las filas
+func (s *memPropertyStore) Reference(ctx context.Context) (PropertyReference, error) {
+	return PropertyReference{
+		Towns:            s.distinct(func(p *Property) string { return p.Town }),
+		PropertyTypes:    s.distinct(func(p *Property) string { return p.PropertyType }),
+		ResidentialTypes: s.distinct(func(p *Property) string { return p.ResidentialType }),
+	}, nil
+}
+
+func (s *memPropertyStore) ListYears(ctx context.Context) ([]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[int]bool)
+	var years []int
+	for _, row := range s.rows {
+		if row.DeletedAt != nil || seen[row.ListYear] {
+			continue
+		}
+		seen[row.ListYear] = true
+		years = append(years, row.ListYear)
+	}
+	sort.Ints(years)
+	return years, nil
+}
+
+func (s *memPropertyStore) Create(ctx context.Context, actor *Actor, p Property) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.insert(actor, p); err != nil {
+		return err
+	}
+	s.audit.record(actor, auditCreate, "property", p.SerialNumber, nil, p)
+	return nil
+}
+
+// Insertar una propiedad nueva; requiere el bloqueo
+func (s *memPropertyStore) insert(actor *Actor, p Property) error {
+	// La clave primaria incluye las filas en la papelera
+	if _, exists := s.rows[p.SerialNumber]; exists {
+		return errConflict
+	}
+	s.rows[p.SerialNumber] = &memProperty{Property: p, Version: 1}
+	s.appendRevision(p, revisionInsert, time.Now(), actor)
+	return nil
+}
+
+// Fila vigente comprobando la versión esperada (0 no comprueba); requiere el bloqueo
+func (s *memPropertyStore) current(id, version int64) (*memProperty, error) {
+	row, ok := s.rows[id]
+	if !ok || row.DeletedAt != nil {
+		return nil, errNotFound
+	}
+	if version != 0 && version != row.Version {
+		return nil, errVersionMismatch
+	}
+	return row, nil
+}
+
+func (s *memPropertyStore) Update(ctx context.Context, actor *Actor, p Property, version int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	row, err := s.current(p.SerialNumber, version)
+	if err != nil {
+		return 0, err
+	}
+	// Como el UPDATE completo en SQL, se audita aunque no cambie nada
+	before := row.Property
+	s.replace(actor, row, p)
+	s.audit.record(actor, auditUpdate, "property", p.SerialNumber, before, p)
+	return row.Version, nil
+}
+
+// Escribir el nuevo estado si cambia; requiere el bloqueo. Devuelve el estado anterior y si cambió
+func (s *memPropertyStore) replace(actor *Actor, row *memProperty, p Property) (Property, bool) {
+	before := row.Property
+	if before == p {
+		return before, false
+	}
+	row.Property = p
+	row.Version++
+	s.appendRevision(p, revisionUpdate, time.Now(), actor)
+	return before, true
+}
+
+// El parche se aplica fuera del bloqueo (apply puede consultar el store) y se
+// vuelve a aplicar si la fila cambió entretanto
+func (s *memPropertyStore) Patch(ctx context.Context, actor *Actor, id int64, version int64, apply func(p *Property) error) (Property, int64, error) {
+	for {
+		s.mu.RLock()
+		row, err := s.current(id, version)
+		var before Property
+		var seen int64
+		if err == nil {
+			before, seen = row.Property, row.Version
+		}
+		s.mu.RUnlock()
+		if err != nil {
+			return Property{}, 0, err
+		}
+
+		after := before
+		if err := apply(&after); err != nil {
+			return Property{}, 0, err
+		}
+
+		s.mu.Lock()
+		row, err = s.current(id, 0)
+		switch {
+		case err != nil:
+			s.mu.Unlock()
+			return Property{}, 0, err
+		case row.Version != seen:
+			s.mu.Unlock()
+			continue
+		}
+		if before, changed := s.replace(actor, row, after); changed {
+			s.audit.record(actor, auditUpdate, "property", id, before, after)
+		}
+		updated := row.Version
+		s.mu.Unlock()
+		return after, updated, nil
+	}
+}
+
+func (s *memPropertyStore) Delete(ctx context.Context, actor *Actor, id int64, version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before, err := s.remove(actor, id, version)
+	if err != nil {
+		return err
+	}
+	s.audit.record(actor, auditDelete, "property", id, before, nil)
+	return nil
+}
+
+// Mover una propiedad a la papelera y devolver su estado; requiere el bloqueo
+func (s *memPropertyStore) remove(actor *Actor, id, version int64) (Property, error) {
+	row, err := s.current(id, version)
+	if err != nil {
+		return Property{}, err
+	}
+	now := time.Now()
+	username := actorUsername(actor)
+	row.DeletedAt, row.DeletedBy = &now, &username
+	row.Version++
+	s.appendRevision(row.Property, revisionDelete, now, actor)
+	return row.Property, nil
+}
+
+// Estado de las filas tocadas por una escritura masiva, para deshacerla
+type memUndo struct {
+	rows         map[int64]*memProperty // nil si la fila no existía
+	revisions    map[int64][]PropertyRevision
+	nextRevision int64
+}
+
+// Guardar el estado de la fila antes de su primer cambio
+func (s *memPropertyStore) remember(undo *memUndo, id int64) {
+	if _, saved := undo.rows[id]; saved {
+		return
+	}
+	var previous *memProperty
+	if row, ok := s.rows[id]; ok {
+		copied := *row
+		previous = &copied
+	}
+	undo.rows[id] = previous
+	undo.revisions[id] = append([]PropertyRevision(nil), s.revisions[id]...)
+}
+
+// Volver al estado guardado
+func (s *memPropertyStore) rollback(undo *memUndo) {
+	for id, row := range undo.rows {
+		if row == nil {
+			delete(s.rows, id)
+		} else {
+			s.rows[id] = row
+		}
+		if revisions := undo.revisions[id]; len(revisions) > 0 {
+			s.revisions[id] = revisions
+		} else {
+			delete(s.revisions, id)
+		}
+	}
+	s.nextRevision = undo.nextRevision
+}
+
+// El bloqueo se mantiene durante toda la operación, por eso Apply no puede consultar el store
+func (s *memPropertyStore) Bulk(ctx context.Context, actor *Actor, ops []PropertyOperation, atomic bool) ([]PropertyOperationResult, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	undo := &memUndo{rows: make(map[int64]*memProperty), revisions: make(map[int64][]PropertyRevision), nextRevision: s.nextRevision}
+	var results []PropertyOperationResult
+	// La auditoría se escribe solo si la operación se confirma
+	var audits []memAuditChange
+	failed := false
+	for i, op := range ops {
+		ids := []int64{op.ID}
+		if op.Kind == bulkCreate {
+			ids = []int64{op.Property.SerialNumber}
+		}
+		if op.Filter != nil {
+			ids = nil
+			for _, p := range s.source(*op.Filter) {
+				ids = append(ids, p.SerialNumber)
+			}
+			sort.Slice(ids, func(a, b int) bool { return ids[a] < ids[b] })
+		}
+
+		for _, id := range ids {
+			if err := ctx.Err(); err != nil {
+				s.rollback(undo)
+				return nil, false, err
+			}
+			result := PropertyOperationResult{Index: i, ID: id}
+			s.remember(undo, id)
+			switch op.Kind {
+			case bulkCreate:
+				p := op.Property
+				if result.Err = applyOptional(op.Apply, &p); result.Err == nil {
+					result.Err = s.insert(actor, p)
+				}
+				if result.Err == nil {
+					result.Version = 1
+					audits = append(audits, memAuditChange{auditCreate, p.SerialNumber, nil, p})
+				}
+			case bulkUpdate:
+				row, err := s.current(id, op.Version)
+				if err == nil {
+					after := row.Property
+					if err = op.Apply(&after); err == nil {
+						if before, changed := s.replace(actor, row, after); changed {
+							audits = append(audits, memAuditChange{auditUpdate, id, before, after})
+						}
+						result.Version = row.Version
+					}
+				}
+				result.Err = err
+			case bulkDelete:
+				var before Property
+				if before, result.Err = s.remove(actor, id, op.Version); result.Err == nil {
+					audits = append(audits, memAuditChange{auditDelete, id, before, nil})
+				}
+			default:
+				result.Err = fmt.Errorf("unknown bulk operation %q", op.Kind)
+			}
+			failed = failed || result.Err != nil
+			results = append(results, result)
+		}
+	}
+
+	if failed && atomic {
+		s.rollback(undo)
+		return results, false, nil
+	}
+	for _, change := range audits {
+		s.audit.record(actor, change.action, "property", change.id, change.before, change.after)
+	}
+	return results, true, nil
+}
+
+func (s *memPropertyStore) LookupAddress(ctx context.Context, normalized, town string, limit int) ([]Property, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matches []Property
+	for _, row := range s.rows {
+		if row.DeletedAt == nil && (town == "" || row.Town == town) && normalizeAddress(row.Address).Normalized == normalized {
+			matches = append(matches, row.Property)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].SerialNumber < matches[j].SerialNumber })
+	return paginate(matches, limit, 0), nil
+}
+
+func (s *memPropertyStore) AddressDuplicates(ctx context.Context, town string, limit, offset int) ([]AddressDuplicate, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type groupKey struct{ town, address string }
+	groups := make(map[groupKey][]int64)
+	for _, row := range s.rows {
+		address := normalizeAddress(row.Address).Normalized
+		if row.DeletedAt != nil || address == "" || (town != "" && row.Town != town) {
+			continue
+		}
+		key := groupKey{row.Town, address}
+		groups[key] = append(groups[key], row.SerialNumber)
+	}
+
+	duplicates := []AddressDuplicate{}
+	for key, ids := range groups {
+		if len(ids) > 1 {
+			slices.Sort(ids)
+			duplicates = append(duplicates, AddressDuplicate{Town: key.town, Address: key.address, Count: len(ids), SerialNumbers: ids})
+		}
+	}
+	sort.Slice(duplicates, func(i, j int) bool {
+		a, b := duplicates[i], duplicates[j]
+		switch {
+		case a.Count != b.Count:
+			return a.Count > b.Count
+		case a.Town != b.Town:
+			return a.Town < b.Town
+		}
+		return a.Address < b.Address
+	})
+	return paginate(duplicates, limit, offset), len(duplicates), nil
+}
+
+func (s *memPropertyStore) ListDeleted(ctx context.Context, limit, offset int) ([]DeletedProperty, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deleted := []DeletedProperty{}
+	for _, row := range s.rows {
+		if row.DeletedAt != nil {
+			deleted = append(deleted, DeletedProperty{Property: row.Property, DeletedAt: *row.DeletedAt, DeletedBy: row.DeletedBy})
+		}
+	}
+	sort.Slice(deleted, func(i, j int) bool {
+		if !deleted[i].DeletedAt.Equal(deleted[j].DeletedAt) {
+			return deleted[i].DeletedAt.After(deleted[j].DeletedAt)
+		}
+		return deleted[i].SerialNumber < deleted[j].SerialNumber
+	})
+	return paginate(deleted, limit, offset), len(deleted), nil
+}
+
+func (s *memPropertyStore) Restore(ctx context.Context, actor *Actor, id int64) (Property, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	row, ok := s.rows[id]
+	if !ok || row.DeletedAt == nil {
+		return Property{}, errNotFound
+	}
+	row.DeletedAt, row.DeletedBy = nil, nil
+	row.Version++
+	s.appendRevision(row.Property, revisionRestore, time.Now(), actor)
+	s.audit.record(actor, auditRestore, "property", id, nil, row.Property)
+	return row.Property, nil
+}
+
+func (s *memPropertyStore) History(ctx context.Context, id int64) ([]PropertyRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var revisions []PropertyRevision
+	for _, r := range s.revisions[id] {
+		// Una eliminación no tiene estado posterior
+		if r.Operation == revisionDelete {
+			r.Data = nil
+		}
+		revisions = append(revisions, r)
+	}
+	return withRevisionDiffs(revisions), nil
+}
+
+// Página de un listado ya ordenado
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	items = items[offset:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// Usuario almacenado con su contraseña y estado de papelera
+type memUser struct {
+	User
+	PasswordHash string
+	DeletedAt    *time.Time
+	DeletedBy    *string
+}
+
+// UserStore en memoria. Guarda también los roles, que memRoleStore y
+// memSessionStore consultan bajo el mismo bloqueo, como la clave foránea en SQL.
+type memUserStore struct {
+	mu     sync.RWMutex
+	users  map[int]*memUser
+	roles  map[string]Role
+	nextID int
+	audit  *memAuditStore // nil no audita
+}
+
+func newMemUserStore(roles ...Role) *memUserStore {
+	s := &memUserStore{
+		users: make(map[int]*memUser),
+		roles: make(map[string]Role),
+	}
+	for _, role := range roles {
+		role.BuiltIn = builtInRoles[role.Name]
+		s.roles[role.Name] = role
+	}
+	return s
+}
+
+func (s *memUserStore) Credentials(ctx context.Context, username string) (User, string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.Username == username && u.DeletedAt == nil {
+			return u.User, u.PasswordHash, nil
+		}
+	}
+	return User{}, "", errNotFound
+}
+
+func (s *memUserStore) Get(ctx context.Context, id int) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[id]
+	if !ok || u.DeletedAt != nil {
+		return User{}, errNotFound
+	}
+	return u.User, nil
+}
+
+func (s *memUserStore) List(ctx context.Context) ([]User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var users []User
+	for _, u := range s.users {
+		if u.DeletedAt == nil {
+			users = append(users, u.User)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (s *memUserStore) RoleExists(ctx context.Context, role string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, exists := s.roles[role]
+	return exists, nil
+}
+
+func (s *memUserStore) Register(ctx context.Context, u NewUser) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Nombre y email son únicos también entre los eliminados
+	for _, existing := range s.users {
+		if existing.Username == u.Username || existing.Email == u.Email {
+			return User{}, errConflict
+		}
+	}
+
+	s.nextID++
+	now := time.Now()
+	user := User{ID: s.nextID, Username: u.Username, Email: u.Email, Role: u.Role, CreatedAt: now, UpdatedAt: now}
+	s.users[user.ID] = &memUser{User: user, PasswordHash: u.PasswordHash}
+	return user, nil
+}
+
+func (s *memUserStore) Create(ctx context.Context, actor *Actor, u NewUser) (User, error) {
+	created, err := s.Register(ctx, u)
+	if err != nil {
+		return User{}, err
+	}
+	s.audit.record(actor, auditCreate, "user", created.ID, nil, created)
+	return created, nil
+}
+
+func (s *memUserStore) UpdateEmail(ctx context.Context, id int, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return errNotFound
+	}
+	for _, existing := range s.users {
+		if existing.ID != id && existing.Email == email {
+			return errConflict
+		}
+	}
+	u.Email, u.UpdatedAt = email, time.Now()
+	return nil
+}
+
+func (s *memUserStore) Update(ctx context.Context, actor *Actor, id int, email, role string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok || u.DeletedAt != nil {
+		return User{}, errNotFound
+	}
+	for _, existing := range s.users {
+		if existing.ID != id && existing.Email == email {
+			return User{}, errConflict
+		}
+	}
+	before := u.User
+	u.Email, u.Role, u.UpdatedAt = email, role, time.Now()
+	s.audit.record(actor, auditUpdate, "user", id, before, u.User)
+	return u.User, nil
+}
+
+// Igual que memPropertyStore.Patch: apply se ejecuta sin el bloqueo
+func (s *memUserStore) Patch(ctx context.Context, actor *Actor, id int, apply func(u *User) error) (User, error) {
+	for {
+		before, err := s.Get(ctx, id)
+		if err != nil {
+			return User{}, err
+		}
+		after := before
+		if err := apply(&after); err != nil {
+			return User{}, err
+		}
+
+		s.mu.Lock()
+		u, ok := s.users[id]
+		switch {
+		case !ok || u.DeletedAt != nil:
+			s.mu.Unlock()
+			return User{}, errNotFound
+		case u.User != before:
+			s.mu.Unlock()
+			continue
+		}
+		if after.Email != before.Email || after.Role != before.Role {
+			for _, existing := range s.users {
+				if existing.ID != id && existing.Email == after.Email {
+					s.mu.Unlock()
+					return User{}, errConflict
+				}
+			}
+			u.Email, u.Role, u.UpdatedAt = after.Email, after.Role, time.Now()
+			s.audit.record(actor, auditUpdate, "user", id, before, u.User)
+		}
+		after = u.User
+		s.mu.Unlock()
+		return after, nil
+	}
+}
+
+func (s *memUserStore) Delete(ctx context.Context, actor *Actor, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok || u.DeletedAt != nil {
+		return errNotFound
+	}
+	now := time.Now()
+	username := actorUsername(actor)
+	u.DeletedAt, u.DeletedBy = &now, &username
+	s.audit.record(actor, auditDelete, "user", id, u.User, nil)
+	return nil
+}
+
+func (s *memUserStore) ListDeleted(ctx context.Context, limit, offset int) ([]DeletedUser, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deleted := []DeletedUser{}
+	for _, u := range s.users {
+		if u.DeletedAt != nil {
+			deleted = append(deleted, DeletedUser{User: u.User, DeletedAt: *u.DeletedAt, DeletedBy: u.DeletedBy})
+		}
+	}
+	sort.Slice(deleted, func(i, j int) bool {
+		if !deleted[i].DeletedAt.Equal(deleted[j].DeletedAt) {
+			return deleted[i].DeletedAt.After(deleted[j].DeletedAt)
+		}
+		return deleted[i].ID < deleted[j].ID
+	})
+	return paginate(deleted, limit, offset), len(deleted), nil
+}
+
+func (s *memUserStore) Restore(ctx context.Context, actor *Actor, id int) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok || u.DeletedAt == nil {
+		return User{}, errNotFound
+	}
+	u.DeletedAt, u.DeletedBy, u.UpdatedAt = nil, nil, time.Now()
+	s.audit.record(actor, auditRestore, "user", id, nil, u.User)
+	return u.User, nil
+}
+
+// RoleStore en memoria sobre los roles de memUserStore
+type memRoleStore struct {
+	users *memUserStore
+}
+
+func newMemRoleStore(users *memUserStore) *memRoleStore {
+	return &memRoleStore{users: users}
+}
+
+// Permisos ordenados y sin repetir, como array_agg(... ORDER BY permission)
+func sortedPermissions(permissions []string) []string {
+	sorted := append([]string{}, permissions...)
+	slices.Sort(sorted)
+	return slices.Compact(sorted)
+}
+
+func (s *memRoleStore) List(ctx context.Context) ([]Role, error) {
+	s.users.mu.RLock()
+	defer s.users.mu.RUnlock()
+
+	roles := []Role{}
+	for _, role := range s.users.roles {
+		role.Permissions = sortedPermissions(role.Permissions)
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, nil
+}
+
+func (s *memRoleStore) Create(ctx context.Context, actor *Actor, role Role) error {
+	s.users.mu.Lock()
+	defer s.users.mu.Unlock()
+
+	if _, exists := s.users.roles[role.Name]; exists {
+		return errConflict
+	}
+	stored := role
+	stored.Permissions = sortedPermissions(role.Permissions)
+	s.users.roles[role.Name] = stored
+	s.users.audit.record(actor, auditCreate, "role", role.Name, nil, role)
+	return nil
+}
+
+func (s *memRoleStore) Update(ctx context.Context, actor *Actor, role Role) (Role, error) {
+	s.users.mu.Lock()
+	defer s.users.mu.Unlock()
+
+	before, exists := s.users.roles[role.Name]
+	if !exists {
+		return Role{}, errNotFound
+	}
+	role.BuiltIn = builtInRoles[role.Name]
+	stored := role
+	stored.Permissions = sortedPermissions(role.Permissions)
+	s.users.roles[role.Name] = stored
+	s.users.audit.record(actor, auditUpdate, "role", role.Name, before, role)
+	return role, nil
+}
+
+func (s *memRoleStore) Delete(ctx context.Context, actor *Actor, name string) error {
+	s.users.mu.Lock()
+	defer s.users.mu.Unlock()
+
+	before, exists := s.users.roles[name]
+	if !exists {
+		return errNotFound
+	}
+	// La clave foránea incluye a los usuarios en la papelera
+	assigned := 0
+	for _, u := range s.users.users {
+		if u.Role == name {
+			assigned++
+		}
+	}
+	if assigned > 0 {
+		return &roleInUseError{Users: assigned}
+	}
+	delete(s.users.roles, name)
+	s.users.audit.record(actor, auditDelete, "role", name, before, nil)
+	return nil
+}
+
+// Sesión almacenada; solo se guardan los hashes de los refresh tokens
+type memSession struct {
+	ID           int64
+	UserID       int
+	RefreshHash  string
+	PreviousHash string
+	ExpiresAt    time.Time
+	Revoked      bool
+}
+
+// SessionStore en memoria; los usuarios y roles se leen de memUserStore
+type memSessionStore struct {
+	mu       sync.Mutex
+	sessions map[int64]*memSession
+	nextID   int64
+	users    *memUserStore
+}
+
+func newMemSessionStore(users *memUserStore) *memSessionStore {
+	return &memSessionStore{sessions: make(map[int64]*memSession), users: users}
+}
+
+func (s *memSessionStore) Create(ctx context.Context, session NewSession) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	s.sessions[s.nextID] = &memSession{
+		ID:          s.nextID,
+		UserID:      session.UserID,
+		RefreshHash: session.RefreshHash,
+		ExpiresAt:   time.Now().Add(session.TTL),
+	}
+	return s.nextID, nil
+}
+
+// Usuario no eliminado de una sesión activa; requiere el bloqueo de sesiones
+func (s *memSessionStore) activeUser(session *memSession) (User, bool) {
+	if session.Revoked || !session.ExpiresAt.After(time.Now()) {
+		return User{}, false
+	}
+	s.users.mu.RLock()
+	defer s.users.mu.RUnlock()
+	u, ok := s.users.users[session.UserID]
+	if !ok || u.DeletedAt != nil {
+		return User{}, false
+	}
+	return u.User, true
+}
+
+func (s *memSessionStore) Active(ctx context.Context, sessionID int64, userID int) (string, []string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok || session.UserID != userID {
+		return "", nil, errNotFound
+	}
+	user, ok := s.activeUser(session)
+	if !ok {
+		return "", nil, errNotFound
+	}
+	s.users.mu.RLock()
+	permissions := append([]string{}, s.users.roles[user.Role].Permissions...)
+	s.users.mu.RUnlock()
+	return user.Role, permissions, nil
+}
+
+func (s *memSessionStore) Rotate(ctx context.Context, presentedHash, newHash string) (SessionOwner, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, session := range s.sessions {
+		if session.RefreshHash != presentedHash && session.PreviousHash != presentedHash {
+			continue
+		}
+		user, ok := s.activeUser(session)
+		if !ok {
+			continue
+		}
+		owner := SessionOwner{SessionID: session.ID, UserID: user.ID, Username: user.Username, Role: user.Role}
+
+		// Un token ya rotado indica robo: se revoca la sesión completa
+		if session.RefreshHash != presentedHash {
+			session.Revoked = true
+			return owner, errRefreshTokenReuse
+		}
+		session.PreviousHash, session.RefreshHash = session.RefreshHash, newHash
+		return owner, nil
+	}
+	return SessionOwner{}, errNotFound
+}
+
+// Revocar las sesiones activas que cumplen match
+func (s *memSessionStore) revoke(match func(session *memSession) bool) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var revoked int64
+	for _, session := range s.sessions {
+		if !session.Revoked && match(session) {
+			session.Revoked = true
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func (s *memSessionStore) RevokeToken(ctx context.Context, refreshHash string) (int64, error) {
+	return s.revoke(func(session *memSession) bool { return session.RefreshHash == refreshHash })
+}
+
+func (s *memSessionStore) Revoke(ctx context.Context, sessionID int64) (int64, error) {
+	return s.revoke(func(session *memSession) bool { return session.ID == sessionID })
+}
+
+func (s *memSessionStore) RevokeUser(ctx context.Context, userID int) (int64, error) {
+	return s.revoke(func(session *memSession) bool { return session.UserID == userID })
+}
+
+// AuditStore en memoria; los demás stores en memoria registran aquí sus mutaciones
+type memAuditStore struct {
+	mu      sync.RWMutex
+	entries []AuditEntry
+}
+
+func newMemAuditStore() *memAuditStore {
+	return &memAuditStore{}
+}
+
+// Mutación de una escritura masiva pendiente de auditar
+type memAuditChange struct {
+	action        string
+	id            int64
+	before, after interface{}
+}
+
+// Registrar una mutación como recordAudit; sin store (nil) no se audita
+func (s *memAuditStore) record(actor *Actor, action, entity string, entityID interface{}, before, after interface{}) {
+	if s == nil {
+		return
+	}
+	// Los estados auditados son structs de la API, que siempre se pueden serializar
+	beforeMap, _ := toJSONMap(before)
+	afterMap, _ := toJSONMap(after)
+	entry := AuditEntry{
+		OccurredAt: time.Now(),
+		Action:     action,
+		Entity:     entity,
+		EntityID:   fmt.Sprint(entityID),
+	}
+	entry.Before, _ = json.Marshal(beforeMap)
+	entry.After, _ = json.Marshal(afterMap)
+	entry.Diff, _ = json.Marshal(auditDiff(beforeMap, afterMap))
+	if actor != nil {
+		entry.ActorID = actor.UserID
+		if actor.Username != "" {
+			username := actor.Username
+			entry.ActorUsername = &username
+		}
+		entry.RequestID, entry.ClientIP = actor.RequestID, actor.ClientIP
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry.ID = int64(len(s.entries)) + 1
+	s.entries = append(s.entries, entry)
+}
+
+// Evaluar el filtro con la semántica de auditFilterConditions
+func (f AuditFilter) matches(e *AuditEntry) bool {
+	switch {
+	case f.ActorID != nil && (e.ActorID == nil || *e.ActorID != *f.ActorID),
+		f.ActorUsername != "" && (e.ActorUsername == nil || *e.ActorUsername != f.ActorUsername),
+		f.Entity != "" && e.Entity != f.Entity,
+		f.EntityID != "" && e.EntityID != f.EntityID,
+		f.Action != "" && e.Action != f.Action,
+		f.From != nil && e.OccurredAt.Before(*f.From),
+		f.To != nil && !e.OccurredAt.Before(*f.To):
+		return false
+	}
+	return true
+}
+
+func (s *memAuditStore) List(ctx context.Context, filter AuditFilter, limit, offset int) ([]AuditEntry, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := []AuditEntry{}
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if filter.matches(&s.entries[i]) {
+			entries = append(entries, s.entries[i])
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].OccurredAt.After(entries[j].OccurredAt) })
+	return paginate(entries, limit, offset), len(entries), nil
+}
+
+// AnalyticsStore calculado sobre un memPropertyStore
+type memAnalyticsStore struct {
+	properties *memPropertyStore
+}
+
+func newMemAnalyticsStore(properties *memPropertyStore) *memAnalyticsStore {
+	return &memAnalyticsStore{properties: properties}
+}
+
+// Propiedades no eliminadas
+func (s *memAnalyticsStore) current() []Property {
+	s.properties.mu.RLock()
+	defer s.properties.mu.RUnlock()
+	return s.properties.source(PropertyFilter{})
+}
+
+// Acumulador de conteo y promedio de un grupo
+type memGroup struct {
+	key   string
+	count int
+	sum   float64
+	sum2  float64
+}
+
+// Agrupar propiedades por clave acumulando dos valores para promediar
+func groupProperties(properties []Property, key func(p *Property) string, values func(p *Property) (float64, float64)) []*memGroup {
+	index := make(map[string]*memGroup)
+	var groups []*memGroup
+	for i := range properties {
+		k := key(&properties[i])
+		g, ok := index[k]
+		if !ok {
+			g = &memGroup{key: k}
+			index[k] = g
+			groups = append(groups, g)
+		}
+		a, b := values(&properties[i])
+		g.count++
+		g.sum += a
+		g.sum2 += b
+	}
+	return groups
+}
+
+// Grupo más numeroso; ante empate el primero alfabéticamente
+func topGroup(groups []*memGroup) NamedCount {
+	var top NamedCount
+	for _, g := range groups {
+		if g.count > top.Count || (g.count == top.Count && g.key < top.Name) {
+			top = NamedCount{Name: g.key, Count: g.count}
+		}
+	}
+	return top
+}
+
+// Ordenar grupos por número de filas descendente
+func sortByCount(groups []*memGroup) {
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].count != groups[j].count {
+			return groups[i].count > groups[j].count
+		}
+		return groups[i].key < groups[j].key
+	})
+}
+
+func limitGroups(groups []*memGroup, limit int) []*memGroup {
+	if limit > 0 && limit < len(groups) {
+		return groups[:limit]
+	}
+	return groups
+}
+
+func (s *memAnalyticsStore) KPIs(ctx context.Context) (KPIs, error) {
+	properties := s.current()
+	k := KPIs{TotalProperties: len(properties)}
+
+	var priceSum, ratioSum, yearsSum float64
+	var priceCount, ratioCount, yearsCount int
+	for _, p := range properties {
+		if p.SaleAmount > 0 {
+			priceSum += p.SaleAmount
+			priceCount++
+		}
+		if p.SalesRatio > 0 {
+			ratioSum += p.SalesRatio
+			ratioCount++
+		}
+		if p.YearsUntilSold >= 0 {
+			yearsSum += float64(p.YearsUntilSold)
+			yearsCount++
+		}
+	}
+	if priceCount > 0 {
+		k.AveragePrice = priceSum / float64(priceCount)
+	}
+	if ratioCount > 0 {
+		k.AverageSalesRatio = ratioSum / float64(ratioCount)
+	}
+	if yearsCount > 0 {
+		k.AverageYearsUntilSold = yearsSum / float64(yearsCount)
+	}
+
+	none := func(p *Property) (float64, float64) { return 0, 0 }
+	k.TopCity = topGroup(groupProperties(properties, func(p *Property) string { return p.Town }, none))
+	k.TopPropertyType = topGroup(groupProperties(properties, func(p *Property) string { return p.PropertyType }, none))
+	return k, nil
+}
+
+func (s *memAnalyticsStore) TrendsByYear(ctx context.Context) ([]YearTrend, error) {
+	type acc struct {
+		count                   int
+		price, ratio, yearsSold float64
+	}
+	byYear := make(map[int]*acc)
+	for _, p := range s.current() {
+		a, ok := byYear[p.ListYear]
+		if !ok {
+			a = &acc{}
+			byYear[p.ListYear] = a
+		}
+		a.count++
+		a.price += p.SaleAmount
+		a.ratio += p.SalesRatio
+		a.yearsSold += float64(p.YearsUntilSold)
+	}
+
+	trends := []YearTrend{}
+	for year, a := range byYear {
+		n := float64(a.count)
+		trends = append(trends, YearTrend{
+			Year:              year,
+			TotalSales:        a.count,
+			AvgPrice:          a.price / n,
+			AvgSalesRatio:     a.ratio / n,
+			AvgYearsUntilSold: a.yearsSold / n,
+		})
+	}
+	sort.Slice(trends, func(i, j int) bool { return trends[i].Year < trends[j].Year })
+	return trends, nil
+}
+
+func (s *memAnalyticsStore) AveragePriceByTown(ctx context.Context, limit int) ([]TownPrice, error) {
+	var sold []Property
+	for _, p := range s.current() {
+		if p.SaleAmount > 0 {
+			sold = append(sold, p)
+		}
+	}
+	groups := groupProperties(sold, func(p *Property) string { return p.Town },
+		func(p *Property) (float64, float64) { return p.SaleAmount, 0 })
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].sum/float64(groups[i].count) > groups[j].sum/float64(groups[j].count)
+	})
+
+	towns := []TownPrice{}
+	for _, g := range limitGroups(groups, limit) {
+		towns = append(towns, TownPrice{Town: g.key, AveragePrice: g.sum / float64(g.count), Count: g.count})
+	}
+	return towns, nil
+}
+
+func (s *memAnalyticsStore) PropertyTypeAnalysis(ctx context.Context) ([]PropertyTypeStats, error) {
+	groups := groupProperties(s.current(), func(p *Property) string { return p.PropertyType },
+		func(p *Property) (float64, float64) { return p.SaleAmount, p.SalesRatio })
+	sortByCount(groups)
+
+	stats := []PropertyTypeStats{}
+	for _, g := range groups {
+		n := float64(g.count)
+		stats = append(stats, PropertyTypeStats{PropertyType: g.key, Count: g.count, AveragePrice: g.sum / n, AvgSalesRatio: g.sum2 / n})
+	}
+	return stats, nil
+}
+
+// Distribución por tramos; omite los tramos vacíos como GROUP BY
+func distribution(labels []string, indexes []int) []DistributionBucket {
+	counts := make([]int, len(labels))
+	for _, i := range indexes {
+		counts[i]++
+	}
+
+	buckets := []DistributionBucket{}
+	for i, label := range labels {
+		if counts[i] == 0 {
+			continue
+		}
+		buckets = append(buckets, DistributionBucket{
+			Range:      label,
+			Count:      counts[i],
+			Percentage: round2(float64(counts[i]) * 100 / float64(len(indexes))),
+		})
+	}
+	return buckets
+}
+
+func (s *memAnalyticsStore) SalesRatioDistribution(ctx context.Context) ([]DistributionBucket, error) {
+	labels := make([]string, len(salesRatioBuckets))
+	for i, b := range salesRatioBuckets {
+		labels[i] = b.Label
+	}
+
+	var indexes []int
+	for _, p := range s.current() {
+		if p.SalesRatio <= 0 {
+			continue
+		}
+		for i, b := range salesRatioBuckets {
+			if p.SalesRatio < b.Below {
+				indexes = append(indexes, i)
+				break
+			}
+		}
+	}
+	return distribution(labels, indexes), nil
+}
+
+func (s *memAnalyticsStore) TimeToSellDistribution(ctx context.Context) ([]DistributionBucket, error) {
+	var indexes []int
+	for _, p := range s.current() {
+		if p.YearsUntilSold >= 0 {
+			indexes = append(indexes, min(p.YearsUntilSold, len(timeToSellBuckets)-1))
+		}
+	}
+	return distribution(timeToSellBuckets, indexes), nil
+}
+
+func (s *memAnalyticsStore) TopCitiesByVolume(ctx context.Context, limit int) ([]TownVolume, error) {
+	groups := groupProperties(s.current(), func(p *Property) string { return p.Town },
+		func(p *Property) (float64, float64) { return p.SaleAmount, 0 })
+	sortByCount(groups)
+
+	cities := []TownVolume{}
+	for _, g := range limitGroups(groups, limit) {
+		cities = append(cities, TownVolume{Town: g.key, Count: g.count, AveragePrice: g.sum / float64(g.count)})
+	}
+	return cities, nil
+}
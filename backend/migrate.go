@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Clave del advisory lock que serializa las migraciones entre réplicas
+const migrationLockKey = 7_263_921_004
+
+// Archivos NNNN_nombre.up.sql / NNNN_nombre.down.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migración de esquema embebida en el binario
+type migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Estado de una migración en la base de datos
+type migrationStatus struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// Leer y ordenar las migraciones embebidas
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		data, err := fs.ReadFile(migrationFiles, "migrations/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Ejecutor de migraciones sobre el pool de la aplicación
+type migrator struct {
+	db         *pgxpool.Pool
+	migrations []migration
+}
+
+func newMigrator(db *pgxpool.Pool) (*migrator, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	return &migrator{db: db, migrations: migrations}, nil
+}
+
+// Ejecutar fn con el advisory lock tomado en una conexión dedicada
+func (m *migrator) withLock(ctx context.Context, fn func(conn *pgx.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey)
+
+	_, err = conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	return fn(conn.Conn())
+}
+
+// Versiones aplicadas con su fecha
+func appliedMigrations(ctx context.Context, conn *pgx.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.Query(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// Ejecutar el SQL de una migración y registrar el cambio en la misma transacción
+func runMigrationStep(ctx context.Context, conn *pgx.Conn, sql, record string, args ...interface{}) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Aplicar todas las migraciones pendientes en orden
+func (m *migrator) Up(ctx context.Context) ([]migration, error) {
+	var done []migration
+	err := m.withLock(ctx, func(conn *pgx.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			err := runMigrationStep(ctx, conn, mig.Up,
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", mig.Version, mig.Name)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %v", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Revertir las últimas steps migraciones aplicadas
+func (m *migrator) Down(ctx context.Context, steps int) ([]migration, error) {
+	var done []migration
+	err := m.withLock(ctx, func(conn *pgx.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			err := runMigrationStep(ctx, conn, mig.Down,
+				"DELETE FROM schema_migrations WHERE version = $1", mig.Version)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %v", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Estado de cada migración conocida
+func (m *migrator) Status(ctx context.Context) ([]migrationStatus, error) {
+	var status []migrationStatus
+	err := m.withLock(ctx, func(conn *pgx.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			s := migrationStatus{Version: mig.Version, Name: mig.Name}
+			if appliedAt, ok := applied[mig.Version]; ok {
+				s.AppliedAt = &appliedAt
+			}
+			status = append(status, s)
+		}
+		return nil
+	})
+	return status, err
+}
+
+// Migraciones no aplicadas; no toma el lock para no bloquearse durante una migración
+func (m *migrator) Pending(ctx context.Context) ([]migration, error) {
+	var exists bool
+	if err := m.db.QueryRow(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return m.migrations, nil
+	}
+
+	var versions []int64
+	if err := m.db.QueryRow(ctx, "SELECT COALESCE(array_agg(version), '{}') FROM schema_migrations").Scan(&versions); err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]bool, len(versions))
+	for _, v := range versions {
+		applied[v] = true
+	}
+
+	var pending []migration
+	for _, mig := range m.migrations {
+		if !applied[mig.Version] {
+			pending = append(pending, mig)
+		}
+	}
+	return pending, nil
+}
+
+// Aplicar migraciones al iniciar o avisar si hay pendientes
+func (app *App) checkMigrations() error {
+	ctx := context.Background()
+
+	if app.config.Server.AutoMigrate {
+		done, err := app.migrator.Up(ctx)
+		for _, mig := range done {
+			log.Printf("Applied migration %d_%s", mig.Version, mig.Name)
+		}
+		return err
+	}
+
+	// Sin AUTO_MIGRATE se arranca igualmente; /readyz reporta el estado hasta que la base responda
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	pending, err := app.migrator.Pending(ctx)
+	if err != nil {
+		log.Printf("⚠️  Unable to check schema migrations: %v", err)
+		return nil
+	}
+	if len(pending) > 0 {
+		log.Printf("⚠️  %d pending schema migrations, run `backend migrate up` or set AUTO_MIGRATE=true", len(pending))
+	}
+	return nil
+}
+
+// Subcomando migrate: up | down [N] | status
+func (app *App) runMigrateCommand(args []string) error {
+	m, err := newMigrator(app.db)
+	if err != nil {
+		return err
+	}
+
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	ctx := context.Background()
+
+	switch action {
+	case "up":
+		done, err := m.Up(ctx)
+		for _, mig := range done {
+			log.Printf("Applied migration %d_%s", mig.Version, mig.Name)
+		}
+		if err == nil && len(done) == 0 {
+			log.Printf("Schema is up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		done, err := m.Down(ctx, steps)
+		for _, mig := range done {
+			log.Printf("Reverted migration %d_%s", mig.Version, mig.Name)
+		}
+		return err
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range status {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate action %q (use up, down or status)", action)
+	}
+}
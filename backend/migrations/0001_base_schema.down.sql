@@ -0,0 +1,6 @@
+-- Las tablas properties y users pueden ser anteriores a las migraciones: no se eliminan
+DO $$
+BEGIN
+    RAISE EXCEPTION 'migration 0001_base_schema cannot be reverted: properties and users hold adopted data';
+END
+$$;
@@ -0,0 +1,56 @@
+-- Esquema base: propiedades y usuarios
+CREATE TABLE IF NOT EXISTS properties (
+    serial_number BIGINT PRIMARY KEY,
+    list_year BIGINT,
+    date_recorded TEXT,
+    town TEXT,
+    address TEXT,
+    assessed_value BIGINT,
+    sale_amount DOUBLE PRECISION,
+    sales_ratio DOUBLE PRECISION,
+    property_type TEXT,
+    residential_type TEXT,
+    years_until_sold BIGINT
+);
+
+-- Las bases creadas a mano pueden no tener clave única en serial_number
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_index i
+        JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+        WHERE i.indrelid = 'properties'::regclass AND i.indisunique AND i.indnatts = 1 AND a.attname = 'serial_number'
+    ) THEN
+        CREATE UNIQUE INDEX properties_serial_number_key ON properties(serial_number);
+    END IF;
+END;
+$$;
+
+CREATE INDEX IF NOT EXISTS idx_properties_town ON properties(town);
+CREATE INDEX IF NOT EXISTS idx_properties_list_year ON properties(list_year);
+
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(50) UNIQUE NOT NULL,
+    email VARCHAR(100) UNIQUE NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    role VARCHAR(20) DEFAULT 'user',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
+
+CREATE OR REPLACE FUNCTION update_updated_at_column() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS update_users_updated_at ON users;
+CREATE TRIGGER update_users_updated_at
+    BEFORE UPDATE ON users
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
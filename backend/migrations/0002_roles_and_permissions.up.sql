@@ -0,0 +1,34 @@
+-- Roles y permisos
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE ON UPDATE CASCADE,
+    permission VARCHAR(100) NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name, description) VALUES
+('admin', 'Acceso completo'),
+('user', 'Acceso de lectura'),
+('analyst', 'Puede exportar datos'),
+('data_steward', 'Puede editar propiedades')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'properties:write'),
+('admin', 'users:manage'),
+('admin', 'roles:manage'),
+('admin', 'analytics:export'),
+('admin', 'audit:read'),
+('analyst', 'analytics:export'),
+('data_steward', 'properties:write')
+ON CONFLICT DO NOTHING;
+
+-- El rol del usuario pasa a referenciar la tabla roles
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_fkey;
+ALTER TABLE users ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles(name) ON UPDATE CASCADE;
@@ -0,0 +1,14 @@
+-- Sin borrado lógico los registros de la papelera volverían a estar activos: exigir que esté vacía
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM properties WHERE deleted_at IS NOT NULL)
+        OR EXISTS (SELECT 1 FROM users WHERE deleted_at IS NOT NULL) THEN
+        RAISE EXCEPTION 'trash is not empty: restore or purge deleted properties and users before reverting 0005_soft_delete';
+    END IF;
+END
+$$;
+
+ALTER TABLE properties DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE properties DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_by;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS property_history_capture ON properties;
+DROP FUNCTION IF EXISTS property_history_capture();
+DROP TABLE IF EXISTS property_history;
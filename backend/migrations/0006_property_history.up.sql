@@ -0,0 +1,71 @@
+-- Historial de versiones de propiedades (valid_to NULL = versión vigente)
+CREATE TABLE IF NOT EXISTS property_history (
+    revision_id BIGSERIAL PRIMARY KEY,
+    serial_number BIGINT NOT NULL,
+    operation VARCHAR(10) NOT NULL,
+    valid_from TIMESTAMPTZ NOT NULL,
+    valid_to TIMESTAMPTZ,
+    changed_by VARCHAR(50),
+    list_year BIGINT,
+    date_recorded TEXT,
+    town TEXT,
+    address TEXT,
+    assessed_value BIGINT,
+    sale_amount DOUBLE PRECISION,
+    sales_ratio DOUBLE PRECISION,
+    property_type TEXT,
+    residential_type TEXT,
+    years_until_sold BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_property_history_serial ON property_history(serial_number, revision_id);
+CREATE INDEX IF NOT EXISTS idx_property_history_validity ON property_history(valid_from, valid_to);
+
+CREATE OR REPLACE FUNCTION property_history_capture() RETURNS trigger AS $$
+DECLARE
+    actor VARCHAR(50) := NULLIF(current_setting('urbanytics.actor', true), '');
+BEGIN
+    IF TG_OP = 'UPDATE' AND OLD IS NOT DISTINCT FROM NEW THEN
+        RETURN NEW;
+    END IF;
+
+    -- La purga de una fila ya eliminada no genera una nueva revisión
+    IF TG_OP = 'DELETE' AND OLD.deleted_at IS NOT NULL THEN
+        RETURN OLD;
+    END IF;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE property_history SET valid_to = NOW()
+        WHERE serial_number = OLD.serial_number AND valid_to IS NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' OR (OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL) THEN
+        INSERT INTO property_history (serial_number, operation, valid_from, valid_to, changed_by, list_year, date_recorded, town, address,
+            assessed_value, sale_amount, sales_ratio, property_type, residential_type, years_until_sold)
+        VALUES (OLD.serial_number, 'delete', NOW(), NOW(), actor, OLD.list_year, OLD.date_recorded, OLD.town, OLD.address,
+            OLD.assessed_value, OLD.sale_amount, OLD.sales_ratio, OLD.property_type, OLD.residential_type, OLD.years_until_sold);
+        RETURN OLD;
+    END IF;
+
+    INSERT INTO property_history (serial_number, operation, valid_from, changed_by, list_year, date_recorded, town, address,
+        assessed_value, sale_amount, sales_ratio, property_type, residential_type, years_until_sold)
+    VALUES (NEW.serial_number, CASE WHEN TG_OP = 'UPDATE' AND OLD.deleted_at IS NOT NULL THEN 'restore' ELSE lower(TG_OP) END, NOW(), actor,
+        NEW.list_year, NEW.date_recorded, NEW.town, NEW.address,
+        NEW.assessed_value, NEW.sale_amount, NEW.sales_ratio, NEW.property_type, NEW.residential_type, NEW.years_until_sold);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS property_history_capture ON properties;
+CREATE TRIGGER property_history_capture
+    AFTER INSERT OR UPDATE OR DELETE ON properties
+    FOR EACH ROW EXECUTE FUNCTION property_history_capture();
+
+-- Versión inicial de las propiedades existentes
+INSERT INTO property_history (serial_number, operation, valid_from, list_year, date_recorded, town, address,
+    assessed_value, sale_amount, sales_ratio, property_type, residential_type, years_until_sold)
+SELECT serial_number, 'baseline', NOW(), list_year, date_recorded, town, address,
+    assessed_value, sale_amount, sales_ratio, property_type, residential_type, years_until_sold
+FROM properties p
+WHERE deleted_at IS NULL
+    AND NOT EXISTS (SELECT 1 FROM property_history h WHERE h.serial_number = p.serial_number);
@@ -0,0 +1,4 @@
+-- Permiso para consultar la configuración efectiva del servidor
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'config:read')
+ON CONFLICT DO NOTHING;
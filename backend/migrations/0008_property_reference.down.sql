@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS residential_types;
+DROP TABLE IF EXISTS property_types;
+DROP TABLE IF EXISTS towns;
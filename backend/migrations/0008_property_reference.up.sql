@@ -0,0 +1,25 @@
+-- Valores válidos de las columnas categóricas de properties. Se cargan con los
+-- datos existentes; una tabla vacía no restringe las escrituras.
+CREATE TABLE IF NOT EXISTS towns (
+    name TEXT PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS property_types (
+    name TEXT PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS residential_types (
+    name TEXT PRIMARY KEY
+);
+
+INSERT INTO towns (name)
+SELECT DISTINCT town FROM properties WHERE town IS NOT NULL AND town NOT IN ('', 'Nan')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO property_types (name)
+SELECT DISTINCT property_type FROM properties WHERE property_type IS NOT NULL AND property_type NOT IN ('', 'Nan')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO residential_types (name)
+SELECT DISTINCT residential_type FROM properties WHERE residential_type IS NOT NULL AND residential_type NOT IN ('', 'Nan')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS property_version_bump ON properties;
+DROP FUNCTION IF EXISTS property_version_bump();
+ALTER TABLE properties DROP COLUMN IF EXISTS version;
@@ -0,0 +1,17 @@
+-- Versión de fila para la concurrencia optimista (ETag / If-Match). Solo
+-- aumenta si la fila cambia, igual que el historial.
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+CREATE OR REPLACE FUNCTION property_version_bump() RETURNS trigger AS $$
+BEGIN
+    IF NEW IS DISTINCT FROM OLD THEN
+        NEW.version := OLD.version + 1;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS property_version_bump ON properties;
+CREATE TRIGGER property_version_bump
+    BEFORE UPDATE ON properties
+    FOR EACH ROW EXECUTE FUNCTION property_version_bump();
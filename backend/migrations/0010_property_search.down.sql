@@ -0,0 +1,4 @@
+-- La extensión pg_trgm se mantiene: puede usarla otro esquema
+DROP INDEX IF EXISTS idx_properties_town_trgm;
+DROP INDEX IF EXISTS idx_properties_address_trgm;
+DROP INDEX IF EXISTS idx_properties_search;
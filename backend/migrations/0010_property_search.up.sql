@@ -0,0 +1,11 @@
+-- Búsqueda de texto completo y por similitud (pg_trgm) sobre dirección y ciudad.
+-- La expresión del primer índice debe coincidir con propertySearchVector
+-- (search.go); si no, las consultas no lo usan.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_properties_search ON properties USING GIN (
+    (setweight(to_tsvector('simple', coalesce(nullif(address, 'Nan'), '')), 'A') || setweight(to_tsvector('simple', coalesce(nullif(town, 'Nan'), '')), 'B'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_properties_address_trgm ON properties USING GIN (address gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_properties_town_trgm ON properties USING GIN (town gin_trgm_ops);
@@ -0,0 +1,56 @@
+-- Triggers como en las migraciones 0006 y 0009
+CREATE OR REPLACE FUNCTION property_version_bump() RETURNS trigger AS $$
+BEGIN
+    IF NEW IS DISTINCT FROM OLD THEN
+        NEW.version := OLD.version + 1;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION property_history_capture() RETURNS trigger AS $$
+DECLARE
+    actor VARCHAR(50) := NULLIF(current_setting('urbanytics.actor', true), '');
+BEGIN
+    IF TG_OP = 'UPDATE' AND OLD IS NOT DISTINCT FROM NEW THEN
+        RETURN NEW;
+    END IF;
+
+    -- La purga de una fila ya eliminada no genera una nueva revisión
+    IF TG_OP = 'DELETE' AND OLD.deleted_at IS NOT NULL THEN
+        RETURN OLD;
+    END IF;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE property_history SET valid_to = NOW()
+        WHERE serial_number = OLD.serial_number AND valid_to IS NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' OR (OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL) THEN
+        INSERT INTO property_history (serial_number, operation, valid_from, valid_to, changed_by, list_year, date_recorded, town, address,
+            assessed_value, sale_amount, sales_ratio, property_type, residential_type, years_until_sold)
+        VALUES (OLD.serial_number, 'delete', NOW(), NOW(), actor, OLD.list_year, OLD.date_recorded, OLD.town, OLD.address,
+            OLD.assessed_value, OLD.sale_amount, OLD.sales_ratio, OLD.property_type, OLD.residential_type, OLD.years_until_sold);
+        RETURN OLD;
+    END IF;
+
+    INSERT INTO property_history (serial_number, operation, valid_from, changed_by, list_year, date_recorded, town, address,
+        assessed_value, sale_amount, sales_ratio, property_type, residential_type, years_until_sold)
+    VALUES (NEW.serial_number, CASE WHEN TG_OP = 'UPDATE' AND OLD.deleted_at IS NOT NULL THEN 'restore' ELSE lower(TG_OP) END, NOW(), actor,
+        NEW.list_year, NEW.date_recorded, NEW.town, NEW.address,
+        NEW.assessed_value, NEW.sale_amount, NEW.sales_ratio, NEW.property_type, NEW.residential_type, NEW.years_until_sold);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP FUNCTION IF EXISTS property_tracked(properties);
+DROP INDEX IF EXISTS idx_properties_address_normalized;
+ALTER TABLE properties
+    DROP COLUMN IF EXISTS address_number,
+    DROP COLUMN IF EXISTS address_pre_direction,
+    DROP COLUMN IF EXISTS address_street,
+    DROP COLUMN IF EXISTS address_suffix,
+    DROP COLUMN IF EXISTS address_post_direction,
+    DROP COLUMN IF EXISTS address_unit_type,
+    DROP COLUMN IF EXISTS address_unit,
+    DROP COLUMN IF EXISTS address_normalized;
@@ -0,0 +1,66 @@
+-- Componentes normalizados de la dirección. Los calcula el backend en cada
+-- escritura; las filas anteriores quedan a NULL hasta `normalize-addresses`.
+ALTER TABLE properties
+    ADD COLUMN IF NOT EXISTS address_number TEXT,
+    ADD COLUMN IF NOT EXISTS address_pre_direction TEXT,
+    ADD COLUMN IF NOT EXISTS address_street TEXT,
+    ADD COLUMN IF NOT EXISTS address_suffix TEXT,
+    ADD COLUMN IF NOT EXISTS address_post_direction TEXT,
+    ADD COLUMN IF NOT EXISTS address_unit_type TEXT,
+    ADD COLUMN IF NOT EXISTS address_unit TEXT,
+    ADD COLUMN IF NOT EXISTS address_normalized TEXT;
+
+-- Búsqueda exacta (con o sin ciudad) y agrupación de duplicados
+CREATE INDEX IF NOT EXISTS idx_properties_address_normalized ON properties (address_normalized, town)
+    WHERE deleted_at IS NULL;
+
+-- Fila sin las columnas derivadas de la dirección: cambiarlas no es una nueva
+-- versión ni una nueva revisión del historial
+CREATE OR REPLACE FUNCTION property_tracked(p properties) RETURNS jsonb AS $$
+    SELECT to_jsonb(p) - ARRAY['address_number', 'address_pre_direction', 'address_street', 'address_suffix',
+        'address_post_direction', 'address_unit_type', 'address_unit', 'address_normalized'];
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION property_version_bump() RETURNS trigger AS $$
+BEGIN
+    IF property_tracked(NEW) IS DISTINCT FROM property_tracked(OLD) THEN
+        NEW.version := OLD.version + 1;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION property_history_capture() RETURNS trigger AS $$
+DECLARE
+    actor VARCHAR(50) := NULLIF(current_setting('urbanytics.actor', true), '');
+BEGIN
+    IF TG_OP = 'UPDATE' AND property_tracked(OLD) IS NOT DISTINCT FROM property_tracked(NEW) THEN
+        RETURN NEW;
+    END IF;
+
+    -- La purga de una fila ya eliminada no genera una nueva revisión
+    IF TG_OP = 'DELETE' AND OLD.deleted_at IS NOT NULL THEN
+        RETURN OLD;
+    END IF;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE property_history SET valid_to = NOW()
+        WHERE serial_number = OLD.serial_number AND valid_to IS NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' OR (OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL) THEN
+        INSERT INTO property_history (serial_number, operation, valid_from, valid_to, changed_by, list_year, date_recorded, town, address,
+            assessed_value, sale_amount, sales_ratio, property_type, residential_type, years_until_sold)
+        VALUES (OLD.serial_number, 'delete', NOW(), NOW(), actor, OLD.list_year, OLD.date_recorded, OLD.town, OLD.address,
+            OLD.assessed_value, OLD.sale_amount, OLD.sales_ratio, OLD.property_type, OLD.residential_type, OLD.years_until_sold);
+        RETURN OLD;
+    END IF;
+
+    INSERT INTO property_history (serial_number, operation, valid_from, changed_by, list_year, date_recorded, town, address,
+        assessed_value, sale_amount, sales_ratio, property_type, residential_type, years_until_sold)
+    VALUES (NEW.serial_number, CASE WHEN TG_OP = 'UPDATE' AND OLD.deleted_at IS NOT NULL THEN 'restore' ELSE lower(TG_OP) END, NOW(), actor,
+        NEW.list_year, NEW.date_recorded, NEW.town, NEW.address,
+        NEW.assessed_value, NEW.sale_amount, NEW.sales_ratio, NEW.property_type, NEW.residential_type, NEW.years_until_sold);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
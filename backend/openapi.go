@@ -0,0 +1,713 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Prefijo común de las rutas descritas en la especificación
+const apiBasePath = "/api/v1"
+
+// Parámetro de query string de una operación
+type apiParam struct {
+	Name        string
+	Type        string
+	Description string
+	Enum        []string
+	Required    bool
+}
+
+// Descripción de una ruta de setupRoutes para la especificación OpenAPI.
+// Body y Response son valores de ejemplo cuyo tipo se refleja como esquema.
+type apiOperation struct {
+	Summary      string
+	Tag          string
+	Auth         bool
+	Permission   string
+	Feature      bool // se puede desactivar por configuración y responde 404
+	Static       bool // no consulta la base de datos
+	Versioned    bool // ETag de versión: If-None-Match en GET, If-Match en escrituras
+	Query        []apiParam
+	Body         interface{}
+	OptionalBody bool
+	BodyTypes    map[string]gin.H // cuerpos no JSON por content type
+	Patch        interface{}      // campos modificables con PATCH (merge patch o JSON Patch)
+	Status       int              // código de éxito; 200 si no se indica
+	Response     interface{}
+	Produces     []string // respuestas no JSON
+	Errors       []int
+}
+
+// Cuerpo application/json-patch+json (RFC 6902)
+var jsonPatchSchema = gin.H{
+	"type": "array",
+	"items": gin.H{
+		"type":     "object",
+		"required": []string{"op", "path"},
+		"properties": gin.H{
+			"op":    gin.H{"type": "string", "enum": []string{"add", "remove", "replace", "move", "copy", "test"}},
+			"path":  gin.H{"type": "string", "description": "JSON Pointer to the target field, e.g. /sale_amount"},
+			"from":  gin.H{"type": "string", "description": "Source pointer for move and copy"},
+			"value": gin.H{"description": "Value for add, replace and test"},
+		},
+	},
+}
+
+// Respuestas construidas con gin.H, descritas para la especificación
+type apiData[T any] struct {
+	Success bool `json:"success"`
+	Data    T    `json:"data"`
+}
+
+type apiPage[T any] struct {
+	Success    bool             `json:"success"`
+	Data       []T              `json:"data"`
+	Pagination OffsetPagination `json:"pagination"`
+}
+
+type OffsetPagination struct {
+	CurrentPage int `json:"current_page"`
+	TotalPages  int `json:"total_pages"`
+	TotalCount  int `json:"total_count"`
+	Limit       int `json:"limit"`
+	Offset      int `json:"offset"`
+}
+
+type CursorPagination struct {
+	Mode       string  `json:"mode"`
+	Limit      int     `json:"limit"`
+	Sort       string  `json:"sort"`
+	NextCursor *string `json:"next_cursor"`
+	PrevCursor *string `json:"prev_cursor"`
+	TotalCount int     `json:"total_count,omitempty"`
+}
+
+// Con cursor la paginación es keyset; sin él, por página
+type propertyPagination struct{}
+
+func (propertyPagination) openAPISchema(r *schemaRegistry) gin.H {
+	return gin.H{"oneOf": []gin.H{
+		r.schema(reflect.TypeOf(OffsetPagination{})),
+		r.schema(reflect.TypeOf(CursorPagination{})),
+	}}
+}
+
+type PropertyPage struct {
+	Success    bool               `json:"success"`
+	Data       []Property         `json:"data"`
+	Pagination propertyPagination `json:"pagination"`
+}
+
+// Campos de un usuario modificables con PATCH
+type UserPatch struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type UserSummary struct {
+	ID       int    `json:"id"`
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+}
+
+type AuthResponse struct {
+	Success      bool         `json:"success"`
+	Token        string       `json:"token"`
+	RefreshToken string       `json:"refresh_token"`
+	ExpiresIn    int          `json:"expires_in"`
+	User         *UserSummary `json:"user,omitempty"`
+}
+
+type Profile struct {
+	ID          int       `json:"id"`
+	Username    string    `json:"username"`
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ProfileResponse struct {
+	Success bool    `json:"success"`
+	User    Profile `json:"user"`
+}
+
+type MessageResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	UserID  int    `json:"user_id,omitempty"`
+	Revoked int64  `json:"revoked,omitempty"`
+}
+
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type ConfigView struct {
+	File     string        `json:"file"`
+	Settings []ConfigEntry `json:"settings"`
+}
+
+// Parámetros de paginación por página
+var pageParams = []apiParam{
+	{Name: "page", Type: "integer", Description: "Page number, starting at 1"},
+	{Name: "limit", Type: "integer", Description: "Rows per page"},
+}
+
+// Filtros de propiedades compartidos por el listado y la exportación
+var propertyFilterParams = []apiParam{
+	{Name: "q", Type: "string", Description: "Full-text and fuzzy search over address and town; ranked by relevance unless a sort is given"},
+	{Name: "town", Type: "string", Description: "Town name, case-insensitive substring"},
+	{Name: "min_price", Type: "number", Description: "Minimum sale amount"},
+	{Name: "max_price", Type: "number", Description: "Maximum sale amount"},
+	{Name: "property_type", Type: "string", Description: "Exact property type"},
+	{Name: "residential_type", Type: "string", Description: "Exact residential type"},
+	{Name: "list_year", Type: "integer", Description: "List year"},
+	{Name: "status", Type: "string", Description: "Whether the property has a recorded sale", Enum: []string{"sold", "available"}},
+	{Name: "min_sales_ratio", Type: "number", Description: "Minimum sales ratio"},
+	{Name: "max_sales_ratio", Type: "number", Description: "Maximum sales ratio"},
+	{Name: "min_years_until_sold", Type: "integer", Description: "Minimum years until sold"},
+	{Name: "max_years_until_sold", Type: "integer", Description: "Maximum years until sold"},
+}
+
+var asOfParam = apiParam{Name: "as_of", Type: "string", Description: "Return the data as it was at this instant (RFC3339 or YYYY-MM-DD)"}
+
+// Parámetros de getProperties
+var propertyListParams = append(append([]apiParam{}, pageParams...), append([]apiParam{
+	{Name: "sort", Type: "string", Description: "Comma-separated sort keys, '-' prefix for descending (e.g. -sale_amount,town)"},
+	{Name: "sort_by", Type: "string", Description: "Single sort key, used when 'sort' is absent"},
+	{Name: "sort_order", Type: "string", Description: "Order for 'sort_by'", Enum: []string{"asc", "desc"}},
+	{Name: "cursor", Type: "string", Description: "Opaque cursor; an empty value starts keyset pagination"},
+	{Name: "include_total", Type: "boolean", Description: "Include total_count in cursor mode"},
+	asOfParam,
+}, propertyFilterParams...)...)
+
+// Operaciones documentadas, por método y ruta tal como se registran en gin
+var apiOperations = map[string]apiOperation{
+	"GET /api/v1/openapi.json": {Summary: "OpenAPI document of this API", Tag: "docs", Static: true,
+		Response: map[string]interface{}{}},
+	"GET /api/v1/docs": {Summary: "Interactive API reference", Tag: "docs", Static: true,
+		Produces: []string{"text/html"}},
+
+	// Autenticación
+	"POST /api/v1/auth/login": {Summary: "Log in with username and password", Tag: "auth",
+		Body: LoginRequest{}, Response: AuthResponse{}, Errors: []int{http.StatusUnauthorized}},
+	"POST /api/v1/auth/register": {Summary: "Register a user account", Tag: "auth", Feature: true,
+		Body: RegisterRequest{}, Status: http.StatusCreated, Response: AuthResponse{}, Errors: []int{http.StatusConflict}},
+	"POST /api/v1/auth/refresh": {Summary: "Rotate the refresh token and issue a new access token", Tag: "auth",
+		Body: RefreshRequest{}, Response: AuthResponse{}, Errors: []int{http.StatusUnauthorized}},
+	"POST /api/v1/auth/logout": {Summary: "Revoke the session of a refresh token or of the bearer token", Tag: "auth",
+		Body: LogoutRequest{}, OptionalBody: true, Response: MessageResponse{}, Errors: []int{http.StatusUnauthorized}},
+	"POST /api/v1/auth/logout-all": {Summary: "Revoke every session of the authenticated user", Tag: "auth", Auth: true,
+		Response: MessageResponse{}},
+
+	// Propiedades
+	"GET /api/v1/properties": {Summary: "List properties with filters, sorting and pagination", Tag: "properties",
+		Query: propertyListParams, Response: PropertyPage{}},
+	"GET /api/v1/properties/suggest": {Summary: "Autocomplete addresses and towns from a partial query", Tag: "properties",
+		Query: []apiParam{
+			{Name: "q", Type: "string", Required: true, Description: "Text typed so far"},
+			{Name: "limit", Type: "integer", Description: "Maximum suggestions, 1 to 25 (default 10)"},
+		},
+		Response: apiData[[]PropertySuggestion]{}},
+	"GET /api/v1/properties/lookup": {Summary: "Find properties by exact normalized address", Tag: "properties",
+		Query: []apiParam{
+			{Name: "address", Type: "string", Required: true, Description: "Free-text address; abbreviations, case, directions and unit designators are normalized"},
+			{Name: "town", Type: "string", Description: "Only this town (case-insensitive)"},
+		},
+		Response: apiData[AddressLookup]{}},
+	"GET /api/v1/properties/:id": {Summary: "Get a property by serial number", Tag: "properties",
+		Query: []apiParam{asOfParam}, Versioned: true, Response: apiData[Property]{}, Errors: []int{http.StatusNotFound}},
+	"GET /api/v1/properties/:id/history": {Summary: "List the revisions of a property with field diffs", Tag: "properties",
+		Response: apiData[[]PropertyRevision]{}, Errors: []int{http.StatusNotFound}},
+	"GET /api/v1/properties/filters/cities": {Summary: "List towns", Tag: "properties",
+		Response: apiData[[]string]{}},
+	"GET /api/v1/properties/filters/property-types": {Summary: "List property types", Tag: "properties",
+		Response: apiData[[]string]{}},
+	"GET /api/v1/properties/filters/residential-types": {Summary: "List residential types", Tag: "properties",
+		Response: apiData[[]string]{}},
+	"GET /api/v1/properties/filters/list-years": {Summary: "List list years", Tag: "properties",
+		Response: apiData[[]int]{}},
+	"GET /api/v1/properties/export": {Summary: "Export filtered properties without pagination", Tag: "properties",
+		Auth: true, Permission: permAnalyticsExport, Feature: true,
+		Query: append([]apiParam{{Name: "format", Type: "string", Description: "Output format; defaults to the Accept header, then csv",
+			Enum: []string{"csv", "ndjson", "parquet"}}}, propertyFilterParams...),
+		Produces: []string{"text/csv", "application/x-ndjson", "application/vnd.apache.parquet"}},
+
+	// Analítica
+	"GET /api/v1/analytics/kpis": {Summary: "Headline KPIs", Tag: "analytics",
+		Response: apiData[KPIs]{}},
+	"GET /api/v1/analytics/trends-by-year": {Summary: "Sales trends by list year", Tag: "analytics",
+		Response: apiData[[]YearTrend]{}},
+	"GET /api/v1/analytics/avg-price-by-town": {Summary: "Average sale price of the top 20 towns", Tag: "analytics",
+		Response: apiData[[]TownPrice]{}},
+	"GET /api/v1/analytics/property-type-analysis": {Summary: "Sales statistics by property type", Tag: "analytics",
+		Response: apiData[[]PropertyTypeStats]{}},
+	"GET /api/v1/analytics/sales-ratio-distribution": {Summary: "Distribution of sales ratios", Tag: "analytics",
+		Response: apiData[[]DistributionBucket]{}},
+	"GET /api/v1/analytics/time-to-sell-distribution": {Summary: "Distribution of years until sold", Tag: "analytics",
+		Response: apiData[[]DistributionBucket]{}},
+	"GET /api/v1/analytics/top-cities-by-volume": {Summary: "Top 10 towns by number of sales", Tag: "analytics",
+		Response: apiData[[]TownVolume]{}},
+
+	// Perfil
+	"GET /api/v1/profile": {Summary: "Profile and permissions of the authenticated user", Tag: "profile", Auth: true,
+		Response: ProfileResponse{}, Errors: []int{http.StatusNotFound}},
+	"PUT /api/v1/profile": {Summary: "Update the email of the authenticated user", Tag: "profile", Auth: true,
+		Body: UpdateProfileRequest{}, Response: MessageResponse{}, Errors: []int{http.StatusNotFound, http.StatusConflict}},
+
+	// Administración de propiedades
+	"POST /api/v1/admin/properties": {Summary: "Create a property", Tag: "admin", Auth: true, Permission: permPropertiesWrite,
+		Body: Property{}, Status: http.StatusCreated, Response: apiData[Property]{}, Errors: []int{http.StatusConflict}},
+	"POST /api/v1/admin/properties/import": {Summary: "Bulk import properties from CSV or NDJSON", Tag: "admin",
+		Auth: true, Permission: permPropertiesWrite, Feature: true,
+		Query: []apiParam{
+			{Name: "mode", Type: "string", Description: "Insert new rows only or update existing ones", Enum: []string{"upsert", "insert"}},
+			{Name: "dry_run", Type: "boolean", Description: "Validate and report without writing"},
+			{Name: "format", Type: "string", Description: "Input format; detected from the content type or file name when absent", Enum: []string{"csv", "ndjson"}},
+		},
+		BodyTypes: map[string]gin.H{
+			"text/csv":             {"type": "string"},
+			"application/x-ndjson": {"type": "string"},
+			"multipart/form-data": {"type": "object", "required": []string{"file"},
+				"properties": gin.H{"file": gin.H{"type": "string", "contentMediaType": "application/octet-stream"}}},
+		},
+		Response: apiData[importReport]{}, Errors: []int{http.StatusRequestEntityTooLarge}},
+	"POST /api/v1/admin/properties/bulk": {Summary: "Create, update and delete properties in one transaction", Tag: "admin",
+		Auth: true, Permission: permPropertiesWrite,
+		Body: BulkPropertyRequest{}, Response: apiData[bulkReport]{}, Errors: []int{http.StatusRequestEntityTooLarge}},
+	"PUT /api/v1/admin/properties/:id": {Summary: "Replace a property", Tag: "admin", Auth: true, Permission: permPropertiesWrite,
+		Body: Property{}, Versioned: true, Response: apiData[Property]{}, Errors: []int{http.StatusNotFound}},
+	"PATCH /api/v1/admin/properties/:id": {Summary: "Change some fields of a property", Tag: "admin", Auth: true, Permission: permPropertiesWrite,
+		Patch: Property{}, Versioned: true, Response: apiData[Property]{}, Errors: []int{http.StatusNotFound}},
+	"DELETE /api/v1/admin/properties/:id": {Summary: "Move a property to the trash", Tag: "admin", Auth: true, Permission: permPropertiesWrite,
+		Versioned: true, Response: MessageResponse{}, Errors: []int{http.StatusNotFound}},
+	"GET /api/v1/admin/properties/trash": {Summary: "List deleted properties", Tag: "admin", Auth: true, Permission: permPropertiesWrite,
+		Query: pageParams, Response: apiPage[DeletedProperty]{}},
+	"GET /api/v1/admin/properties/duplicates": {Summary: "List normalized addresses shared by several properties of a town", Tag: "admin", Auth: true, Permission: permPropertiesWrite,
+		Query:    append([]apiParam{{Name: "town", Type: "string", Description: "Only this town (case-insensitive)"}}, pageParams...),
+		Response: apiPage[AddressDuplicate]{}},
+	"POST /api/v1/admin/properties/:id/restore": {Summary: "Restore a deleted property", Tag: "admin", Auth: true, Permission: permPropertiesWrite,
+		Response: apiData[Property]{}, Errors: []int{http.StatusNotFound}},
+
+	// Administración de usuarios
+	"GET /api/v1/admin/users": {Summary: "List users", Tag: "admin", Auth: true, Permission: permUsersManage,
+		Response: apiData[[]User]{}},
+	"POST /api/v1/admin/users": {Summary: "Create a user", Tag: "admin", Auth: true, Permission: permUsersManage,
+		Body: RegisterRequest{}, Status: http.StatusCreated, Response: apiData[UserSummary]{}, Errors: []int{http.StatusConflict}},
+	"PUT /api/v1/admin/users/:id": {Summary: "Update the email and role of a user", Tag: "admin", Auth: true, Permission: permUsersManage,
+		Body: UpdateUserRequest{}, Response: apiData[UserSummary]{}, Errors: []int{http.StatusNotFound, http.StatusConflict}},
+	"PATCH /api/v1/admin/users/:id": {Summary: "Change the email or role of a user", Tag: "admin", Auth: true, Permission: permUsersManage,
+		Patch: UserPatch{}, Response: apiData[User]{}, Errors: []int{http.StatusNotFound}},
+	"DELETE /api/v1/admin/users/:id": {Summary: "Move a user to the trash and revoke their sessions", Tag: "admin", Auth: true, Permission: permUsersManage,
+		Response: MessageResponse{}, Errors: []int{http.StatusNotFound}},
+	"DELETE /api/v1/admin/users/:id/sessions": {Summary: "Revoke every session of a user", Tag: "admin", Auth: true, Permission: permUsersManage,
+		Response: MessageResponse{}},
+	"GET /api/v1/admin/users/trash": {Summary: "List deleted users", Tag: "admin", Auth: true, Permission: permUsersManage,
+		Query: pageParams, Response: apiPage[DeletedUser]{}},
+	"POST /api/v1/admin/users/:id/restore": {Summary: "Restore a deleted user", Tag: "admin", Auth: true, Permission: permUsersManage,
+		Response: apiData[User]{}, Errors: []int{http.StatusNotFound}},
+
+	// Roles y permisos
+	"GET /api/v1/admin/permissions": {Summary: "List the permission catalog", Tag: "admin", Auth: true, Permission: permRolesManage,
+		Static: true, Response: apiData[[]Permission]{}},
+	"GET /api/v1/admin/roles": {Summary: "List roles with their permissions", Tag: "admin", Auth: true, Permission: permRolesManage,
+		Response: apiData[[]Role]{}},
+	"POST /api/v1/admin/roles": {Summary: "Create a role", Tag: "admin", Auth: true, Permission: permRolesManage,
+		Body: RoleRequest{}, Status: http.StatusCreated, Response: apiData[Role]{}, Errors: []int{http.StatusConflict}},
+	"PUT /api/v1/admin/roles/:name": {Summary: "Replace the description and permissions of a role", Tag: "admin", Auth: true, Permission: permRolesManage,
+		Body: RoleRequest{}, Response: apiData[Role]{}, Errors: []int{http.StatusNotFound}},
+	"DELETE /api/v1/admin/roles/:name": {Summary: "Delete a role without assigned users", Tag: "admin", Auth: true, Permission: permRolesManage,
+		Response: MessageResponse{}, Errors: []int{http.StatusNotFound, http.StatusConflict}},
+
+	// Auditoría y configuración
+	"GET /api/v1/admin/audit": {Summary: "Query the audit log", Tag: "admin", Auth: true, Permission: permAuditRead,
+		Query: append(append([]apiParam{}, pageParams...),
+			apiParam{Name: "actor", Type: "string", Description: "Actor user ID or username"},
+			apiParam{Name: "entity", Type: "string", Description: "Entity type (property, user, role)"},
+			apiParam{Name: "entity_id", Type: "string", Description: "Entity identifier"},
+			apiParam{Name: "action", Type: "string", Description: "Audited action"},
+			apiParam{Name: "from", Type: "string", Description: "Entries at or after this time (RFC3339 or YYYY-MM-DD)"},
+			apiParam{Name: "to", Type: "string", Description: "Entries before this time (RFC3339 or YYYY-MM-DD)"},
+		),
+		Response: apiPage[AuditEntry]{}},
+	"GET /api/v1/admin/config": {Summary: "Effective configuration with secrets redacted", Tag: "admin", Auth: true, Permission: permConfigRead,
+		Static: true, Response: apiData[ConfigView]{}},
+}
+
+// Tipos de los parámetros de ruta
+var pathParamTypes = map[string]string{"id": "integer", "name": "string"}
+
+// Esquemas con nombre que se publican en components
+type schemaRegistry struct {
+	schemas gin.H
+}
+
+// Tipos que describen su propio esquema
+type openAPISchemaer interface {
+	openAPISchema(r *schemaRegistry) gin.H
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	rawJSONType  = reflect.TypeOf(json.RawMessage{})
+	schemaerType = reflect.TypeOf((*openAPISchemaer)(nil)).Elem()
+)
+
+// Esquema JSON de un tipo Go según sus etiquetas json y binding
+func (r *schemaRegistry) schema(t reflect.Type) gin.H {
+	if t.Implements(schemaerType) {
+		return reflect.Zero(t).Interface().(openAPISchemaer).openAPISchema(r)
+	}
+
+	switch t {
+	case timeType:
+		return gin.H{"type": "string", "format": "date-time"}
+	case rawJSONType:
+		return gin.H{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(r.schema(t.Elem()))
+	case reflect.Slice, reflect.Array:
+		return gin.H{"type": "array", "items": r.schema(t.Elem())}
+	case reflect.Map:
+		return gin.H{"type": "object", "additionalProperties": r.schema(t.Elem())}
+	case reflect.Interface:
+		return gin.H{}
+	case reflect.String:
+		return gin.H{"type": "string"}
+	case reflect.Bool:
+		return gin.H{"type": "boolean"}
+	case reflect.Int64:
+		return gin.H{"type": "integer", "format": "int64"}
+	case reflect.Int, reflect.Int32, reflect.Int16, reflect.Int8, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return gin.H{"type": "integer"}
+	case reflect.Float64, reflect.Float32:
+		return gin.H{"type": "number"}
+	case reflect.Struct:
+		// Los genéricos de respuesta se expanden en línea
+		if t.Name() == "" || strings.Contains(t.Name(), "[") {
+			return r.object(t)
+		}
+		name := componentName(t)
+		if _, ok := r.schemas[name]; !ok {
+			r.schemas[name] = gin.H{} // reservar antes de recorrer tipos recursivos
+			r.schemas[name] = r.object(t)
+		}
+		return gin.H{"$ref": "#/components/schemas/" + name}
+	}
+	return gin.H{}
+}
+
+// Esquema de objeto con los campos exportados; los embebidos se aplanan
+func (r *schemaRegistry) object(t reflect.Type) gin.H {
+	properties := gin.H{}
+	var required []string
+
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if f.Anonymous && tag == "" {
+				collect(f.Type)
+				continue
+			}
+			if !f.IsExported() || tag == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+			if name == "" {
+				name = f.Name
+			}
+
+			property := r.schema(f.Type)
+			for _, rule := range strings.Split(f.Tag.Get("binding"), ",") {
+				switch {
+				case rule == "required":
+					required = append(required, name)
+				case rule == "email":
+					property["format"] = "email"
+				case strings.HasPrefix(rule, "min="):
+					if n, err := strconv.Atoi(strings.TrimPrefix(rule, "min=")); err == nil {
+						property["minLength"] = n
+					}
+				}
+			}
+			properties[name] = property
+		}
+	}
+	collect(t)
+
+	schema := gin.H{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// Permitir null además del esquema dado
+func nullable(schema gin.H) gin.H {
+	if t, ok := schema["type"].(string); ok {
+		schema["type"] = []string{t, "null"}
+		return schema
+	}
+	return gin.H{"oneOf": []gin.H{schema, {"type": "null"}}}
+}
+
+// Nombre público de un tipo: importReport -> ImportReport
+func componentName(t reflect.Type) string {
+	name := []rune(t.Name())
+	name[0] = unicode.ToUpper(name[0])
+	return string(name)
+}
+
+// Convertir una ruta de gin (:id) a plantilla OpenAPI ({id}) y listar sus parámetros
+func openAPIPath(path string) (string, []string) {
+	var params []string
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			params = append(params, part[1:])
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/"), params
+}
+
+// Nombre del handler como operationId: main.(*App).getProperties-fm -> getProperties
+func operationID(handler string) string {
+	handler = strings.TrimSuffix(handler, "-fm")
+	if i := strings.LastIndex(handler, "."); i >= 0 {
+		handler = handler[i+1:]
+	}
+	return handler
+}
+
+// Respuesta de error problem+json
+func errorResponse(status int) gin.H {
+	return gin.H{
+		"description": http.StatusText(status),
+		"content": gin.H{problemContentType: gin.H{
+			"schema": gin.H{"$ref": "#/components/schemas/Problem"},
+		}},
+	}
+}
+
+// Construir la operación OpenAPI de una ruta
+func (op apiOperation) build(r *schemaRegistry, method, id string, pathParams []string) gin.H {
+	operation := gin.H{
+		"operationId": id,
+		"summary":     op.Summary,
+		"tags":        []string{op.Tag},
+	}
+
+	var parameters []gin.H
+	for _, name := range pathParams {
+		paramType := pathParamTypes[name]
+		if paramType == "" {
+			paramType = "string"
+		}
+		parameters = append(parameters, gin.H{
+			"name": name, "in": "path", "required": true,
+			"schema": gin.H{"type": paramType},
+		})
+	}
+	for _, p := range op.Query {
+		schema := gin.H{"type": p.Type}
+		if len(p.Enum) > 0 {
+			schema["enum"] = p.Enum
+		}
+		parameters = append(parameters, gin.H{
+			"name": p.Name, "in": "query", "description": p.Description,
+			"required": p.Required, "schema": schema,
+		})
+	}
+	if op.Versioned {
+		header, description := "If-Match", "Entity tags from previous responses, comma-separated, or *; 412 if the current version is not listed"
+		if method == http.MethodGet {
+			header, description = "If-None-Match", "Entity tag from a previous response; 304 if the property did not change"
+		}
+		parameters = append(parameters, gin.H{
+			"name": header, "in": "header", "description": description,
+			"schema": gin.H{"type": "string"},
+		})
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	// Cuerpo de la petición
+	content := gin.H{}
+	if op.Body != nil {
+		content["application/json"] = gin.H{"schema": r.schema(reflect.TypeOf(op.Body))}
+	}
+	for contentType, schema := range op.BodyTypes {
+		content[contentType] = gin.H{"schema": schema}
+	}
+	if op.Patch != nil {
+		content[mergePatchContentType] = gin.H{"schema": r.schema(reflect.TypeOf(op.Patch))}
+		content[jsonPatchContentType] = gin.H{"schema": jsonPatchSchema}
+	}
+	if len(content) > 0 {
+		operation["requestBody"] = gin.H{"required": !op.OptionalBody, "content": content}
+	}
+
+	// Respuesta correcta
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := gin.H{"description": http.StatusText(status)}
+	content = gin.H{}
+	if op.Response != nil {
+		content["application/json"] = gin.H{"schema": r.schema(reflect.TypeOf(op.Response))}
+	}
+	for _, contentType := range op.Produces {
+		content[contentType] = gin.H{"schema": gin.H{"type": "string"}}
+	}
+	if len(content) > 0 {
+		success["content"] = content
+	}
+	if op.Versioned && method != http.MethodDelete {
+		success["headers"] = gin.H{"ETag": gin.H{
+			"description": "Version of the property, for If-Match and If-None-Match",
+			"schema":      gin.H{"type": "string"},
+		}}
+	}
+	responses := gin.H{strconv.Itoa(status): success}
+	if op.Versioned && method == http.MethodGet {
+		responses[strconv.Itoa(http.StatusNotModified)] = gin.H{"description": http.StatusText(http.StatusNotModified)}
+	}
+
+	// Errores según cómo está protegida la ruta y qué hace el handler
+	errors := append([]int{}, op.Errors...)
+	if len(pathParams) > 0 || len(op.Query) > 0 || op.Body != nil || len(op.BodyTypes) > 0 || op.Patch != nil {
+		errors = append(errors, http.StatusBadRequest)
+	}
+	if op.Body != nil || op.Patch != nil {
+		errors = append(errors, http.StatusUnprocessableEntity)
+	}
+	if op.Patch != nil {
+		errors = append(errors, http.StatusConflict, http.StatusUnsupportedMediaType)
+	}
+	if op.Auth {
+		errors = append(errors, http.StatusUnauthorized)
+		operation["security"] = []gin.H{{"bearerAuth": []string{}}}
+	}
+	if op.Permission != "" {
+		errors = append(errors, http.StatusForbidden)
+		operation["description"] = "Requires the `" + op.Permission + "` permission."
+		operation["x-permission"] = op.Permission
+	}
+	if op.Feature {
+		errors = append(errors, http.StatusNotFound)
+	}
+	if op.Versioned && method != http.MethodGet {
+		errors = append(errors, http.StatusPreconditionFailed, http.StatusPreconditionRequired)
+	}
+	if !op.Static {
+		errors = append(errors, http.StatusInternalServerError, http.StatusGatewayTimeout)
+	}
+	for _, code := range errors {
+		responses[strconv.Itoa(code)] = errorResponse(code)
+	}
+	operation["responses"] = responses
+	return operation
+}
+
+// Generar el documento OpenAPI a partir de las rutas registradas en el router.
+// Las rutas sin entrada en apiOperations se publican igualmente con lo que se
+// deduce de la ruta; la prueba de deriva exige documentarlas.
+func buildOpenAPISpec(routes gin.RoutesInfo) gin.H {
+	registry := &schemaRegistry{schemas: gin.H{}}
+	registry.schema(reflect.TypeOf(Problem{}))
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	paths := gin.H{}
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, apiBasePath+"/") {
+			continue
+		}
+		path, params := openAPIPath(strings.TrimPrefix(route.Path, apiBasePath))
+		op, ok := apiOperations[route.Method+" "+route.Path]
+		if !ok {
+			op = apiOperation{Summary: "Undocumented route", Tag: "undocumented"}
+		}
+
+		item, _ := paths[path].(gin.H)
+		if item == nil {
+			item = gin.H{}
+			paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = op.build(registry, route.Method, operationID(route.Handler), params)
+	}
+
+	return gin.H{
+		"openapi": "3.1.0",
+		"info": gin.H{
+			"title":       "Urbanytics API",
+			"version":     currentBuildInfo().Version,
+			"description": "Real estate sales, analytics and administration API of the Urbanytics backend.",
+		},
+		"servers": []gin.H{{"url": apiBasePath}},
+		"tags": []gin.H{
+			{"name": "auth", "description": "Sessions and tokens"},
+			{"name": "properties", "description": "Property listings, history and export"},
+			{"name": "analytics", "description": "Aggregated statistics"},
+			{"name": "profile", "description": "Authenticated user"},
+			{"name": "admin", "description": "Administration, gated by permissions"},
+			{"name": "docs", "description": "This specification"},
+		},
+		"paths": paths,
+		"components": gin.H{
+			"schemas": registry.schemas,
+			"securitySchemes": gin.H{
+				"bearerAuth": gin.H{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+}
+
+// Servir la especificación generada al arrancar
+func (app *App) getOpenAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", app.openAPISpec)
+}
+
+// Referencia interactiva con Redoc sobre openapi.json
+const apiDocsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<title>Urbanytics API</title>
+</head>
+<body>
+	<redoc spec-url="openapi.json"></redoc>
+	<script src="https://cdn.redoc.ly/redoc/v2.1.5/bundles/redoc.standalone.js"></script>
+</body>
+</html>
+`
+
+func (app *App) getAPIDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(apiDocsPage))
+}
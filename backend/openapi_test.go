@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Falla si las rutas /api/v1 de setupRoutes y la especificación se separan
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	app := NewApp(defaultConfig())
+	router := app.setupRoutes()
+
+	registered := make(map[string]bool)
+	for _, route := range router.Routes() {
+		if strings.HasPrefix(route.Path, apiBasePath+"/") {
+			registered[route.Method+" "+route.Path] = true
+		}
+	}
+
+	var undocumented, stale []string
+	for key := range registered {
+		if _, ok := apiOperations[key]; !ok {
+			undocumented = append(undocumented, key)
+		}
+	}
+	for key := range apiOperations {
+		if !registered[key] {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(undocumented)
+	sort.Strings(stale)
+	if len(undocumented) > 0 {
+		t.Errorf("routes missing from apiOperations:\n  %s", strings.Join(undocumented, "\n  "))
+	}
+	if len(stale) > 0 {
+		t.Errorf("apiOperations entries without a route:\n  %s", strings.Join(stale, "\n  "))
+	}
+
+	// El documento servido debe contener cada ruta con un operationId único
+	w := doRequest(t, router, "openapi", apiRequest{Method: http.MethodGet, Path: apiBasePath + "/openapi.json"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET openapi.json: status %d", w.Code)
+	}
+	var spec struct {
+		OpenAPI string                                       `json:"openapi"`
+		Paths   map[string]map[string]map[string]interface{} `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+	if spec.OpenAPI != "3.1.0" {
+		t.Errorf("openapi version = %q, want 3.1.0", spec.OpenAPI)
+	}
+
+	operationIDs := make(map[string]string)
+	for key := range registered {
+		method, path, _ := strings.Cut(key, " ")
+		path, _ = openAPIPath(strings.TrimPrefix(path, apiBasePath))
+		operation, ok := spec.Paths[path][strings.ToLower(method)]
+		if !ok {
+			t.Errorf("%s: not in the served document as %s %s", key, method, path)
+			continue
+		}
+		id, _ := operation["operationId"].(string)
+		if other, dup := operationIDs[id]; dup {
+			t.Errorf("operationId %q used by %s and %s", id, other, key)
+		}
+		operationIDs[id] = key
+	}
+}
+
+// Todas las referencias del documento apuntan a esquemas publicados
+func TestOpenAPIReferencesResolve(t *testing.T) {
+	spec := buildOpenAPISpec(NewApp(defaultConfig()).setupRoutes().Routes())
+	data, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				name := strings.TrimPrefix(ref, "#/components/schemas/")
+				if _, ok := schemas[name]; !ok {
+					t.Errorf("unresolved reference %s", ref)
+				}
+			}
+			for _, field := range v {
+				walk(field)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(doc)
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tamaño máximo de página en modo cursor
+const maxCursorPageSize = 1000
+
+// Direcciones de recorrido del cursor
+const (
+	cursorNext = "next"
+	cursorPrev = "prev"
+)
+
+// Cursor opaco: orden, valores de la fila límite y serial_number
+type pageCursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+	Serial int64         `json:"id"`
+	Dir    string        `json:"d"`
+}
+
+var errInvalidCursor = errors.New("Invalid cursor")
+
+// Crear cursor a partir de una fila
+func newPageCursor(fields []sortField, p *Property, dir string) *pageCursor {
+	values := make([]interface{}, len(fields))
+	for i, f := range fields {
+		values[i] = f.column().Value(p)
+	}
+	return &pageCursor{
+		Sort:   sortSpecString(fields),
+		Values: values,
+		Serial: p.SerialNumber,
+		Dir:    dir,
+	}
+}
+
+// Codificar cursor en base64 URL-safe
+func (pc *pageCursor) encode() string {
+	data, _ := json.Marshal(pc)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decodificar y validar un cursor recibido del cliente
+func decodePageCursor(encoded string) (*pageCursor, []sortField, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, errInvalidCursor
+	}
+
+	var pc pageCursor
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&pc); err != nil {
+		return nil, nil, errInvalidCursor
+	}
+	if pc.Dir != cursorNext && pc.Dir != cursorPrev {
+		return nil, nil, errInvalidCursor
+	}
+
+	fields, err := parseSortSpec(pc.Sort)
+	if err != nil || len(fields) != len(pc.Values) {
+		return nil, nil, errInvalidCursor
+	}
+
+	// Convertir cada valor al tipo Go de su columna
+	var zero Property
+	for i, f := range fields {
+		value, err := convertCursorValue(pc.Values[i], f.column().Value(&zero))
+		if err != nil {
+			return nil, nil, errInvalidCursor
+		}
+		pc.Values[i] = value
+	}
+	return &pc, fields, nil
+}
+
+// Convertir un valor JSON al mismo tipo que el valor de referencia
+func convertCursorValue(raw interface{}, reference interface{}) (interface{}, error) {
+	switch reference.(type) {
+	case string:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, errInvalidCursor
+		}
+		return s, nil
+	case int:
+		n, ok := raw.(json.Number)
+		if !ok {
+			return nil, errInvalidCursor
+		}
+		v, err := strconv.Atoi(n.String())
+		return v, err
+	case int64:
+		n, ok := raw.(json.Number)
+		if !ok {
+			return nil, errInvalidCursor
+		}
+		return n.Int64()
+	case float64:
+		n, ok := raw.(json.Number)
+		if !ok {
+			return nil, errInvalidCursor
+		}
+		return n.Float64()
+	default:
+		return nil, errInvalidCursor
+	}
+}
+
+// Construir condición keyset: filas estrictamente posteriores a values en el orden de fields
+func keysetCondition(fields []sortField, values []interface{}, argID int) (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+
+	for i, f := range fields {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = $%d", fields[j].column().Column, argID+j))
+		}
+
+		op := ">"
+		if f.Desc {
+			op = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s $%d", f.column().Column, op, argID+i))
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	args = append(args, values...)
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
+
+// Invertir dirección de todos los campos de orden
+func reverseSortFields(fields []sortField) []sortField {
+	reversed := make([]sortField, len(fields))
+	for i, f := range fields {
+		reversed[i] = sortField{Key: f.Key, Desc: !f.Desc}
+	}
+	return reversed
+}
+
+// Indica si el cliente envió explícitamente un orden
+func hasExplicitSort(c *gin.Context) bool {
+	return c.Query("sort") != "" || c.Query("sort_by") != "" || c.Query("sort_order") != ""
+}
+
+// Obtener propiedades con paginación por cursor (keyset)
+func (app *App) getPropertiesByCursor(c *gin.Context, filter PropertyFilter, sortFields []sortField, limit int) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > maxCursorPageSize {
+		limit = maxCursorPageSize
+	}
+
+	fields := withTiebreaker(sortFields)
+	backward := false
+	hasCursor := false
+	var after []interface{}
+
+	// Aplicar el cursor recibido, si existe
+	if encoded := c.Query("cursor"); encoded != "" {
+		cursor, cursorFields, err := decodePageCursor(encoded)
+		if err != nil {
+			abortProblem(c, http.StatusBadRequest, codeInvalidCursor, err.Error())
+			return
+		}
+		if hasExplicitSort(c) && sortSpecString(fields) != cursor.Sort {
+			abortProblem(c, http.StatusBadRequest, codeInvalidCursor, "Cursor does not match sort specification")
+			return
+		}
+
+		fields = cursorFields
+		backward = cursor.Dir == cursorPrev
+		hasCursor = true
+		after = cursor.Values
+	}
+
+	// Hacia atrás se recorre el orden invertido y se da la vuelta a la página
+	queryFields := fields
+	if backward {
+		queryFields = reverseSortFields(fields)
+	}
+
+	// Se pide una fila extra para saber si hay más resultados
+	properties, err := app.properties.List(c.Request.Context(), PropertyQuery{
+		Filter: filter,
+		Sort:   queryFields,
+		After:  after,
+		Limit:  limit + 1,
+	})
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to query properties")
+		return
+	}
+	if properties == nil {
+		properties = []Property{}
+	}
+
+	hasMore := len(properties) > limit
+	if hasMore {
+		properties = properties[:limit]
+	}
+	if backward {
+		for i, j := 0, len(properties)-1; i < j; i, j = i+1, j-1 {
+			properties[i], properties[j] = properties[j], properties[i]
+		}
+	}
+
+	var nextCursor, prevCursor interface{}
+	if len(properties) > 0 {
+		first, last := &properties[0], &properties[len(properties)-1]
+		if backward || hasMore {
+			nextCursor = newPageCursor(fields, last, cursorNext).encode()
+		}
+		if (backward && hasMore) || (!backward && hasCursor) {
+			prevCursor = newPageCursor(fields, first, cursorPrev).encode()
+		}
+	}
+
+	pagination := gin.H{
+		"mode":        "cursor",
+		"limit":       limit,
+		"sort":        sortSpecString(fields),
+		"next_cursor": nextCursor,
+		"prev_cursor": prevCursor,
+	}
+
+	// El total es opcional porque requiere un COUNT(*) completo
+	if includeTotal, _ := strconv.ParseBool(c.Query("include_total")); includeTotal {
+		totalCount, err := app.properties.Count(c.Request.Context(), filter)
+		if err != nil {
+			internalError(c, "Failed to count properties")
+			return
+		}
+		pagination["total_count"] = totalCount
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"data":       properties,
+		"pagination": pagination,
+	})
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestPageCursorRoundTrip(t *testing.T) {
+	for _, spec := range []string{"", "-sale_amount,town", "year,-date_recorded", "-years_until_sold,ratio,assessed_value"} {
+		t.Run(spec, func(t *testing.T) {
+			fields, err := parseSortSpec(spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fields = withTiebreaker(fields)
+			want := newPageCursor(fields, &memoryProperties[2], cursorPrev)
+
+			got, gotFields, err := decodePageCursor(want.encode())
+			if err != nil {
+				t.Fatalf("decodePageCursor: %v", err)
+			}
+			if sortSpecString(gotFields) != want.Sort || !reflect.DeepEqual(got, want) {
+				t.Errorf("decoded %+v (sort %s), want %+v", got, sortSpecString(gotFields), want)
+			}
+		})
+	}
+}
+
+func TestDecodePageCursorRejects(t *testing.T) {
+	valid := newPageCursor(withTiebreaker([]sortField{{Key: "town"}}), &memoryProperties[0], cursorNext).encode()
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := map[string]string{
+		"not base64":        "not a cursor!",
+		"padded base64":     valid + "==",
+		"truncated":         valid[:len(valid)-4],
+		"not json":          encode("town,serial_number"),
+		"unknown direction": encode(`{"s":"town,serial_number","v":["Hartford",1],"id":1,"d":"up"}`),
+		"unknown sort key":  encode(`{"s":"owner,serial_number","v":["x",1],"id":1,"d":"next"}`),
+		"missing value":     encode(`{"s":"town,serial_number","v":["Hartford"],"id":1,"d":"next"}`),
+		"string for number": encode(`{"s":"-price,serial_number","v":["180000",1],"id":1,"d":"next"}`),
+		"number for string": encode(`{"s":"town,serial_number","v":[7,1],"id":1,"d":"next"}`),
+		"fraction for int":  encode(`{"s":"year,serial_number","v":[2020.5,1],"id":1,"d":"next"}`),
+	}
+	for name, cursor := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := decodePageCursor(cursor); !errors.Is(err, errInvalidCursor) {
+				t.Errorf("error = %v, want errInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	fields := []sortField{{Key: "price", Desc: true}, {Key: "town"}, {Key: "serial_number"}}
+	values := []interface{}{180000.0, "Hartford", int64(1)}
+
+	condition, args := keysetCondition(fields, values, 3)
+	want := "((sale_amount < $3) OR (sale_amount = $3 AND town > $4) OR (sale_amount = $3 AND town = $4 AND serial_number > $5))"
+	if condition != want {
+		t.Errorf("condition = %s\nwant        %s", condition, want)
+	}
+	if !reflect.DeepEqual(args, values) {
+		t.Errorf("args = %v, want %v", args, values)
+	}
+
+	// Hacia atrás se invierte cada dirección
+	condition, _ = keysetCondition(reverseSortFields(fields), values, 1)
+	want = "((sale_amount > $1) OR (sale_amount = $1 AND town < $2) OR (sale_amount = $1 AND town = $2 AND serial_number < $3))"
+	if condition != want {
+		t.Errorf("reversed condition = %s\nwant                 %s", condition, want)
+	}
+}
+
+// Recorre las páginas del listado por cursor sobre los stores en memoria
+func TestHandlersCursorPagination(t *testing.T) {
+	_, router := newMemoryApp(t, memoryProperties...)
+
+	type page struct {
+		serials    []int64
+		next, prev string
+	}
+	get := func(query url.Values) page {
+		t.Helper()
+		w := doRequest(t, router, "cursor", apiRequest{Method: http.MethodGet, Path: "/api/v1/properties?" + query.Encode()})
+		expectStatus(t, w, http.StatusOK, "")
+		var p page
+		for _, item := range responseField(t, w, "data").([]interface{}) {
+			p.serials = append(p.serials, int64(item.(map[string]interface{})["serial_number"].(float64)))
+		}
+		p.next, _ = responseField(t, w, "pagination", "next_cursor").(string)
+		p.prev, _ = responseField(t, w, "pagination", "prev_cursor").(string)
+		return p
+	}
+	expect := func(p page, serials []int64, hasNext, hasPrev bool) {
+		t.Helper()
+		if !slices.Equal(p.serials, serials) || (p.next != "") != hasNext || (p.prev != "") != hasPrev {
+			t.Fatalf("page = %v next=%t prev=%t, want %v next=%t prev=%t", p.serials, p.next != "", p.prev != "", serials, hasNext, hasPrev)
+		}
+	}
+
+	// Orden descendente por precio: 5, 3, 4, 1, 2
+	first := get(url.Values{"cursor": {""}, "sort": {"-price"}, "limit": {"2"}})
+	expect(first, []int64{5, 3}, true, false)
+	second := get(url.Values{"cursor": {first.next}, "limit": {"2"}})
+	expect(second, []int64{4, 1}, true, true)
+	last := get(url.Values{"cursor": {second.next}, "sort": {"-price"}, "limit": {"2"}})
+	expect(last, []int64{2}, false, true)
+
+	// Volver atrás desde el final hasta el principio
+	back := get(url.Values{"cursor": {last.prev}, "limit": {"2"}})
+	expect(back, []int64{4, 1}, true, true)
+	expect(get(url.Values{"cursor": {back.prev}, "limit": {"2"}}), []int64{5, 3}, true, false)
+
+	// Orden mixto con una columna entera: año ascendente y ciudad descendente
+	first = get(url.Values{"cursor": {""}, "sort": {"year,-town"}, "limit": {"3"}})
+	expect(first, []int64{1, 2, 3}, true, false)
+	expect(get(url.Values{"cursor": {first.next}, "limit": {"3"}}), []int64{4, 5}, false, true)
+
+	// Cursores manipulados o de otro orden
+	for name, query := range map[string]url.Values{
+		"garbage":       {"cursor": {"%%%"}},
+		"tampered":      {"cursor": {base64.RawURLEncoding.EncodeToString([]byte(`{"s":"-price,serial_number","v":["x",1],"id":1,"d":"next"}`))}},
+		"other sort":    {"cursor": {first.next}, "sort": {"-price"}},
+		"legacy params": {"cursor": {first.next}, "sort_by": {"town"}},
+	} {
+		w := doRequest(t, router, name, apiRequest{Method: http.MethodGet, Path: "/api/v1/properties?" + query.Encode()})
+		expectStatus(t, w, http.StatusBadRequest, codeInvalidCursor)
+	}
+}
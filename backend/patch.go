@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+)
+
+// Tipos de contenido de PATCH: JSON Merge Patch (RFC 7396) y JSON Patch (RFC 6902)
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// Cuerpo de un PATCH ya decodificado; se aplica sobre la representación JSON del recurso
+type resourcePatch struct {
+	merge []byte
+	ops   jsonpatch.Patch
+}
+
+// Leer el cuerpo de un PATCH según su Content-Type; application/json se trata como merge patch
+func readPatch(c *gin.Context) (*resourcePatch, *APIError) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, newAPIError(http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, newAPIError(http.StatusBadRequest, codeInvalidRequestBody, "Request body is required")
+	}
+
+	switch c.ContentType() {
+	case mergePatchContentType, "application/json", "":
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return nil, newAPIError(http.StatusBadRequest, codeInvalidRequestBody, "Merge patch must be a JSON object")
+		}
+		return &resourcePatch{merge: body}, nil
+	case jsonPatchContentType:
+		ops, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			return nil, newAPIError(http.StatusBadRequest, codeInvalidRequestBody, "JSON Patch must be an array of operations")
+		}
+		return &resourcePatch{ops: ops}, nil
+	default:
+		return nil, newAPIError(http.StatusUnsupportedMediaType, codeUnsupportedMediaType,
+			"PATCH requires "+mergePatchContentType+" or "+jsonPatchContentType)
+	}
+}
+
+// Aplicar el parche sobre current y decodificar el resultado en target.
+// Un campo eliminado queda a su valor cero; los campos desconocidos son un error.
+func (rp *resourcePatch) apply(current, target interface{}) error {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+
+	var patched []byte
+	if rp.ops != nil {
+		patched, err = rp.ops.Apply(doc)
+		switch {
+		case errors.Is(err, jsonpatch.ErrTestFailed):
+			return newAPIError(http.StatusConflict, codePatchTestFailed, "JSON Patch test operation failed")
+		case err != nil:
+			return newAPIError(http.StatusUnprocessableEntity, codeInvalidPatch, "JSON Patch could not be applied: "+err.Error())
+		}
+	} else if patched, err = jsonpatch.MergePatch(doc, rp.merge); err != nil {
+		return newAPIError(http.StatusUnprocessableEntity, codeInvalidPatch, "Merge patch could not be applied")
+	}
+	return decodeResource(patched, target)
+}
+
+// Decodificar la representación JSON de un recurso rechazando los campos desconocidos
+func decodeResource(data []byte, target interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return validationFailed(FieldError{Field: strings.Trim(field, `"`), Rule: "unknown", Message: "is not a field of this resource"})
+		}
+		return bindingProblem(err)
+	}
+	return nil
+}
+
+// Error de validación para un campo que el parche no puede modificar
+func readOnlyField(field string) *APIError {
+	return validationFailed(FieldError{Field: field, Rule: "readonly", Message: "cannot be changed"})
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Permisos disponibles en el sistema
+const (
+	permPropertiesWrite = "properties:write"
+	permUsersManage     = "users:manage"
+	permRolesManage     = "roles:manage"
+	permAnalyticsExport = "analytics:export"
+	permAuditRead       = "audit:read"
+	permConfigRead      = "config:read"
+)
+
+// Catálogo de permisos con su descripción
+var permissionCatalog = map[string]string{
+	permPropertiesWrite: "Create, update, delete and import properties",
+	permUsersManage:     "Manage users and their sessions",
+	permRolesManage:     "Create roles and assign permissions",
+	permAnalyticsExport: "Export property data in bulk",
+	permAuditRead:       "Read the audit log of admin mutations",
+	permConfigRead:      "Read the effective server configuration",
+}
+
+// Roles incluidos por defecto que no se pueden eliminar
+var builtInRoles = map[string]bool{"admin": true, "user": true}
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+	BuiltIn     bool     `json:"built_in"`
+}
+
+type RoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// Verificar que todos los permisos existan en el catálogo
+func unknownPermissions(permissions []string) []FieldError {
+	var unknown []FieldError
+	for i, p := range permissions {
+		if _, ok := permissionCatalog[p]; !ok {
+			unknown = append(unknown, FieldError{
+				Field:   fmt.Sprintf("permissions[%d]", i),
+				Rule:    "permission",
+				Message: fmt.Sprintf("unknown permission '%s'", p),
+			})
+		}
+	}
+	return unknown
+}
+
+// Middleware que exige un permiso al rol del usuario autenticado
+func (app *App) requirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasPermission(c, permission) {
+			abortWithError(c, &APIError{
+				Status: http.StatusForbidden,
+				Code:   codePermissionDenied,
+				Detail: "Permission denied",
+				Extra:  gin.H{"permission": permission},
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Indica si el usuario autenticado tiene el permiso
+func hasPermission(c *gin.Context, permission string) bool {
+	value, exists := c.Get("permissions")
+	if !exists {
+		return false
+	}
+	for _, p := range value.([]string) {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Listar permisos disponibles (admin)
+func (app *App) getPermissions(c *gin.Context) {
+	names := make([]string, 0, len(permissionCatalog))
+	for name := range permissionCatalog {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	permissions := make([]gin.H, len(names))
+	for i, name := range names {
+		permissions[i] = gin.H{"name": name, "description": permissionCatalog[name]}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    permissions,
+	})
+}
+
+// Listar roles con sus permisos (admin)
+func (app *App) getRoles(c *gin.Context) {
+	roles, err := app.roles.List(c.Request.Context())
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to get roles")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    roles,
+	})
+}
+
+// Crear rol (admin)
+func (app *App) createRole(c *gin.Context) {
+	var req RoleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	req.Name = strings.ToLower(strings.TrimSpace(req.Name))
+	if !roleNamePattern.MatchString(req.Name) {
+		abortWithError(c, validationFailed(FieldError{
+			Field:   "name",
+			Rule:    "pattern",
+			Message: "must start with a lowercase letter and contain only lowercase letters, digits and underscores",
+		}))
+		return
+	}
+	if unknown := unknownPermissions(req.Permissions); len(unknown) > 0 {
+		abortWithError(c, validationFailed(unknown...))
+		return
+	}
+
+	role := Role{Name: req.Name, Description: req.Description, Permissions: req.Permissions}
+	if err := app.roles.Create(c.Request.Context(), actorFromContext(c), role); err != nil {
+		if errors.Is(err, errConflict) {
+			abortProblem(c, http.StatusConflict, codeRoleExists, "Role already exists")
+		} else {
+			log.Printf("Database error: %v", err)
+			internalError(c, "Failed to create role")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    role,
+	})
+}
+
+// Actualizar descripción y permisos de un rol (admin)
+func (app *App) updateRole(c *gin.Context) {
+	name := c.Param("name")
+
+	var req RoleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if unknown := unknownPermissions(req.Permissions); len(unknown) > 0 {
+		abortWithError(c, validationFailed(unknown...))
+		return
+	}
+
+	// Evitar que el administrador pierda el control de roles
+	if name == "admin" {
+		abortProblem(c, http.StatusBadRequest, codeRoleProtected, "The admin role cannot be modified")
+		return
+	}
+
+	role, err := app.roles.Update(c.Request.Context(), actorFromContext(c), Role{Name: name, Description: req.Description, Permissions: req.Permissions})
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			abortProblem(c, http.StatusNotFound, codeRoleNotFound, "Role not found")
+		} else {
+			log.Printf("Database error: %v", err)
+			internalError(c, "Failed to update role")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    role,
+	})
+}
+
+// Eliminar rol sin usuarios asignados (admin)
+func (app *App) deleteRole(c *gin.Context) {
+	name := c.Param("name")
+	if builtInRoles[name] {
+		abortProblem(c, http.StatusBadRequest, codeRoleProtected, "Built-in roles cannot be deleted")
+		return
+	}
+
+	err := app.roles.Delete(c.Request.Context(), actorFromContext(c), name)
+	var inUse *roleInUseError
+	switch {
+	case errors.Is(err, errNotFound):
+		abortProblem(c, http.StatusNotFound, codeRoleNotFound, "Role not found")
+		return
+	case errors.As(err, &inUse):
+		abortWithError(c, &APIError{
+			Status: http.StatusConflict,
+			Code:   codeRoleInUse,
+			Detail: "Role is assigned to users",
+			Extra:  gin.H{"users": inUse.Users},
+		})
+		return
+	case err != nil:
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to delete role")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Role deleted successfully",
+	})
+}
@@ -0,0 +1,1388 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Columnas de users expuestas en User
+const userColumns = "id, username, email, role, created_at, updated_at"
+
+// Escanear una fila de users
+func scanUser(row pgx.Row, u *User) error {
+	return row.Scan(&u.ID, &u.Username, &u.Email, &u.Role, &u.CreatedAt, &u.UpdatedAt)
+}
+
+// Violación de una restricción UNIQUE
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// Traducir los errores de pgx a los errores comunes de los stores
+func storeError(err error) error {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return errNotFound
+	case isUniqueViolation(err):
+		return errConflict
+	default:
+		return err
+	}
+}
+
+// Construir condiciones WHERE a partir de los filtros
+func propertyFilterConditions(filter PropertyFilter) ([]string, []interface{}) {
+	// Las propiedades eliminadas nunca se listan
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Town != "" {
+		add("town ILIKE $%d", "%"+filter.Town+"%")
+	}
+	if len(filter.Towns) > 0 {
+		add("town = ANY($%d)", filter.Towns)
+	}
+	if filter.MinPrice != nil {
+		add("sale_amount >= $%d", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		add("sale_amount <= $%d", *filter.MaxPrice)
+	}
+	if filter.PropertyType != "" {
+		add("property_type = $%d", filter.PropertyType)
+	}
+	if filter.ResidentialType != "" {
+		add("residential_type = $%d", filter.ResidentialType)
+	}
+	if filter.ListYear != nil {
+		add("list_year = $%d", *filter.ListYear)
+	}
+	switch filter.Status {
+	case "sold":
+		conditions = append(conditions, "sale_amount > 0")
+	case "available":
+		conditions = append(conditions, "sale_amount = 0")
+	}
+	if filter.MinSalesRatio != nil {
+		add("sales_ratio >= $%d", *filter.MinSalesRatio)
+	}
+	if filter.MaxSalesRatio != nil {
+		add("sales_ratio <= $%d", *filter.MaxSalesRatio)
+	}
+	if filter.MinYearsUntilSold != nil {
+		add("years_until_sold >= $%d", *filter.MinYearsUntilSold)
+	}
+	if filter.MaxYearsUntilSold != nil {
+		add("years_until_sold <= $%d", *filter.MaxYearsUntilSold)
+	}
+	// Todas las palabras como prefijo (índice GIN del vector) o parecido por trigramas
+	if filter.Search != "" {
+		args = append(args, prefixTSQuery(searchTerms(filter.Search), ""), filter.Search)
+		conditions = append(conditions, fmt.Sprintf("(%s @@ to_tsquery('simple', $%d) OR $%[3]d <%% address OR $%[3]d %% town)",
+			propertySearchVector, len(args)-1, len(args)))
+	}
+
+	return conditions, args
+}
+
+// Tabla o subconsulta histórica de la que leer y condiciones de filtrado
+func propertySource(filter PropertyFilter) (string, []string, []interface{}) {
+	conditions, args := propertyFilterConditions(filter)
+
+	// Con AsOf se consulta el estado histórico en lugar de la tabla actual
+	if filter.AsOf != nil {
+		args = append(args, *filter.AsOf)
+		return propertiesAsOf(len(args)), conditions, args
+	}
+	return "properties", conditions, args
+}
+
+// Unir condiciones en una cláusula WHERE
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// Leer todas las filas de una consulta de propiedades
+func collectProperties(rows pgx.Rows) ([]Property, error) {
+	defer rows.Close()
+
+	var properties []Property
+	for rows.Next() {
+		var p Property
+		if err := scanProperty(rows, &p); err != nil {
+			return nil, err
+		}
+		properties = append(properties, p)
+	}
+	return properties, rows.Err()
+}
+
+// Leer una columna de valores de una consulta
+func collectColumn[T any](rows pgx.Rows) ([]T, error) {
+	defer rows.Close()
+
+	var values []T
+	for rows.Next() {
+		var value T
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// Valores de Property en el orden de propertyColumns
+func propertyValues(p Property) []interface{} {
+	return []interface{}{p.SerialNumber, p.ListYear, p.DateRecorded, p.Town, p.Address, p.AssessedValue, p.SaleAmount, p.SalesRatio, p.PropertyType, p.ResidentialType, p.YearsUntilSold}
+}
+
+// Columnas escritas en cada inserción o modificación: las de Property y los
+// componentes normalizados de la dirección
+const propertyRowColumns = propertyColumns + ", " + addressColumns
+
+// Valores de propertyRowColumns
+func propertyRowValues(p Property) []interface{} {
+	return append(propertyValues(p), addressValues(p.Address)...)
+}
+
+// "$1, $2, ..., $n" para una lista de n columnas
+func placeholders(columns string) string {
+	values := strings.Split(columns, ", ")
+	for i := range values {
+		values[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return strings.Join(values, ", ")
+}
+
+// "EXCLUDED.a, EXCLUDED.b" para ON CONFLICT DO UPDATE
+func excludedColumns(columns string) string {
+	return "EXCLUDED." + strings.ReplaceAll(columns, ", ", ", EXCLUDED.")
+}
+
+// Asignaciones "columna = $n" de los valores que cambian y sus argumentos
+func changedColumns(columns []string, before, after []interface{}) ([]string, []interface{}) {
+	var assignments []string
+	var args []interface{}
+	for i, column := range columns {
+		if before[i] != after[i] {
+			args = append(args, after[i])
+			assignments = append(assignments, fmt.Sprintf("%s = $%d", column, len(args)))
+		}
+	}
+	return assignments, args
+}
+
+// PropertyStore sobre PostgreSQL
+type pgPropertyStore struct {
+	db *pgxpool.Pool
+}
+
+func newPgPropertyStore(db *pgxpool.Pool) *pgPropertyStore {
+	return &pgPropertyStore{db: db}
+}
+
+func (s *pgPropertyStore) List(ctx context.Context, q PropertyQuery) ([]Property, error) {
+	source, conditions, args := propertySource(q.Filter)
+	if q.After != nil {
+		condition, afterArgs := keysetCondition(q.Sort, q.After, len(args)+1)
+		conditions = append(conditions, condition)
+		args = append(args, afterArgs...)
+	}
+
+	query := "SELECT " + propertyColumns + " FROM " + source + whereClause(conditions) + buildOrderBy(q.Sort)
+	if q.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+	if q.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", q.Offset)
+	}
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return collectProperties(rows)
+}
+
+func (s *pgPropertyStore) Search(ctx context.Context, q PropertyQuery) ([]PropertyMatch, error) {
+	source, conditions, args := propertySource(q.Filter)
+	args = append(args, prefixTSQuery(searchTerms(q.Filter.Search), ""), q.Filter.Search)
+	relevance := fmt.Sprintf("round((ts_rank(%s, to_tsquery('simple', $%d)) + greatest(word_similarity($%[3]d, address), similarity($%[3]d, town)))::numeric, 4)::float8",
+		propertySearchVector, len(args)-1, len(args))
+
+	orderBy := " ORDER BY relevance DESC, serial_number ASC"
+	if len(q.Sort) > 0 {
+		orderBy = buildOrderBy(q.Sort)
+	}
+	query := "SELECT " + propertyColumns + ", " + relevance + " AS relevance FROM " + source + whereClause(conditions) + orderBy
+	if q.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+	if q.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", q.Offset)
+	}
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []PropertyMatch
+	for rows.Next() {
+		var m PropertyMatch
+		if err := rows.Scan(append(propertyFields(&m.Property), &m.Relevance)...); err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// Las ciudades se completan por prefijo y las direcciones por prefijo de cualquier
+// palabra; ambas también por parecido. Lo que empieza igual va primero.
+func (s *pgPropertyStore) Suggest(ctx context.Context, q string, limit int) ([]PropertySuggestion, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT type, value, town, matches FROM (
+			SELECT 'town' AS type, town AS value, '' AS town, COUNT(*) AS matches,
+			       similarity($1, town) + (town ILIKE $2)::int AS score
+			FROM properties
+			WHERE deleted_at IS NULL AND town != 'Nan' AND (town ILIKE $2 OR $1 % town)
+			GROUP BY town
+			UNION ALL
+			SELECT 'address', address, town, COUNT(*),
+			       word_similarity($1, address) + (`+propertySearchVector+` @@ to_tsquery('simple', $3))::int
+			FROM properties
+			WHERE deleted_at IS NULL AND address != 'Nan' AND (`+propertySearchVector+` @@ to_tsquery('simple', $3) OR $1 <% address)
+			GROUP BY address, town
+		) suggestions
+		ORDER BY score DESC, matches DESC, value, town
+		LIMIT $4
+	`, q, likePrefix(q), prefixTSQuery(searchTerms(q), "A"), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []PropertySuggestion{}
+	for rows.Next() {
+		var suggestion PropertySuggestion
+		if err := rows.Scan(&suggestion.Type, &suggestion.Value, &suggestion.Town, &suggestion.Count); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, rows.Err()
+}
+
+func (s *pgPropertyStore) LookupAddress(ctx context.Context, normalized, town string, limit int) ([]Property, error) {
+	rows, err := s.db.Query(ctx,
+		"SELECT "+propertyColumns+" FROM properties WHERE deleted_at IS NULL AND address_normalized = $1 AND ($2 = '' OR town = $2) ORDER BY serial_number LIMIT $3",
+		normalized, town, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectProperties(rows)
+}
+
+// Grupos de más de una propiedad vigente; las filas sin normalizar no cuentan
+const addressDuplicateGroups = `
+	FROM properties
+	WHERE deleted_at IS NULL AND address_normalized <> '' AND ($1 = '' OR town = $1)
+	GROUP BY town, address_normalized
+	HAVING COUNT(*) > 1`
+
+func (s *pgPropertyStore) AddressDuplicates(ctx context.Context, town string, limit, offset int) ([]AddressDuplicate, int, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT town, address_normalized, COUNT(*), array_agg(serial_number ORDER BY serial_number)`+addressDuplicateGroups+`
+		ORDER BY COUNT(*) DESC, town, address_normalized
+		LIMIT $2 OFFSET $3`, town, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	duplicates := []AddressDuplicate{}
+	for rows.Next() {
+		var d AddressDuplicate
+		if err := rows.Scan(&d.Town, &d.Address, &d.Count, &d.SerialNumbers); err != nil {
+			return nil, 0, err
+		}
+		duplicates = append(duplicates, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = s.db.QueryRow(ctx, "SELECT COUNT(*) FROM (SELECT 1"+addressDuplicateGroups+") duplicates", town).Scan(&total)
+	return duplicates, total, err
+}
+
+func (s *pgPropertyStore) Count(ctx context.Context, filter PropertyFilter) (int, error) {
+	source, conditions, args := propertySource(filter)
+	var count int
+	err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM "+source+whereClause(conditions), args...).Scan(&count)
+	return count, err
+}
+
+func (s *pgPropertyStore) Get(ctx context.Context, id int64, asOf *time.Time) (Property, error) {
+	query := "SELECT " + propertyColumns + " FROM properties WHERE serial_number = $1 AND deleted_at IS NULL"
+	args := []interface{}{id}
+	if asOf != nil {
+		query = "SELECT " + propertyColumns + " FROM " + propertiesAsOf(2) + " WHERE serial_number = $1"
+		args = append(args, *asOf)
+	}
+
+	var p Property
+	err := scanProperty(s.db.QueryRow(ctx, query, args...), &p)
+	return p, storeError(err)
+}
+
+func (s *pgPropertyStore) GetWithVersion(ctx context.Context, id int64) (Property, int64, error) {
+	var p Property
+	var version int64
+	err := s.db.QueryRow(ctx, "SELECT "+propertyColumns+", version FROM properties WHERE serial_number = $1 AND deleted_at IS NULL", id).
+		Scan(append(propertyFields(&p), &version)...)
+	return p, version, storeError(err)
+}
+
+// Valores distintos de una columna de texto, sin los registros 'Nan'
+func (s *pgPropertyStore) distinct(ctx context.Context, column string) ([]string, error) {
+	rows, err := s.db.Query(ctx, fmt.Sprintf(
+		"SELECT DISTINCT %[1]s FROM properties WHERE deleted_at IS NULL AND %[1]s IS NOT NULL AND %[1]s != 'Nan' ORDER BY %[1]s", column))
+	if err != nil {
+		return nil, err
+	}
+	return collectColumn[string](rows)
+}
+
+func (s *pgPropertyStore) Towns(ctx context.Context) ([]string, error) {
+	return s.distinct(ctx, "town")
+}
+
+func (s *pgPropertyStore) PropertyTypes(ctx context.Context) ([]string, error) {
+	return s.distinct(ctx, "property_type")
+}
+
+func (s *pgPropertyStore) ResidentialTypes(ctx context.Context) ([]string, error) {
+	return s.distinct(ctx, "residential_type")
+}
+
+// Tablas de referencia de town, property_type y residential_type
+func (s *pgPropertyStore) Reference(ctx context.Context) (PropertyReference, error) {
+	var ref PropertyReference
+	for _, target := range []struct {
+		table  string
+		values *[]string
+	}{
+		{"towns", &ref.Towns},
+		{"property_types", &ref.PropertyTypes},
+		{"residential_types", &ref.ResidentialTypes},
+	} {
+		rows, err := s.db.Query(ctx, "SELECT name FROM "+target.table+" ORDER BY name")
+		if err != nil {
+			return PropertyReference{}, err
+		}
+		if *target.values, err = collectColumn[string](rows); err != nil {
+			return PropertyReference{}, err
+		}
+	}
+	return ref, nil
+}
+
+func (s *pgPropertyStore) ListYears(ctx context.Context) ([]int, error) {
+	rows, err := s.db.Query(ctx, "SELECT DISTINCT list_year FROM properties WHERE deleted_at IS NULL AND list_year IS NOT NULL ORDER BY list_year")
+	if err != nil {
+		return nil, err
+	}
+	return collectColumn[int](rows)
+}
+
+// Ejecutar una mutación en una transacción con el autor registrado para los triggers
+func (s *pgPropertyStore) mutate(ctx context.Context, actor *Actor, fn func(tx pgx.Tx) error) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := setChangeActor(ctx, tx, actor); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Bloquear la propiedad vigente para escribirla comprobando la versión esperada (0 no comprueba)
+func lockProperty(ctx context.Context, tx pgx.Tx, id, version int64) (Property, int64, error) {
+	var p Property
+	var current int64
+	err := tx.QueryRow(ctx, "SELECT "+propertyColumns+", version FROM properties WHERE serial_number = $1 AND deleted_at IS NULL FOR UPDATE", id).
+		Scan(append(propertyFields(&p), &current)...)
+	if err != nil {
+		return Property{}, 0, storeError(err)
+	}
+	if version != 0 && version != current {
+		return Property{}, 0, errVersionMismatch
+	}
+	return p, current, nil
+}
+
+// Insertar una propiedad y auditarla dentro de tx
+func insertProperty(ctx context.Context, tx pgx.Tx, actor *Actor, p Property) (int64, error) {
+	var version int64
+	err := tx.QueryRow(ctx,
+		"INSERT INTO properties ("+propertyRowColumns+") VALUES ("+placeholders(propertyRowColumns)+") RETURNING version",
+		propertyRowValues(p)...).Scan(&version)
+	if err != nil {
+		return 0, storeError(err)
+	}
+	return version, recordAudit(ctx, tx, actor, auditCreate, "property", p.SerialNumber, nil, p)
+}
+
+// Aplicar apply sobre la fila bloqueada y escribir solo las columnas que cambian
+func patchPropertyRow(ctx context.Context, tx pgx.Tx, actor *Actor, id, version int64, apply func(p *Property) error) (Property, int64, error) {
+	// La fila queda bloqueada mientras se aplica y valida el cambio
+	before, current, err := lockProperty(ctx, tx, id, version)
+	if err != nil {
+		return Property{}, 0, err
+	}
+	after := before
+	if err := apply(&after); err != nil {
+		return Property{}, 0, err
+	}
+
+	assignments, args := changedColumns(strings.Split(propertyRowColumns, ", "), propertyRowValues(before), propertyRowValues(after))
+	if len(assignments) == 0 {
+		return after, current, nil
+	}
+	args = append(args, id)
+	err = tx.QueryRow(ctx, fmt.Sprintf("UPDATE properties SET %s WHERE serial_number = $%d RETURNING version", strings.Join(assignments, ", "), len(args)), args...).Scan(&current)
+	if err != nil {
+		return Property{}, 0, storeError(err)
+	}
+	return after, current, recordAudit(ctx, tx, actor, auditUpdate, "property", id, before, after)
+}
+
+// Mover la propiedad a la papelera y auditarlo dentro de tx
+func deletePropertyRow(ctx context.Context, tx pgx.Tx, actor *Actor, id, version int64) error {
+	// Estado previo para la auditoría
+	before, _, err := lockProperty(ctx, tx, id, version)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "UPDATE properties SET deleted_at = CURRENT_TIMESTAMP, deleted_by = $2 WHERE serial_number = $1", id, actorUsername(actor)); err != nil {
+		return err
+	}
+	return recordAudit(ctx, tx, actor, auditDelete, "property", id, before, nil)
+}
+
+func (s *pgPropertyStore) Create(ctx context.Context, actor *Actor, p Property) error {
+	return s.mutate(ctx, actor, func(tx pgx.Tx) error {
+		_, err := insertProperty(ctx, tx, actor, p)
+		return err
+	})
+}
+
+func (s *pgPropertyStore) Update(ctx context.Context, actor *Actor, p Property, version int64) (int64, error) {
+	var updated int64
+	err := s.mutate(ctx, actor, func(tx pgx.Tx) error {
+		// Estado previo para la auditoría
+		before, _, err := lockProperty(ctx, tx, p.SerialNumber, version)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx,
+			"UPDATE properties SET list_year = $1, date_recorded = $2, town = $3, address = $4, assessed_value = $5, sale_amount = $6, sales_ratio = $7, property_type = $8, residential_type = $9, years_until_sold = $10, ("+addressColumns+") = ($12, $13, $14, $15, $16, $17, $18, $19) WHERE serial_number = $11 RETURNING version",
+			append([]interface{}{p.ListYear, p.DateRecorded, p.Town, p.Address, p.AssessedValue, p.SaleAmount, p.SalesRatio, p.PropertyType, p.ResidentialType, p.YearsUntilSold, p.SerialNumber}, addressValues(p.Address)...)...).Scan(&updated)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, actor, auditUpdate, "property", p.SerialNumber, before, p)
+	})
+	return updated, err
+}
+
+func (s *pgPropertyStore) Patch(ctx context.Context, actor *Actor, id int64, version int64, apply func(p *Property) error) (Property, int64, error) {
+	var after Property
+	var updated int64
+	err := s.mutate(ctx, actor, func(tx pgx.Tx) error {
+		var err error
+		after, updated, err = patchPropertyRow(ctx, tx, actor, id, version, apply)
+		return err
+	})
+	return after, updated, err
+}
+
+func (s *pgPropertyStore) Delete(ctx context.Context, actor *Actor, id int64, version int64) error {
+	return s.mutate(ctx, actor, func(tx pgx.Tx) error {
+		return deletePropertyRow(ctx, tx, actor, id, version)
+	})
+}
+
+// Filas vigentes que cumplen el filtro, bloqueadas hasta el final de tx
+func filteredPropertyIDs(ctx context.Context, tx pgx.Tx, filter PropertyFilter) ([]int64, error) {
+	conditions, args := propertyFilterConditions(filter)
+	rows, err := tx.Query(ctx, "SELECT serial_number FROM properties"+whereClause(conditions)+" ORDER BY serial_number FOR UPDATE", args...)
+	if err != nil {
+		return nil, err
+	}
+	return collectColumn[int64](rows)
+}
+
+func (s *pgPropertyStore) Bulk(ctx context.Context, actor *Actor, ops []PropertyOperation, atomic bool) ([]PropertyOperationResult, bool, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback(ctx)
+	if err := setChangeActor(ctx, tx, actor); err != nil {
+		return nil, false, err
+	}
+
+	var results []PropertyOperationResult
+	failed := false
+	for i, op := range ops {
+		ids := []int64{op.ID}
+		if op.Kind == bulkCreate {
+			ids = []int64{op.Property.SerialNumber}
+		}
+		if op.Filter != nil {
+			if ids, err = filteredPropertyIDs(ctx, tx, *op.Filter); err != nil {
+				return nil, false, err
+			}
+		}
+
+		for _, id := range ids {
+			result := PropertyOperationResult{Index: i, ID: id}
+			// Cada fila en su propio savepoint para descartarla sin perder las demás
+			result.Err = pgx.BeginFunc(ctx, tx, func(sp pgx.Tx) error {
+				var err error
+				switch op.Kind {
+				case bulkCreate:
+					p := op.Property
+					if err := applyOptional(op.Apply, &p); err != nil {
+						return err
+					}
+					result.Version, err = insertProperty(ctx, sp, actor, p)
+				case bulkUpdate:
+					_, result.Version, err = patchPropertyRow(ctx, sp, actor, id, op.Version, op.Apply)
+				case bulkDelete:
+					err = deletePropertyRow(ctx, sp, actor, id, op.Version)
+				default:
+					err = fmt.Errorf("unknown bulk operation %q", op.Kind)
+				}
+				return err
+			})
+			if ctx.Err() != nil {
+				return nil, false, ctx.Err()
+			}
+			failed = failed || result.Err != nil
+			results = append(results, result)
+		}
+	}
+
+	if failed && atomic {
+		return results, false, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, err
+	}
+	return results, true, nil
+}
+
+func (s *pgPropertyStore) ListDeleted(ctx context.Context, limit, offset int) ([]DeletedProperty, int, error) {
+	rows, err := s.db.Query(ctx,
+		"SELECT "+propertyColumns+", deleted_at, deleted_by FROM properties WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, serial_number LIMIT $1 OFFSET $2",
+		limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	properties := []DeletedProperty{}
+	for rows.Next() {
+		var p DeletedProperty
+		err := rows.Scan(&p.SerialNumber, &p.ListYear, &p.DateRecorded, &p.Town, &p.Address, &p.AssessedValue, &p.SaleAmount, &p.SalesRatio, &p.PropertyType, &p.ResidentialType, &p.YearsUntilSold,
+			&p.DeletedAt, &p.DeletedBy)
+		if err != nil {
+			return nil, 0, err
+		}
+		properties = append(properties, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = s.db.QueryRow(ctx, "SELECT COUNT(*) FROM properties WHERE deleted_at IS NOT NULL").Scan(&total)
+	return properties, total, err
+}
+
+func (s *pgPropertyStore) Restore(ctx context.Context, actor *Actor, id int64) (Property, error) {
+	var property Property
+	err := s.mutate(ctx, actor, func(tx pgx.Tx) error {
+		err := scanProperty(tx.QueryRow(ctx,
+			"UPDATE properties SET deleted_at = NULL, deleted_by = NULL WHERE serial_number = $1 AND deleted_at IS NOT NULL RETURNING "+propertyColumns,
+			id), &property)
+		if err != nil {
+			return storeError(err)
+		}
+		return recordAudit(ctx, tx, actor, auditRestore, "property", id, nil, property)
+	})
+	return property, err
+}
+
+func (s *pgPropertyStore) History(ctx context.Context, id int64) ([]PropertyRevision, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT revision_id, operation, valid_from, valid_to, changed_by, `+propertyColumns+`
+		FROM property_history
+		WHERE serial_number = $1
+		ORDER BY revision_id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []PropertyRevision
+	for rows.Next() {
+		var r PropertyRevision
+		var p Property
+		err := rows.Scan(&r.RevisionID, &r.Operation, &r.ValidFrom, &r.ValidTo, &r.ChangedBy,
+			&p.SerialNumber, &p.ListYear, &p.DateRecorded, &p.Town, &p.Address, &p.AssessedValue, &p.SaleAmount, &p.SalesRatio, &p.PropertyType, &p.ResidentialType, &p.YearsUntilSold)
+		if err != nil {
+			return nil, err
+		}
+		// Una eliminación no tiene estado posterior
+		if r.Operation != revisionDelete {
+			r.Data = &p
+		}
+		revisions = append(revisions, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return withRevisionDiffs(revisions), nil
+}
+
+// Nombre del autor para deleted_by; vacío en tareas sin petición
+func actorUsername(actor *Actor) string {
+	if actor == nil {
+		return ""
+	}
+	return actor.Username
+}
+
+// UserStore sobre PostgreSQL
+type pgUserStore struct {
+	db *pgxpool.Pool
+}
+
+func newPgUserStore(db *pgxpool.Pool) *pgUserStore {
+	return &pgUserStore{db: db}
+}
+
+func (s *pgUserStore) Credentials(ctx context.Context, username string) (User, string, error) {
+	var user User
+	var passwordHash string
+	err := s.db.QueryRow(ctx,
+		"SELECT id, username, email, role, password_hash FROM users WHERE username = $1 AND deleted_at IS NULL",
+		username).Scan(&user.ID, &user.Username, &user.Email, &user.Role, &passwordHash)
+	return user, passwordHash, storeError(err)
+}
+
+func (s *pgUserStore) Get(ctx context.Context, id int) (User, error) {
+	var user User
+	err := scanUser(s.db.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1 AND deleted_at IS NULL", id), &user)
+	return user, storeError(err)
+}
+
+func (s *pgUserStore) List(ctx context.Context) ([]User, error) {
+	rows, err := s.db.Query(ctx, "SELECT "+userColumns+" FROM users WHERE deleted_at IS NULL ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var user User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (s *pgUserStore) RoleExists(ctx context.Context, role string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM roles WHERE name = $1)", role).Scan(&exists)
+	return exists, err
+}
+
+func (s *pgUserStore) Register(ctx context.Context, u NewUser) (User, error) {
+	var user User
+	err := scanUser(s.db.QueryRow(ctx,
+		"INSERT INTO users (username, email, password_hash, role) VALUES ($1, $2, $3, $4) RETURNING "+userColumns,
+		u.Username, u.Email, u.PasswordHash, u.Role), &user)
+	return user, storeError(err)
+}
+
+// Ejecutar una mutación de usuarios en una transacción
+func (s *pgUserStore) mutate(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *pgUserStore) Create(ctx context.Context, actor *Actor, u NewUser) (User, error) {
+	var created User
+	err := s.mutate(ctx, func(tx pgx.Tx) error {
+		err := scanUser(tx.QueryRow(ctx,
+			"INSERT INTO users (username, email, password_hash, role) VALUES ($1, $2, $3, $4) RETURNING "+userColumns,
+			u.Username, u.Email, u.PasswordHash, u.Role), &created)
+		if err != nil {
+			return storeError(err)
+		}
+		return recordAudit(ctx, tx, actor, auditCreate, "user", created.ID, nil, created)
+	})
+	return created, err
+}
+
+func (s *pgUserStore) UpdateEmail(ctx context.Context, id int, email string) error {
+	result, err := s.db.Exec(ctx,
+		"UPDATE users SET email = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2",
+		email, id)
+	if err != nil {
+		return storeError(err)
+	}
+	if result.RowsAffected() == 0 {
+		return errNotFound
+	}
+	return nil
+}
+
+func (s *pgUserStore) Update(ctx context.Context, actor *Actor, id int, email, role string) (User, error) {
+	var after User
+	err := s.mutate(ctx, func(tx pgx.Tx) error {
+		// Estado previo para la auditoría
+		var before User
+		err := scanUser(tx.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id), &before)
+		if err != nil {
+			return storeError(err)
+		}
+
+		err = scanUser(tx.QueryRow(ctx,
+			"UPDATE users SET email = $1, role = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3 RETURNING "+userColumns,
+			email, role, id), &after)
+		if err != nil {
+			return storeError(err)
+		}
+		return recordAudit(ctx, tx, actor, auditUpdate, "user", id, before, after)
+	})
+	return after, err
+}
+
+func (s *pgUserStore) Patch(ctx context.Context, actor *Actor, id int, apply func(u *User) error) (User, error) {
+	var after User
+	err := s.mutate(ctx, func(tx pgx.Tx) error {
+		var before User
+		err := scanUser(tx.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id), &before)
+		if err != nil {
+			return storeError(err)
+		}
+		after = before
+		if err := apply(&after); err != nil {
+			return err
+		}
+
+		assignments, args := changedColumns([]string{"email", "role"},
+			[]interface{}{before.Email, before.Role}, []interface{}{after.Email, after.Role})
+		if len(assignments) == 0 {
+			return nil
+		}
+		args = append(args, id)
+		err = scanUser(tx.QueryRow(ctx,
+			fmt.Sprintf("UPDATE users SET %s, updated_at = CURRENT_TIMESTAMP WHERE id = $%d RETURNING "+userColumns, strings.Join(assignments, ", "), len(args)),
+			args...), &after)
+		if err != nil {
+			return storeError(err)
+		}
+		return recordAudit(ctx, tx, actor, auditUpdate, "user", id, before, after)
+	})
+	return after, err
+}
+
+func (s *pgUserStore) Delete(ctx context.Context, actor *Actor, id int) error {
+	return s.mutate(ctx, func(tx pgx.Tx) error {
+		// Marcar como eliminado devolviendo la fila para la auditoría
+		var before User
+		err := scanUser(tx.QueryRow(ctx,
+			"UPDATE users SET deleted_at = CURRENT_TIMESTAMP, deleted_by = $2 WHERE id = $1 AND deleted_at IS NULL RETURNING "+userColumns,
+			id, actorUsername(actor)), &before)
+		if err != nil {
+			return storeError(err)
+		}
+
+		// Un usuario eliminado no conserva sesiones activas
+		if _, err := tx.Exec(ctx, "UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL", id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, actor, auditDelete, "user", id, before, nil)
+	})
+}
+
+func (s *pgUserStore) ListDeleted(ctx context.Context, limit, offset int) ([]DeletedUser, int, error) {
+	rows, err := s.db.Query(ctx,
+		"SELECT "+userColumns+", deleted_at, deleted_by FROM users WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id LIMIT $1 OFFSET $2",
+		limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []DeletedUser{}
+	for rows.Next() {
+		var u DeletedUser
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.Role, &u.CreatedAt, &u.UpdatedAt, &u.DeletedAt, &u.DeletedBy); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = s.db.QueryRow(ctx, "SELECT COUNT(*) FROM users WHERE deleted_at IS NOT NULL").Scan(&total)
+	return users, total, err
+}
+
+func (s *pgUserStore) Restore(ctx context.Context, actor *Actor, id int) (User, error) {
+	var user User
+	err := s.mutate(ctx, func(tx pgx.Tx) error {
+		err := scanUser(tx.QueryRow(ctx,
+			"UPDATE users SET deleted_at = NULL, deleted_by = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NOT NULL RETURNING "+userColumns,
+			id), &user)
+		if err != nil {
+			return storeError(err)
+		}
+		return recordAudit(ctx, tx, actor, auditRestore, "user", id, nil, user)
+	})
+	return user, err
+}
+
+// SessionStore sobre PostgreSQL
+type pgSessionStore struct {
+	db *pgxpool.Pool
+}
+
+func newPgSessionStore(db *pgxpool.Pool) *pgSessionStore {
+	return &pgSessionStore{db: db}
+}
+
+func (s *pgSessionStore) Create(ctx context.Context, session NewSession) (int64, error) {
+	var id int64
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO sessions (user_id, refresh_token_hash, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP + make_interval(secs => $5))
+		RETURNING id
+	`, session.UserID, session.RefreshHash, session.UserAgent, session.ClientIP, session.TTL.Seconds()).Scan(&id)
+	return id, err
+}
+
+func (s *pgSessionStore) Active(ctx context.Context, sessionID int64, userID int) (string, []string, error) {
+	var role string
+	var permissions []string
+	err := s.db.QueryRow(ctx, `
+		SELECT u.role,
+			COALESCE(array_agg(rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+		FROM sessions s
+		JOIN users u ON u.id = s.user_id
+		LEFT JOIN role_permissions rp ON rp.role = u.role
+		WHERE s.id = $1 AND s.user_id = $2 AND s.revoked_at IS NULL AND s.expires_at > CURRENT_TIMESTAMP
+			AND u.deleted_at IS NULL
+		GROUP BY u.role
+	`, sessionID, userID).Scan(&role, &permissions)
+	return role, permissions, storeError(err)
+}
+
+func (s *pgSessionStore) Rotate(ctx context.Context, presentedHash, newHash string) (SessionOwner, error) {
+	var owner SessionOwner
+	var current bool
+	err := s.db.QueryRow(ctx, `
+		SELECT s.id, s.user_id, u.username, u.role, s.refresh_token_hash = $1
+		FROM sessions s
+		JOIN users u ON u.id = s.user_id
+		WHERE (s.refresh_token_hash = $1 OR s.previous_token_hash = $1)
+			AND s.revoked_at IS NULL AND s.expires_at > CURRENT_TIMESTAMP AND u.deleted_at IS NULL
+	`, presentedHash).Scan(&owner.SessionID, &owner.UserID, &owner.Username, &owner.Role, &current)
+	if err != nil {
+		return SessionOwner{}, storeError(err)
+	}
+
+	// Un token ya rotado indica robo: se revoca la sesión completa
+	if !current {
+		if _, err := s.Revoke(ctx, owner.SessionID); err != nil {
+			return SessionOwner{}, err
+		}
+		return owner, errRefreshTokenReuse
+	}
+
+	// La condición sobre el hash actual evita rotaciones concurrentes
+	result, err := s.db.Exec(ctx, `
+		UPDATE sessions
+		SET previous_token_hash = refresh_token_hash, refresh_token_hash = $1, last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND refresh_token_hash = $3 AND revoked_at IS NULL
+	`, newHash, owner.SessionID, presentedHash)
+	if err != nil {
+		return SessionOwner{}, err
+	}
+	if result.RowsAffected() == 0 {
+		return SessionOwner{}, errNotFound
+	}
+	return owner, nil
+}
+
+// Revocar las sesiones activas que cumplen condition
+func (s *pgSessionStore) revoke(ctx context.Context, condition string, arg interface{}) (int64, error) {
+	result, err := s.db.Exec(ctx, "UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE "+condition+" = $1 AND revoked_at IS NULL", arg)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func (s *pgSessionStore) RevokeToken(ctx context.Context, refreshHash string) (int64, error) {
+	return s.revoke(ctx, "refresh_token_hash", refreshHash)
+}
+
+func (s *pgSessionStore) Revoke(ctx context.Context, sessionID int64) (int64, error) {
+	return s.revoke(ctx, "id", sessionID)
+}
+
+func (s *pgSessionStore) RevokeUser(ctx context.Context, userID int) (int64, error) {
+	return s.revoke(ctx, "user_id", userID)
+}
+
+// RoleStore sobre PostgreSQL
+type pgRoleStore struct {
+	db *pgxpool.Pool
+}
+
+func newPgRoleStore(db *pgxpool.Pool) *pgRoleStore {
+	return &pgRoleStore{db: db}
+}
+
+func (s *pgRoleStore) List(ctx context.Context) ([]Role, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT r.name, COALESCE(r.description, ''),
+			COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role = r.name
+		GROUP BY r.name, r.description
+		ORDER BY r.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []Role{}
+	for rows.Next() {
+		var role Role
+		if err := rows.Scan(&role.Name, &role.Description, &role.Permissions); err != nil {
+			return nil, err
+		}
+		role.BuiltIn = builtInRoles[role.Name]
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// Leer un rol con sus permisos bloqueándolo para actualizarlo
+func lockRole(ctx context.Context, tx pgx.Tx, name string) (Role, error) {
+	role := Role{Name: name, BuiltIn: builtInRoles[name]}
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE(description, ''),
+			COALESCE((SELECT array_agg(permission ORDER BY permission) FROM role_permissions WHERE role = roles.name), '{}')
+		FROM roles WHERE name = $1
+		FOR UPDATE
+	`, name).Scan(&role.Description, &role.Permissions)
+	return role, storeError(err)
+}
+
+// Reemplazar los permisos de un rol dentro de tx
+func replaceRolePermissions(ctx context.Context, tx pgx.Tx, role string, permissions []string) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM role_permissions WHERE role = $1", role); err != nil {
+		return err
+	}
+	for _, p := range permissions {
+		if _, err := tx.Exec(ctx,
+			"INSERT INTO role_permissions (role, permission) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+			role, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *pgRoleStore) Create(ctx context.Context, actor *Actor, role Role) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "INSERT INTO roles (name, description) VALUES ($1, $2)", role.Name, role.Description); err != nil {
+			return storeError(err)
+		}
+		if err := replaceRolePermissions(ctx, tx, role.Name, role.Permissions); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, actor, auditCreate, "role", role.Name, nil, role)
+	})
+}
+
+func (s *pgRoleStore) Update(ctx context.Context, actor *Actor, role Role) (Role, error) {
+	role.BuiltIn = builtInRoles[role.Name]
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		before, err := lockRole(ctx, tx, role.Name)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "UPDATE roles SET description = $1 WHERE name = $2", role.Description, role.Name); err != nil {
+			return err
+		}
+		if err := replaceRolePermissions(ctx, tx, role.Name, role.Permissions); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, actor, auditUpdate, "role", role.Name, before, role)
+	})
+	return role, err
+}
+
+func (s *pgRoleStore) Delete(ctx context.Context, actor *Actor, name string) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		before, err := lockRole(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+
+		var assigned int
+		if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM users WHERE role = $1", name).Scan(&assigned); err != nil {
+			return err
+		}
+		if assigned > 0 {
+			return &roleInUseError{Users: assigned}
+		}
+
+		if _, err := tx.Exec(ctx, "DELETE FROM roles WHERE name = $1", name); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, actor, auditDelete, "role", name, before, nil)
+	})
+}
+
+// AuditStore sobre PostgreSQL
+type pgAuditStore struct {
+	db *pgxpool.Pool
+}
+
+func newPgAuditStore(db *pgxpool.Pool) *pgAuditStore {
+	return &pgAuditStore{db: db}
+}
+
+// Condiciones WHERE de los filtros de auditoría
+func auditFilterConditions(filter AuditFilter) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.ActorID != nil {
+		add("actor_id = $%d", *filter.ActorID)
+	}
+	if filter.ActorUsername != "" {
+		add("actor_username = $%d", filter.ActorUsername)
+	}
+	if filter.Entity != "" {
+		add("entity = $%d", filter.Entity)
+	}
+	if filter.EntityID != "" {
+		add("entity_id = $%d", filter.EntityID)
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.From != nil {
+		add("occurred_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("occurred_at < $%d", *filter.To)
+	}
+	return conditions, args
+}
+
+func (s *pgAuditStore) List(ctx context.Context, filter AuditFilter, limit, offset int) ([]AuditEntry, int, error) {
+	conditions, args := auditFilterConditions(filter)
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := `SELECT id, occurred_at, actor_id, actor_username, action, entity, COALESCE(entity_id, ''),
+		before, after, diff, COALESCE(request_id, ''), COALESCE(client_ip, '')
+		FROM audit_log` + where + fmt.Sprintf(" ORDER BY occurred_at DESC, id DESC LIMIT %d OFFSET %d", limit, offset)
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		err := rows.Scan(&e.ID, &e.OccurredAt, &e.ActorID, &e.ActorUsername, &e.Action, &e.Entity, &e.EntityID,
+			&e.Before, &e.After, &e.Diff, &e.RequestID, &e.ClientIP)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = s.db.QueryRow(ctx, "SELECT COUNT(*) FROM audit_log"+where, args...).Scan(&total)
+	return entries, total, err
+}
+
+// AnalyticsStore sobre PostgreSQL
+type pgAnalyticsStore struct {
+	db *pgxpool.Pool
+}
+
+func newPgAnalyticsStore(db *pgxpool.Pool) *pgAnalyticsStore {
+	return &pgAnalyticsStore{db: db}
+}
+
+func (s *pgAnalyticsStore) KPIs(ctx context.Context) (KPIs, error) {
+	var k KPIs
+	err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM properties WHERE deleted_at IS NULL").Scan(&k.TotalProperties)
+	if err != nil {
+		return k, fmt.Errorf("total properties: %w", err)
+	}
+
+	// Sin filas válidas los promedios son 0
+	err = s.db.QueryRow(ctx, "SELECT COALESCE(AVG(sale_amount), 0) FROM properties WHERE deleted_at IS NULL AND sale_amount > 0").Scan(&k.AveragePrice)
+	if err != nil {
+		return k, fmt.Errorf("average price: %w", err)
+	}
+	err = s.db.QueryRow(ctx, "SELECT COALESCE(AVG(sales_ratio), 0) FROM properties WHERE deleted_at IS NULL AND sales_ratio > 0").Scan(&k.AverageSalesRatio)
+	if err != nil {
+		return k, fmt.Errorf("average sales ratio: %w", err)
+	}
+	err = s.db.QueryRow(ctx, "SELECT COALESCE(AVG(years_until_sold), 0) FROM properties WHERE deleted_at IS NULL AND years_until_sold >= 0").Scan(&k.AverageYearsUntilSold)
+	if err != nil {
+		return k, fmt.Errorf("average years until sold: %w", err)
+	}
+
+	err = s.db.QueryRow(ctx, `
+		SELECT town, COUNT(*) as count
+		FROM properties
+		WHERE deleted_at IS NULL AND town IS NOT NULL
+		GROUP BY town
+		ORDER BY count DESC
+		LIMIT 1
+	`).Scan(&k.TopCity.Name, &k.TopCity.Count)
+	if err != nil && err != pgx.ErrNoRows {
+		return k, fmt.Errorf("top city: %w", err)
+	}
+
+	err = s.db.QueryRow(ctx, `
+		SELECT property_type, COUNT(*) as count
+		FROM properties
+		WHERE deleted_at IS NULL AND property_type IS NOT NULL
+		GROUP BY property_type
+		ORDER BY count DESC
+		LIMIT 1
+	`).Scan(&k.TopPropertyType.Name, &k.TopPropertyType.Count)
+	if err != nil && err != pgx.ErrNoRows {
+		return k, fmt.Errorf("top property type: %w", err)
+	}
+	return k, nil
+}
+
+func (s *pgAnalyticsStore) TrendsByYear(ctx context.Context) ([]YearTrend, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT
+			list_year,
+			COUNT(*) as total_sales,
+			AVG(sale_amount) as avg_price,
+			AVG(sales_ratio) as avg_sales_ratio,
+			AVG(years_until_sold) as avg_years_until_sold
+		FROM properties
+		WHERE deleted_at IS NULL AND list_year IS NOT NULL
+		GROUP BY list_year
+		ORDER BY list_year
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (YearTrend, error) {
+		var t YearTrend
+		err := row.Scan(&t.Year, &t.TotalSales, &t.AvgPrice, &t.AvgSalesRatio, &t.AvgYearsUntilSold)
+		return t, err
+	})
+}
+
+func (s *pgAnalyticsStore) AveragePriceByTown(ctx context.Context, limit int) ([]TownPrice, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT
+			town,
+			AVG(sale_amount) as average_price,
+			COUNT(*) as count
+		FROM properties
+		WHERE deleted_at IS NULL AND town IS NOT NULL AND sale_amount > 0
+		GROUP BY town
+		ORDER BY average_price DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (TownPrice, error) {
+		var t TownPrice
+		err := row.Scan(&t.Town, &t.AveragePrice, &t.Count)
+		return t, err
+	})
+}
+
+func (s *pgAnalyticsStore) PropertyTypeAnalysis(ctx context.Context) ([]PropertyTypeStats, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT
+			property_type,
+			COUNT(*) as count,
+			AVG(sale_amount) as average_price,
+			AVG(sales_ratio) as avg_sales_ratio
+		FROM properties
+		WHERE deleted_at IS NULL AND property_type IS NOT NULL
+		GROUP BY property_type
+		ORDER BY count DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (PropertyTypeStats, error) {
+		var t PropertyTypeStats
+		err := row.Scan(&t.PropertyType, &t.Count, &t.AveragePrice, &t.AvgSalesRatio)
+		return t, err
+	})
+}
+
+// Leer filas (range, count, percentage) de una distribución
+func collectDistribution(rows pgx.Rows) ([]DistributionBucket, error) {
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (DistributionBucket, error) {
+		var b DistributionBucket
+		err := row.Scan(&b.Range, &b.Count, &b.Percentage)
+		return b, err
+	})
+}
+
+func (s *pgAnalyticsStore) SalesRatioDistribution(ctx context.Context) ([]DistributionBucket, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT
+			CASE
+				WHEN sales_ratio < 0.8 THEN '< 80%'
+				WHEN sales_ratio < 0.9 THEN '80-90%'
+				WHEN sales_ratio < 1.0 THEN '90-100%'
+				WHEN sales_ratio < 1.1 THEN '100-110%'
+				WHEN sales_ratio < 1.2 THEN '110-120%'
+				ELSE '> 120%'
+			END as range,
+			COUNT(*) as count,
+			ROUND(COUNT(*) * 100.0 / (SELECT COUNT(*) FROM properties WHERE deleted_at IS NULL AND sales_ratio > 0), 2) as percentage
+		FROM properties
+		WHERE deleted_at IS NULL AND sales_ratio > 0
+		GROUP BY range
+		ORDER BY MIN(sales_ratio)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return collectDistribution(rows)
+}
+
+func (s *pgAnalyticsStore) TimeToSellDistribution(ctx context.Context) ([]DistributionBucket, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT
+			CASE
+				WHEN years_until_sold = 0 THEN '0 años'
+				WHEN years_until_sold = 1 THEN '1 año'
+				WHEN years_until_sold = 2 THEN '2 años'
+				WHEN years_until_sold = 3 THEN '3 años'
+				WHEN years_until_sold = 4 THEN '4 años'
+				ELSE '5+ años'
+			END as range,
+			COUNT(*) as count,
+			ROUND(COUNT(*) * 100.0 / (SELECT COUNT(*) FROM properties WHERE deleted_at IS NULL AND years_until_sold >= 0), 2) as percentage
+		FROM properties
+		WHERE deleted_at IS NULL AND years_until_sold >= 0
+		GROUP BY range
+		ORDER BY MIN(years_until_sold)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return collectDistribution(rows)
+}
+
+func (s *pgAnalyticsStore) TopCitiesByVolume(ctx context.Context, limit int) ([]TownVolume, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT
+			town,
+			COUNT(*) as count,
+			AVG(sale_amount) as average_price
+		FROM properties
+		WHERE deleted_at IS NULL AND town IS NOT NULL
+		GROUP BY town
+		ORDER BY count DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (TownVolume, error) {
+		var t TownVolume
+		err := row.Scan(&t.Town, &t.Count, &t.AveragePrice)
+		return t, err
+	})
+}
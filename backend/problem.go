@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Tipo de contenido de los errores (RFC 7807)
+const problemContentType = "application/problem+json"
+
+// Códigos de error estables; los clientes deben usar estos y no el texto de detail
+const (
+	codeValidationFailed     = "VALIDATION_FAILED"
+	codeInvalidRequestBody   = "INVALID_REQUEST_BODY"
+	codeInvalidParameter     = "INVALID_PARAMETER"
+	codeInvalidSort          = "INVALID_SORT"
+	codeInvalidCursor        = "INVALID_CURSOR"
+	codeInvalidUpload        = "INVALID_UPLOAD"
+	codeInvalidPatch         = "INVALID_PATCH"
+	codePatchTestFailed      = "PATCH_TEST_FAILED"
+	codeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	codeUploadTooLarge       = "UPLOAD_TOO_LARGE"
+	codeAuthRequired         = "AUTHENTICATION_REQUIRED"
+	codeInvalidCredentials   = "INVALID_CREDENTIALS"
+	codeInvalidToken         = "INVALID_TOKEN"
+	codeSessionRevoked       = "SESSION_REVOKED"
+	codeInvalidRefreshToken  = "INVALID_REFRESH_TOKEN"
+	codeRefreshTokenReused   = "REFRESH_TOKEN_REUSED"
+	codePermissionDenied     = "PERMISSION_DENIED"
+	codePropertyNotFound     = "PROPERTY_NOT_FOUND"
+	codePropertyExists       = "PROPERTY_ALREADY_EXISTS"
+	codeUserNotFound         = "USER_NOT_FOUND"
+	codeUserExists           = "USER_ALREADY_EXISTS"
+	codeEmailExists          = "EMAIL_ALREADY_EXISTS"
+	codeRoleNotFound         = "ROLE_NOT_FOUND"
+	codeRoleExists           = "ROLE_ALREADY_EXISTS"
+	codeRoleProtected        = "ROLE_PROTECTED"
+	codePreconditionFailed   = "PRECONDITION_FAILED"
+	codePreconditionRequired = "PRECONDITION_REQUIRED"
+	codeBulkRolledBack       = "BULK_ROLLED_BACK"
+	codeRoleInUse            = "ROLE_IN_USE"
+	codeFeatureDisabled      = "FEATURE_DISABLED"
+	codeRouteNotFound        = "ROUTE_NOT_FOUND"
+	codeRequestTimeout       = "REQUEST_TIMEOUT"
+	codeInternalError        = "INTERNAL_ERROR"
+)
+
+// Error de la API; problemMiddleware lo serializa como problem+json
+type APIError struct {
+	Status int
+	Code   string
+	Detail string
+	Fields []FieldError
+	Extra  gin.H // miembros de extensión adicionales
+}
+
+func (e *APIError) Error() string {
+	return e.Detail
+}
+
+// Detalle de validación de un campo del cuerpo
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Cuerpo problem+json; Extra se añade como miembros de primer nivel
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail"`
+	Instance  string       `json:"instance"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// Crear un error de la API
+func newAPIError(status int, code, detail string) *APIError {
+	return &APIError{Status: status, Code: code, Detail: detail}
+}
+
+// Error de validación con el detalle de cada campo
+func validationFailed(fields ...FieldError) *APIError {
+	return &APIError{
+		Status: http.StatusUnprocessableEntity,
+		Code:   codeValidationFailed,
+		Detail: "Request validation failed",
+		Fields: fields,
+	}
+}
+
+// Registrar el error y detener la cadena de handlers
+func abortWithError(c *gin.Context, err *APIError) {
+	c.Error(err)
+	c.Abort()
+}
+
+// Atajo para errores sin detalle adicional
+func abortProblem(c *gin.Context, status int, code, detail string) {
+	abortWithError(c, newAPIError(status, code, detail))
+}
+
+// Middleware que escribe como problem+json el último APIError si el handler no respondió
+func problemMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if c.Writer.Written() {
+			return
+		}
+		for i := len(c.Errors) - 1; i >= 0; i-- {
+			var apiErr *APIError
+			if errors.As(c.Errors[i].Err, &apiErr) {
+				writeProblem(c, apiErr)
+				return
+			}
+		}
+	}
+}
+
+// Serializar un error como problem+json
+func writeProblem(c *gin.Context, err *APIError) {
+	body := gin.H{
+		"type":       "about:blank",
+		"title":      http.StatusText(err.Status),
+		"status":     err.Status,
+		"detail":     err.Detail,
+		"instance":   c.Request.URL.Path,
+		"code":       err.Code,
+		"request_id": c.GetString("request_id"),
+	}
+	if len(err.Fields) > 0 {
+		body["errors"] = err.Fields
+	}
+	for key, value := range err.Extra {
+		body[key] = value
+	}
+
+	c.Header("Content-Type", problemContentType)
+	c.JSON(err.Status, body)
+}
+
+// Respuesta para rutas inexistentes
+func routeNotFound(c *gin.Context) {
+	abortProblem(c, http.StatusNotFound, codeRouteNotFound, "Route not found")
+}
+
+// Respuesta tras un panic recuperado
+func recoverProblem(c *gin.Context, _ interface{}) {
+	writeProblem(c, newAPIError(http.StatusInternalServerError, codeInternalError, "Internal server error"))
+	c.Abort()
+}
+
+// Los errores de validación usan el nombre JSON del campo, no el de Go
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// Decodificar el cuerpo JSON; si falla aborta con el detalle por campo
+func bindJSON(c *gin.Context, target interface{}) bool {
+	if err := c.ShouldBindJSON(target); err != nil {
+		abortWithError(c, bindingProblem(err))
+		return false
+	}
+	return true
+}
+
+// Traducir un error de binding de gin a un APIError
+func bindingProblem(err error) *APIError {
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make([]FieldError, len(validationErrs))
+		for i, fe := range validationErrs {
+			// El namespace empieza por el tipo raíz: RegisterRequest.email
+			_, field, _ := strings.Cut(fe.Namespace(), ".")
+			fields[i] = FieldError{Field: field, Rule: fe.Tag(), Message: validationMessage(fe)}
+		}
+		return validationFailed(fields...)
+	case errors.As(err, &typeErr):
+		return validationFailed(FieldError{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: "must be " + jsonTypeName(typeErr.Type),
+		})
+	case errors.Is(err, io.EOF):
+		return newAPIError(http.StatusBadRequest, codeInvalidRequestBody, "Request body is required")
+	default:
+		return newAPIError(http.StatusBadRequest, codeInvalidRequestBody, "Invalid request body")
+	}
+}
+
+// Mensaje legible de una regla de validación incumplida
+func validationMessage(fe validator.FieldError) string {
+	unit := ""
+	switch fe.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		return fmt.Sprintf("must be at least %s%s", fe.Param(), unit)
+	case "max":
+		return fmt.Sprintf("must be at most %s%s", fe.Param(), unit)
+	case "gte":
+		return "must be greater than or equal to " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "lte":
+		return "must be less than or equal to " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	default:
+		return fmt.Sprintf("failed the '%s' rule", fe.Tag())
+	}
+}
+
+// Nombre JSON del tipo esperado por un campo
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
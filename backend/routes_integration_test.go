@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Recorre todas las rutas de setupRoutes en un orden fijo sobre los fixtures y
+// compara cada respuesta con su golden. Las mutaciones se hacen al final de cada
+// bloque para que las lecturas vean siempre los mismos datos.
+func TestIntegrationRoutes(t *testing.T) {
+	requireIntegration(t)
+
+	app := newIntegrationApp(t)
+	router := app.setupRoutes()
+	covered := make(map[string]bool)
+
+	call := func(name string, req apiRequest) *httptest.ResponseRecorder {
+		t.Helper()
+		w := doRequest(t, router, name, req)
+		assertGolden(t, name, w)
+		for _, route := range router.Routes() {
+			if route.Method == req.Method && matchRoute(route.Path, req.Path) {
+				covered[route.Method+" "+route.Path] = true
+			}
+		}
+		return w
+	}
+	get := func(name, path, token string) *httptest.ResponseRecorder {
+		t.Helper()
+		return call(name, apiRequest{Method: http.MethodGet, Path: path, Token: token})
+	}
+	login := func(name, username string) string {
+		t.Helper()
+		w := call(name, apiRequest{Method: http.MethodPost, Path: "/api/v1/auth/login",
+			Body: map[string]string{"username": username, "password": "fixture-password"}})
+		if w.Code != http.StatusOK {
+			t.Fatalf("login as %s failed: %d %s", username, w.Code, w.Body.String())
+		}
+		return responseField(t, w, "token").(string)
+	}
+
+	// Salud y claves
+	get("health", "/health", "")
+	get("livez", "/livez", "")
+	get("readyz", "/readyz", "")
+	get("jwks", "/.well-known/jwks.json", "")
+	get("openapi", "/api/v1/openapi.json", "")
+	get("docs", "/api/v1/docs", "")
+
+	// Autenticación
+	call("login_invalid", apiRequest{Method: http.MethodPost, Path: "/api/v1/auth/login",
+		Body: map[string]string{"username": "admin", "password": "wrong-password"}})
+	admin := login("login_admin", "admin")
+	analyst := login("login_analyst", "analyst")
+	viewer := login("login_viewer", "viewer")
+
+	registered := call("register", apiRequest{Method: http.MethodPost, Path: "/api/v1/auth/register",
+		Body: map[string]string{"username": "newcomer", "email": "newcomer@urbanytics.test", "password": "newcomer-password"}})
+	call("register_duplicate", apiRequest{Method: http.MethodPost, Path: "/api/v1/auth/register",
+		Body: map[string]string{"username": "newcomer", "email": "newcomer@urbanytics.test", "password": "newcomer-password"}})
+	call("refresh", apiRequest{Method: http.MethodPost, Path: "/api/v1/auth/refresh",
+		Body: map[string]string{"refresh_token": responseField(t, registered, "refresh_token").(string)}})
+	call("refresh_reused", apiRequest{Method: http.MethodPost, Path: "/api/v1/auth/refresh",
+		Body: map[string]string{"refresh_token": responseField(t, registered, "refresh_token").(string)}})
+
+	// Propiedades
+	get("properties", "/api/v1/properties", "")
+	get("properties_page_2", "/api/v1/properties?page=2&limit=4", "")
+	get("properties_filtered", "/api/v1/properties?town=hart&status=sold&min_price=150000", "")
+	get("properties_sorted", "/api/v1/properties?sort=-sale_amount,town", "")
+	call("property_not_modified", apiRequest{Method: http.MethodGet, Path: "/api/v1/properties/1001",
+		Headers: map[string]string{"If-None-Match": `"1"`}})
+	get("properties_search", "/api/v1/properties?q=mapl", "")
+	get("properties_search_fuzzy", "/api/v1/properties?q=chesire", "")
+	get("properties_search_cursor", "/api/v1/properties?q=elm&cursor=", "")
+	get("properties_suggest", "/api/v1/properties/suggest?q=ha&limit=5", "")
+	get("properties_suggest_missing_q", "/api/v1/properties/suggest", "")
+	get("property_lookup", "/api/v1/properties/lookup?address=12+Main+Street&town=hartford", "")
+	get("property_lookup_unit", "/api/v1/properties/lookup?address=19+Hill+St+%234", "")
+	get("property_lookup_missing_address", "/api/v1/properties/lookup", "")
+	get("properties_invalid_filter", "/api/v1/properties?min_price=cheap", "")
+	get("properties_invalid_sort", "/api/v1/properties?sort=owner", "")
+	first := get("properties_cursor", "/api/v1/properties?cursor=&limit=3&sort=-sales_ratio", "")
+	get("properties_cursor_next", fmt.Sprintf("/api/v1/properties?cursor=%s&limit=3&include_total=true",
+		responseField(t, first, "pagination", "next_cursor")), "")
+	get("property", "/api/v1/properties/1003", "")
+	get("property_not_found", "/api/v1/properties/9999", "")
+	get("property_history", "/api/v1/properties/1003/history", "")
+	get("filters_cities", "/api/v1/properties/filters/cities", "")
+	get("filters_property_types", "/api/v1/properties/filters/property-types", "")
+	get("filters_residential_types", "/api/v1/properties/filters/residential-types", "")
+	get("filters_list_years", "/api/v1/properties/filters/list-years", "")
+
+	// Analítica
+	get("analytics_kpis", "/api/v1/analytics/kpis", "")
+	get("analytics_trends_by_year", "/api/v1/analytics/trends-by-year", "")
+	get("analytics_avg_price_by_town", "/api/v1/analytics/avg-price-by-town", "")
+	get("analytics_property_type_analysis", "/api/v1/analytics/property-type-analysis", "")
+	get("analytics_sales_ratio_distribution", "/api/v1/analytics/sales-ratio-distribution", "")
+	get("analytics_time_to_sell_distribution", "/api/v1/analytics/time-to-sell-distribution", "")
+	get("analytics_top_cities_by_volume", "/api/v1/analytics/top-cities-by-volume", "")
+
+	// Perfil y exportación
+	get("profile_unauthenticated", "/api/v1/profile", "")
+	get("profile", "/api/v1/profile", viewer)
+	call("profile_update", apiRequest{Method: http.MethodPut, Path: "/api/v1/profile", Token: viewer,
+		Body: map[string]string{"email": "viewer+updated@urbanytics.test"}})
+	get("export_forbidden", "/api/v1/properties/export", viewer)
+	get("export_csv", "/api/v1/properties/export?format=csv&town=Bristol", analyst)
+
+	// Administración de propiedades
+	get("admin_forbidden", "/api/v1/admin/users", viewer)
+	call("property_create", apiRequest{Method: http.MethodPost, Path: "/api/v1/admin/properties", Token: admin,
+		Body: Property{SerialNumber: 2001, ListYear: 2024, DateRecorded: "2024-04-04", Town: "Ashford", Address: "1 NEW RD",
+			AssessedValue: 100000, SaleAmount: 125000, SalesRatio: 0.8, PropertyType: "Residential", ResidentialType: "Condo"}})
+	call("property_create_invalid", apiRequest{Method: http.MethodPost, Path: "/api/v1/admin/properties", Token: admin,
+		Body: Property{SerialNumber: 2002, ListYear: 2999, DateRecorded: "04/31/2024", Town: "Nowhere",
+			AssessedValue: 100000, SaleAmount: -5, SalesRatio: 0.5, PropertyType: "Castle"}})
+	call("property_update", apiRequest{Method: http.MethodPut, Path: "/api/v1/admin/properties/2001", Token: admin,
+		Body: Property{ListYear: 2024, DateRecorded: "2024-04-04", Town: "Ashford", Address: "1 NEW RD",
+			AssessedValue: 100000, SaleAmount: 150000, SalesRatio: 0.6667, PropertyType: "Residential", ResidentialType: "Condo"}})
+	call("property_update_stale", apiRequest{Method: http.MethodPut, Path: "/api/v1/admin/properties/2001", Token: admin,
+		Headers: map[string]string{"If-Match": `"1"`},
+		Body:    Property{ListYear: 2024, DateRecorded: "2024-04-04", Town: "Ashford", AssessedValue: 100000, SaleAmount: 125000}})
+	call("property_patch", apiRequest{Method: http.MethodPatch, Path: "/api/v1/admin/properties/2001", Token: admin,
+		ContentType: mergePatchContentType, Body: `{"sale_amount": 200000, "sales_ratio": null}`})
+	call("property_json_patch", apiRequest{Method: http.MethodPatch, Path: "/api/v1/admin/properties/2001", Token: admin,
+		ContentType: jsonPatchContentType, Body: `[{"op": "test", "path": "/town", "value": "Ashford"}, {"op": "replace", "path": "/address", "value": "2 NEW RD"}]`})
+	call("property_patch_readonly", apiRequest{Method: http.MethodPatch, Path: "/api/v1/admin/properties/2001", Token: admin,
+		ContentType: mergePatchContentType, Body: `{"serial_number": 9999}`})
+	call("property_delete", apiRequest{Method: http.MethodDelete, Path: "/api/v1/admin/properties/2001", Token: admin})
+	get("property_trash", "/api/v1/admin/properties/trash", admin)
+	call("property_restore", apiRequest{Method: http.MethodPost, Path: "/api/v1/admin/properties/2001/restore", Token: admin})
+	get("property_history_after_changes", "/api/v1/properties/2001/history", "")
+	call("property_import", apiRequest{Method: http.MethodPost, Path: "/api/v1/admin/properties/import?mode=insert", Token: admin,
+		ContentType: "text/csv",
+		Body: "serial_number,list_year,date_recorded,town,address,assessed_value,sale_amount,property_type,residential_type\n" +
+			"3001,2022,2023-02-02,Bristol,9 MILL ST,50000,100000,Residential,Condo\n" +
+			"3002,2022,2023-02-02,Nowhere,1 LOST RD,50000,100000,Residential,Condo\n"})
+	call("property_bulk_rolled_back", apiRequest{Method: http.MethodPost, Path: "/api/v1/admin/properties/bulk", Token: admin,
+		Body: `{"operations": [
+			{"op": "update", "filter": {"towns": ["cheshire"]}, "data": {"residential_type": "Two Family"}},
+			{"op": "delete", "id": 9999}]}`})
+	call("property_bulk", apiRequest{Method: http.MethodPost, Path: "/api/v1/admin/properties/bulk", Token: admin,
+		Body: `{"mode": "best_effort", "operations": [
+			{"op": "update", "filter": {"towns": ["Cheshire"], "residential_type": "Single Family"}, "data": {"residential_type": "Condo"}},
+			{"op": "create", "data": {"serial_number": 3003, "list_year": 2022, "date_recorded": "2023-03-03", "town": "Bristol", "assessed_value": 50000, "sale_amount": 100000}},
+			{"op": "delete", "id": 3001, "version": 1},
+			{"op": "update", "id": 1005, "version": 7, "data": {"address": "6 RIVER RD"}}]}`})
+	call("property_import_same_address", apiRequest{Method: http.MethodPost, Path: "/api/v1/admin/properties/import?mode=insert", Token: admin,
+		ContentType: "text/csv",
+		Body: "serial_number,list_year,date_recorded,town,address,assessed_value,sale_amount,property_type,residential_type\n" +
+			"3004,2023,2024-01-05,Hartford,12 Main Street,160000,210000,Residential,Single Family\n"})
+	get("property_duplicates", "/api/v1/admin/properties/duplicates?town=hartford", admin)
+	get("property_lookup_patched", "/api/v1/properties/lookup?address=2+New+Road&town=Ashford", "")
+
+	// Administración de usuarios
+	get("users", "/api/v1/admin/users", admin)
+	created := call("user_create", apiRequest{Method: http.MethodPost, Path: "/api/v1/admin/users", Token: admin,
+		Body: map[string]string{"username": "temp", "email": "temp@urbanytics.test", "password": "temp-password"}})
+	userPath := fmt.Sprintf("/api/v1/admin/users/%v", responseField(t, created, "data", "id"))
+	call("user_update", apiRequest{Method: http.MethodPut, Path: userPath, Token: admin,
+		Body: map[string]string{"email": "temp@urbanytics.test", "role": "analyst"}})
+	call("user_update_invalid_role", apiRequest{Method: http.MethodPut, Path: userPath, Token: admin,
+		Body: map[string]string{"email": "temp@urbanytics.test", "role": "superuser"}})
+	call("user_patch", apiRequest{Method: http.MethodPatch, Path: userPath, Token: admin,
+		ContentType: mergePatchContentType, Body: `{"role": "data_steward"}`})
+	call("user_sessions_revoke", apiRequest{Method: http.MethodDelete, Path: userPath + "/sessions", Token: admin})
+	call("user_delete", apiRequest{Method: http.MethodDelete, Path: userPath, Token: admin})
+	get("user_trash", "/api/v1/admin/users/trash", admin)
+	call("user_restore", apiRequest{Method: http.MethodPost, Path: userPath + "/restore", Token: admin})
+
+	// Roles y permisos
+	get("permissions", "/api/v1/admin/permissions", admin)
+	get("roles", "/api/v1/admin/roles", admin)
+	call("role_create", apiRequest{Method: http.MethodPost, Path: "/api/v1/admin/roles", Token: admin,
+		Body: RoleRequest{Name: "auditor", Description: "Puede leer la auditoría", Permissions: []string{permAuditRead}}})
+	call("role_update", apiRequest{Method: http.MethodPut, Path: "/api/v1/admin/roles/auditor", Token: admin,
+		Body: RoleRequest{Description: "Auditoría y configuración", Permissions: []string{permAuditRead, permConfigRead}}})
+	call("role_delete", apiRequest{Method: http.MethodDelete, Path: "/api/v1/admin/roles/auditor", Token: admin})
+
+	// Auditoría y configuración
+	get("audit", "/api/v1/admin/audit?entity=property", admin)
+	get("config", "/api/v1/admin/config", admin)
+
+	// Cierre de sesión al final para no invalidar los tokens anteriores
+	call("logout_all", apiRequest{Method: http.MethodPost, Path: "/api/v1/auth/logout-all", Token: analyst})
+	call("logout", apiRequest{Method: http.MethodPost, Path: "/api/v1/auth/logout", Token: viewer})
+	get("profile_after_logout", "/api/v1/profile", viewer)
+
+	// Todas las rutas registradas deben tener al menos un caso
+	var missing []string
+	for _, route := range router.Routes() {
+		if key := route.Method + " " + route.Path; !covered[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		t.Errorf("routes without integration coverage:\n  %s", strings.Join(missing, "\n  "))
+	}
+}
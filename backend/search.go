@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Límites de la búsqueda por texto y del autocompletado
+const (
+	maxSearchLength     = 200
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 25
+)
+
+// Umbrales por defecto de pg_trgm: word_similarity_threshold (operador <%, para
+// las direcciones) y similarity_threshold (operador %, para las ciudades, que son
+// cortas y se comparan enteras)
+const (
+	wordSimilarityThreshold = 0.6
+	similarityThreshold     = 0.3
+)
+
+// Vector de texto de una propiedad; la dirección (A) pesa más que la ciudad (B).
+// Debe coincidir con el índice idx_properties_search (migración 0010).
+const propertySearchVector = "(setweight(to_tsvector('simple', coalesce(nullif(address, 'Nan'), '')), 'A') || setweight(to_tsvector('simple', coalesce(nullif(town, 'Nan'), '')), 'B'))"
+
+// Palabras de una búsqueda en minúsculas, sin signos de puntuación
+func searchTerms(q string) []string {
+	return strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// tsquery que exige cada palabra como inicio de un término ("map:* & av:*");
+// weights limita las coincidencias a esas clases ("A" = dirección)
+func prefixTSQuery(terms []string, weights string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = term + ":*" + weights
+	}
+	return strings.Join(parts, " & ")
+}
+
+// Patrón LIKE para los textos que empiezan por value
+func likePrefix(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value) + "%"
+}
+
+// Indica si cada palabra de la búsqueda es el inicio de alguna palabra de text
+func prefixMatch(terms []string, text string) bool {
+	words := searchTerms(text)
+	for _, term := range terms {
+		found := false
+		for _, word := range words {
+			if strings.HasPrefix(word, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Trigramas de un texto en orden, como los genera pg_trgm: cada palabra con dos
+// espacios delante y uno detrás
+func trigramSequence(text string) []string {
+	var sequence []string
+	for _, word := range searchTerms(text) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			sequence = append(sequence, string(padded[i:i+3]))
+		}
+	}
+	return sequence
+}
+
+// Conjunto de trigramas de un texto
+func trigrams(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, t := range trigramSequence(text) {
+		set[t] = true
+	}
+	return set
+}
+
+// Trigramas comunes sobre el total, como similarity() de pg_trgm
+func trigramSimilarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for t := range a {
+		if b[t] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// word_similarity(q, text) de pg_trgm: la mayor similitud entre los trigramas de q
+// y un tramo continuo de la secuencia de trigramas de text, que puede empezar o
+// acabar a mitad de palabra
+func wordSimilarity(q, text string) float64 {
+	target := trigrams(q)
+	sequence := trigramSequence(text)
+	best := 0.0
+	for i := range sequence {
+		extent := make(map[string]bool)
+		for _, t := range sequence[i:] {
+			extent[t] = true
+			best = math.Max(best, trigramSimilarity(target, extent))
+		}
+	}
+	return best
+}
+
+// similarity(q, town) de pg_trgm
+func townSimilarity(q, town string) float64 {
+	return trigramSimilarity(trigrams(q), trigrams(town))
+}
+
+// Dirección y ciudad de una propiedad sin los valores 'Nan'
+func searchText(p *Property) string {
+	var parts []string
+	for _, value := range []string{p.Address, p.Town} {
+		if value != "" && value != missingValue {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Texto con las palabras que coinciden con la búsqueda entre <mark>; el resto se
+// escapa para poder insertarlo como HTML
+func highlightMatch(terms []string, text string) string {
+	termTrigrams := make([]map[string]bool, len(terms))
+	for i, term := range terms {
+		termTrigrams[i] = trigrams(term)
+	}
+	matches := func(word string) bool {
+		lower := strings.ToLower(word)
+		for i, term := range terms {
+			if strings.HasPrefix(lower, term) || trigramSimilarity(termTrigrams[i], trigrams(lower)) >= similarityThreshold {
+				return true
+			}
+		}
+		return false
+	}
+
+	var b strings.Builder
+	runes := []rune(text)
+	for start := 0; start < len(runes); {
+		end := start
+		isWord := unicode.IsLetter(runes[start]) || unicode.IsDigit(runes[start])
+		for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end])) == isWord {
+			end++
+		}
+		segment := string(runes[start:end])
+		if isWord && matches(segment) {
+			b.WriteString("<mark>" + html.EscapeString(segment) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(segment))
+		}
+		start = end
+	}
+	return b.String()
+}
+
+// Leer q de la query string; vacío si no se busca
+func searchFromQuery(value string) (string, error) {
+	q := strings.TrimSpace(value)
+	if q == "" {
+		return "", nil
+	}
+	if len(q) > maxSearchLength || len(searchTerms(q)) == 0 {
+		return "", fmt.Errorf("Invalid 'q' filter. Must contain letters or digits (max %d characters)", maxSearchLength)
+	}
+	return q, nil
+}
+
+// Coincidencias de la búsqueda con el fragmento resaltado
+func (app *App) searchProperties(ctx context.Context, q PropertyQuery) ([]PropertyMatch, error) {
+	matches, err := app.properties.Search(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	terms := searchTerms(q.Filter.Search)
+	for i := range matches {
+		matches[i].Highlight = highlightMatch(terms, searchText(&matches[i].Property))
+	}
+	return matches, nil
+}
+
+// Autocompletar direcciones y ciudades a partir de lo que se lleva escrito
+func (app *App) getPropertySuggestions(c *gin.Context) {
+	q, err := searchFromQuery(c.Query("q"))
+	if err != nil || q == "" {
+		abortProblem(c, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("Parameter 'q' is required and must contain letters or digits (max %d characters)", maxSearchLength))
+		return
+	}
+
+	limit := defaultSuggestLimit
+	if value := c.Query("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > maxSuggestLimit {
+			abortProblem(c, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("Invalid 'limit'. Must be between 1 and %d", maxSuggestLimit))
+			return
+		}
+	}
+
+	suggestions, err := app.properties.Suggest(c.Request.Context(), q, limit)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to query suggestions")
+		return
+	}
+	terms := searchTerms(q)
+	for i := range suggestions {
+		suggestions[i].Highlight = highlightMatch(terms, suggestions[i].Value)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    suggestions,
+	})
+}
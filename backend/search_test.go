@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSearchFromQuery(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		fails bool
+	}{
+		{"", "", false},
+		{"   ", "", false},
+		{"  maple st ", "maple st", false},
+		{"O'Brien", "O'Brien", false},
+		{"!?", "", true},
+		{strings.Repeat("a", maxSearchLength), strings.Repeat("a", maxSearchLength), false},
+		{strings.Repeat("a", maxSearchLength+1), "", true},
+	}
+	for _, tt := range tests {
+		got, err := searchFromQuery(tt.value)
+		if got != tt.want || (err != nil) != tt.fails {
+			t.Errorf("searchFromQuery(%q) = %q, %v", tt.value, got, err)
+		}
+	}
+}
+
+func TestPrefixTSQuery(t *testing.T) {
+	tests := []struct {
+		terms   []string
+		weights string
+		want    string
+	}{
+		{searchTerms("Maple Av."), "", "maple:* & av:*"},
+		{searchTerms("12 maple"), "A", "12:*A & maple:*A"},
+		{[]string{"hartford"}, "AB", "hartford:*AB"},
+		{nil, "", ""},
+	}
+	for _, tt := range tests {
+		if got := prefixTSQuery(tt.terms, tt.weights); got != tt.want {
+			t.Errorf("prefixTSQuery(%q, %q) = %q, want %q", tt.terms, tt.weights, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightMatch(t *testing.T) {
+	tests := []struct {
+		q    string
+		text string
+		want string
+	}{
+		{"map", "12 MAPLE ST, Hartford", "12 <mark>MAPLE</mark> ST, Hartford"},
+		{"maple hart", "12 MAPLE ST, Hartford", "12 <mark>MAPLE</mark> ST, <mark>Hartford</mark>"},
+		{"hartfrod", "40 ELM AVE, Hartford", "40 ELM AVE, <mark>Hartford</mark>"},
+		{"o", "O'BRIEN & SONS <LLC>", "<mark>O</mark>&#39;BRIEN &amp; SONS &lt;LLC&gt;"},
+		{"", "1 A&B ST", "1 A&amp;B ST"},
+	}
+	for _, tt := range tests {
+		if got := highlightMatch(searchTerms(tt.q), tt.text); got != tt.want {
+			t.Errorf("highlightMatch(%q, %q) = %q, want %q", tt.q, tt.text, got, tt.want)
+		}
+	}
+}
+
+// Valores de referencia obtenidos con pg_trgm en PostgreSQL 16
+func TestTrigramSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"similarity typo", townSimilarity("hartfrod", "Hartford"), 0.3846154},
+		{"similarity case", townSimilarity("new haven", "New Haven"), 1},
+		{"similarity unrelated", townSimilarity("x", "Hartford"), 0},
+		{"word_similarity words", wordSimilarity("high ridge", "3 HIGH RIDGE RD"), 1},
+		{"word_similarity typo", wordSimilarity("mapel st", "12 MAPLE ST"), 0.5},
+		{"word_similarity prefix", wordSimilarity("chapl", "7 CHAPEL ST"), 0.6666667},
+		{"word_similarity inside word", wordSimilarity("ton rd", "88 MAPLETON RD"), 0.7142857},
+		{"word_similarity longer query", wordSimilarity("elm avenue", "40 ELM AVE"), 0.6363636},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > 1e-6 {
+			t.Errorf("%s = %.7f, want %.7f", tt.name, tt.got, tt.want)
+		}
+	}
+}
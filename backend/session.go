@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	errInvalidToken      = errors.New("Invalid token")
+	errSessionRevoked    = errors.New("Session expired or revoked")
+	errInvalidRefresh    = errors.New("Invalid refresh token")
+	errRefreshTokenReuse = errors.New("Refresh token reuse detected, session revoked")
+)
+
+// Par de tokens emitido al iniciar o renovar una sesión
+type tokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Sin refresh token se revoca la sesión del token de acceso
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Generar refresh token aleatorio y su hash para almacenar
+func generateRefreshToken() (string, string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(bytes)
+	return token, hashToken(token), nil
+}
+
+// Hash SHA-256 de un token; solo el hash se guarda en la base de datos
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Validar firma y expiración de un token de acceso
+func (app *App) parseAccessToken(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, app.verificationKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}))
+	if err != nil || !token.Valid {
+		return nil, errInvalidToken
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || claims.SessionID == 0 {
+		return nil, errInvalidToken
+	}
+	return claims, nil
+}
+
+// Comprobar que la sesión del token esté activa y devolver el rol y permisos actuales
+func (app *App) activeSession(ctx context.Context, claims *Claims) (string, []string, error) {
+	role, permissions, err := app.sessions.Active(ctx, claims.SessionID, claims.UserID)
+	if errors.Is(err, errNotFound) {
+		return "", nil, errSessionRevoked
+	}
+	return role, permissions, err
+}
+
+// Crear sesión persistente y emitir tokens de acceso y refresh
+func (app *App) startSession(c *gin.Context, userID int, username, role string) (*tokenPair, error) {
+	refreshToken, refreshHash, err := generateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, err := app.sessions.Create(c.Request.Context(), NewSession{
+		UserID:      userID,
+		RefreshHash: refreshHash,
+		UserAgent:   c.Request.UserAgent(),
+		ClientIP:    c.ClientIP(),
+		TTL:         app.config.Auth.RefreshTokenTTL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := app.generateJWT(userID, username, role, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(app.config.Auth.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// Rotar el refresh token de una sesión y emitir un nuevo token de acceso
+func (app *App) rotateSession(ctx context.Context, refreshToken string) (*tokenPair, error) {
+	newToken, newHash, err := generateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := app.sessions.Rotate(ctx, hashToken(refreshToken), newHash)
+	switch {
+	case errors.Is(err, errNotFound):
+		return nil, errInvalidRefresh
+	case errors.Is(err, errRefreshTokenReuse):
+		log.Printf("Refresh token reuse on session %d (user %d)", owner.SessionID, owner.UserID)
+		return nil, errRefreshTokenReuse
+	case err != nil:
+		return nil, err
+	}
+
+	accessToken, err := app.generateJWT(owner.UserID, owner.Username, owner.Role, owner.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: newToken,
+		ExpiresIn:    int(app.config.Auth.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// Renovar tokens con un refresh token
+func (app *App) refreshSession(c *gin.Context) {
+	var req RefreshRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tokens, err := app.rotateSession(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		switch err {
+		case errInvalidRefresh:
+			abortProblem(c, http.StatusUnauthorized, codeInvalidRefreshToken, err.Error())
+		case errRefreshTokenReuse:
+			abortProblem(c, http.StatusUnauthorized, codeRefreshTokenReused, err.Error())
+		default:
+			log.Printf("Database error: %v", err)
+			internalError(c, "Internal server error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+// Cerrar sesión: revoca la sesión del refresh token o del token de acceso
+func (app *App) logout(c *gin.Context) {
+	var req LogoutRequest
+	if c.Request.ContentLength != 0 {
+		if !bindJSON(c, &req) {
+			return
+		}
+	}
+
+	var revoked int64
+	var err error
+	if req.RefreshToken != "" {
+		revoked, err = app.sessions.RevokeToken(c.Request.Context(), hashToken(req.RefreshToken))
+	} else {
+		tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		claims, parseErr := app.parseAccessToken(tokenString)
+		if parseErr != nil {
+			abortProblem(c, http.StatusUnauthorized, codeAuthRequired, "Refresh token or valid bearer token required")
+			return
+		}
+		revoked, err = app.sessions.Revoke(c.Request.Context(), claims.SessionID)
+	}
+
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to revoke session")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Logged out successfully",
+		"revoked": revoked,
+	})
+}
+
+// Cerrar sesión en todos los dispositivos del usuario autenticado
+func (app *App) logoutAll(c *gin.Context) {
+	userID := c.GetInt("user_id")
+
+	revoked, err := app.sessions.RevokeUser(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to revoke sessions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Logged out from all devices",
+		"revoked": revoked,
+	})
+}
+
+// Revocar todas las sesiones de un usuario (admin)
+func (app *App) revokeUserSessions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortProblem(c, http.StatusBadRequest, codeInvalidParameter, "Invalid user ID")
+		return
+	}
+
+	revoked, err := app.sessions.RevokeUser(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to revoke sessions")
+		return
+	}
+
+	log.Printf("Admin %s revoked %d sessions of user %d", c.GetString("username"), revoked, id)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "User sessions revoked",
+		"user_id": id,
+		"revoked": revoked,
+	})
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Máximo de columnas permitidas en una especificación de orden
+const maxSortFields = 5
+
+// Columna ordenable de propiedades
+type sortColumn struct {
+	Column string
+	Value  func(p *Property) interface{}
+}
+
+// Claves públicas de ordenamiento mapeadas a columnas de la tabla properties
+var propertySortColumns = map[string]sortColumn{
+	"price":            {"sale_amount", func(p *Property) interface{} { return p.SaleAmount }},
+	"sale_amount":      {"sale_amount", func(p *Property) interface{} { return p.SaleAmount }},
+	"ratio":            {"sales_ratio", func(p *Property) interface{} { return p.SalesRatio }},
+	"sales_ratio":      {"sales_ratio", func(p *Property) interface{} { return p.SalesRatio }},
+	"year":             {"list_year", func(p *Property) interface{} { return p.ListYear }},
+	"list_year":        {"list_year", func(p *Property) interface{} { return p.ListYear }},
+	"town":             {"town", func(p *Property) interface{} { return p.Town }},
+	"recorded_date":    {"date_recorded", func(p *Property) interface{} { return p.DateRecorded }},
+	"date_recorded":    {"date_recorded", func(p *Property) interface{} { return p.DateRecorded }},
+	"assessed_value":   {"assessed_value", func(p *Property) interface{} { return p.AssessedValue }},
+	"years_until_sold": {"years_until_sold", func(p *Property) interface{} { return p.YearsUntilSold }},
+	"serial_number":    {"serial_number", func(p *Property) interface{} { return p.SerialNumber }},
+}
+
+// Campo de ordenamiento ya validado
+type sortField struct {
+	Key  string
+	Desc bool
+}
+
+// Columna SQL asociada al campo
+func (f sortField) column() sortColumn {
+	return propertySortColumns[f.Key]
+}
+
+// Error de especificación de orden inválida
+type sortSpecError struct {
+	Message     string
+	InvalidKeys []string
+}
+
+func (e *sortSpecError) Error() string {
+	return e.Message
+}
+
+// Error 400 con las claves rechazadas y las permitidas
+func (e *sortSpecError) problem() *APIError {
+	return &APIError{
+		Status: http.StatusBadRequest,
+		Code:   codeInvalidSort,
+		Detail: e.Message,
+		Extra: gin.H{
+			"invalid_keys": e.InvalidKeys,
+			"allowed_keys": allowedSortKeys(),
+		},
+	}
+}
+
+// Listar claves de orden permitidas
+func allowedSortKeys() []string {
+	keys := make([]string, 0, len(propertySortColumns))
+	for key := range propertySortColumns {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Parsear especificación de orden tipo "-sale_amount,town"
+func parseSortSpec(spec string) ([]sortField, error) {
+	var fields []sortField
+	var invalid []string
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		desc := false
+		if strings.HasPrefix(part, "-") {
+			desc = true
+			part = part[1:]
+		} else if strings.HasPrefix(part, "+") {
+			part = part[1:]
+		}
+
+		key := strings.ToLower(part)
+		col, ok := propertySortColumns[key]
+		if !ok {
+			invalid = append(invalid, part)
+			continue
+		}
+
+		// Ignorar columnas repetidas a través de alias
+		if seen[col.Column] {
+			continue
+		}
+		seen[col.Column] = true
+		fields = append(fields, sortField{Key: key, Desc: desc})
+	}
+
+	if len(invalid) > 0 {
+		return nil, &sortSpecError{Message: "Invalid sort key", InvalidKeys: invalid}
+	}
+	if len(fields) > maxSortFields {
+		return nil, &sortSpecError{Message: fmt.Sprintf("Too many sort keys (max %d)", maxSortFields)}
+	}
+	return fields, nil
+}
+
+// Obtener orden desde la query (sort=, o sort_by/sort_order heredados)
+func propertySortFromQuery(query url.Values) ([]sortField, error) {
+	if spec := query.Get("sort"); spec != "" {
+		return parseSortSpec(spec)
+	}
+
+	sortBy, sortOrder := query.Get("sort_by"), query.Get("sort_order")
+	if sortBy == "" {
+		sortBy = "serial_number"
+	}
+	switch strings.ToLower(sortOrder) {
+	case "", "asc":
+		return parseSortSpec(sortBy)
+	case "desc":
+		return parseSortSpec("-" + sortBy)
+	default:
+		return nil, &sortSpecError{Message: "Invalid sort order. Must be 'asc' or 'desc'"}
+	}
+}
+
+// Completar orden con serial_number para que sea determinista
+func withTiebreaker(fields []sortField) []sortField {
+	for _, f := range fields {
+		if f.column().Column == "serial_number" {
+			return fields
+		}
+	}
+	return append(fields, sortField{Key: "serial_number"})
+}
+
+// Representación canónica de la especificación de orden
+func sortSpecString(fields []sortField) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		if f.Desc {
+			parts[i] = "-" + f.Key
+		} else {
+			parts[i] = f.Key
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Construir cláusula ORDER BY a partir de campos validados
+func buildOrderBy(fields []sortField) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		direction := "ASC"
+		if f.Desc {
+			direction = "DESC"
+		}
+		parts[i] = f.column().Column + " " + direction
+	}
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestPropertySortFromQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		orderBy string
+		invalid []string
+	}{
+		{"default", "", " ORDER BY serial_number ASC", nil},
+		{"multi key", "sort=-sale_amount,town", " ORDER BY sale_amount DESC, town ASC, serial_number ASC", nil},
+		{"explicit ascending", "sort=+year", " ORDER BY list_year ASC, serial_number ASC", nil},
+		{"case and spaces", "sort= Town , -RATIO", " ORDER BY town ASC, sales_ratio DESC, serial_number ASC", nil},
+		{"duplicate key", "sort=town,-town", " ORDER BY town ASC, serial_number ASC", nil},
+		{"alias of same column", "sort=-price,sale_amount", " ORDER BY sale_amount DESC, serial_number ASC", nil},
+		{"explicit tiebreaker", "sort=-serial_number,town", " ORDER BY serial_number DESC, town ASC", nil},
+		{"legacy params", "sort_by=assessed_value&sort_order=DESC", " ORDER BY assessed_value DESC, serial_number ASC", nil},
+		{"sort wins over legacy", "sort=town&sort_by=price&sort_order=desc", " ORDER BY town ASC, serial_number ASC", nil},
+		{"unknown column", "sort=price,owner", "", []string{"owner"}},
+		{"sql injection", "sort=town%3BDROP+TABLE+users", "", []string{"town;DROP TABLE users"}},
+		{"qualified column", "sort=properties.town", "", []string{"properties.town"}},
+		{"double minus", "sort=--price", "", []string{"-price"}},
+		{"direction suffix", "sort=price+desc", "", []string{"price desc"}},
+		{"legacy unknown column", "sort_by=id", "", []string{"id"}},
+		{"legacy bad direction", "sort_by=price&sort_order=sideways", "", nil},
+		{"too many keys", "sort=price,ratio,year,town,date_recorded,assessed_value", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fields, err := propertySortFromQuery(query)
+			if tt.orderBy != "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got := buildOrderBy(withTiebreaker(fields)); got != tt.orderBy {
+					t.Errorf("ORDER BY = %q, want %q", got, tt.orderBy)
+				}
+				return
+			}
+
+			var specErr *sortSpecError
+			if !errors.As(err, &specErr) {
+				t.Fatalf("error = %v, want *sortSpecError", err)
+			}
+			if !slices.Equal(specErr.InvalidKeys, tt.invalid) {
+				t.Errorf("invalid keys = %q, want %q", specErr.InvalidKeys, tt.invalid)
+			}
+			if problem := specErr.problem(); problem.Status != 400 || problem.Code != codeInvalidSort {
+				t.Errorf("problem = %d %s, want 400 %s", problem.Status, problem.Code, codeInvalidSort)
+			}
+		})
+	}
+}
+
+func TestSortSpecString(t *testing.T) {
+	fields, err := parseSortSpec("-PRICE, town,+year")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sortSpecString(withTiebreaker(fields)); got != "-price,town,year,serial_number" {
+		t.Errorf("sortSpecString = %q", got)
+	}
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Errores comunes de los stores, independientes del motor
+var (
+	errNotFound = errors.New("not found")
+	errConflict = errors.New("already exists")
+	// La versión de fila no coincide con la esperada (If-Match)
+	errVersionMismatch = errors.New("version mismatch")
+)
+
+// Autor de una mutación para auditoría e historial; nil en tareas sin petición HTTP
+type Actor struct {
+	UserID    *int
+	Username  string
+	RequestID string
+	ClientIP  string
+}
+
+// Autor de la petición autenticada
+func actorFromContext(c *gin.Context) *Actor {
+	actor := &Actor{
+		Username:  c.GetString("username"),
+		RequestID: c.GetString("request_id"),
+		ClientIP:  c.ClientIP(),
+	}
+	if id, ok := c.Get("user_id"); ok {
+		value := id.(int)
+		actor.UserID = &value
+	}
+	return actor
+}
+
+// Filtros de listado de propiedades; los campos vacíos o nil no filtran
+type PropertyFilter struct {
+	Town              string
+	Towns             []string // coincidencia exacta con alguno (operaciones masivas)
+	MinPrice          *float64
+	MaxPrice          *float64
+	PropertyType      string
+	ResidentialType   string
+	ListYear          *int
+	Status            string
+	MinSalesRatio     *float64
+	MaxSalesRatio     *float64
+	MinYearsUntilSold *int
+	MaxYearsUntilSold *int
+	Search            string // texto completo y similitud sobre dirección y ciudad
+
+	// Consultar el estado vigente en ese instante en lugar del actual
+	AsOf *time.Time
+}
+
+// Leer los filtros de la query string
+func propertyFilterFromQuery(query url.Values) (PropertyFilter, error) {
+	filter := PropertyFilter{
+		Town:            query.Get("town"),
+		PropertyType:    query.Get("property_type"),
+		ResidentialType: query.Get("residential_type"),
+		Status:          query.Get("status"),
+	}
+
+	search, err := searchFromQuery(query.Get("q"))
+	if err != nil {
+		return filter, err
+	}
+	filter.Search = search
+
+	floats := map[string]**float64{
+		"min_price":       &filter.MinPrice,
+		"max_price":       &filter.MaxPrice,
+		"min_sales_ratio": &filter.MinSalesRatio,
+		"max_sales_ratio": &filter.MaxSalesRatio,
+	}
+	for name, target := range floats {
+		if value := query.Get(name); value != "" {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return filter, fmt.Errorf("Invalid '%s' filter. Must be a number", name)
+			}
+			*target = &f
+		}
+	}
+
+	ints := map[string]**int{
+		"list_year":            &filter.ListYear,
+		"min_years_until_sold": &filter.MinYearsUntilSold,
+		"max_years_until_sold": &filter.MaxYearsUntilSold,
+	}
+	for name, target := range ints {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return filter, fmt.Errorf("Invalid '%s' filter. Must be an integer", name)
+			}
+			*target = &n
+		}
+	}
+	return filter, nil
+}
+
+// Consulta de una página de propiedades. Con After se devuelven sólo las filas
+// estrictamente posteriores a esos valores en el orden de Sort (paginación keyset).
+type PropertyQuery struct {
+	Filter PropertyFilter
+	Sort   []sortField
+	After  []interface{}
+	Limit  int
+	Offset int
+}
+
+// Propiedad encontrada por una búsqueda de texto
+type PropertyMatch struct {
+	Property
+	Relevance float64 `json:"relevance"`
+	Highlight string  `json:"highlight"` // dirección y ciudad con <mark> en las coincidencias
+}
+
+// Tipos de sugerencia del autocompletado
+const (
+	suggestAddress = "address"
+	suggestTown    = "town"
+)
+
+// Dirección o ciudad que completa lo escrito; Count es el número de propiedades
+type PropertySuggestion struct {
+	Type      string `json:"type"`
+	Value     string `json:"value"`
+	Town      string `json:"town,omitempty"`
+	Count     int    `json:"count"`
+	Highlight string `json:"highlight"`
+}
+
+// Valores válidos de las columnas categóricas; una lista vacía no restringe
+type PropertyReference struct {
+	Towns            []string
+	PropertyTypes    []string
+	ResidentialTypes []string
+}
+
+// Tipos de operación de una escritura masiva
+const (
+	bulkCreate = "create"
+	bulkUpdate = "update"
+	bulkDelete = "delete"
+)
+
+// Operación de una escritura masiva. Un update con Filter se aplica a todas las
+// filas que lo cumplen en el momento de ejecutarse, dentro de la misma transacción.
+type PropertyOperation struct {
+	Kind     string
+	ID       int64
+	Version  int64 // 0 no comprueba la versión
+	Filter   *PropertyFilter
+	Property Property // create
+	// update: cambia la fila; create: valida Property antes de insertarla.
+	// Se ejecuta con la fila bloqueada y no debe consultar el store.
+	Apply func(p *Property) error
+}
+
+// Resultado de una operación sobre una fila; Err es el motivo si falló
+type PropertyOperationResult struct {
+	Index   int
+	ID      int64
+	Version int64
+	Err     error
+}
+
+// Ejecutar apply si la operación lo define
+func applyOptional(apply func(p *Property) error, p *Property) error {
+	if apply == nil {
+		return nil
+	}
+	return apply(p)
+}
+
+// Propiedades: lectura, mutaciones auditadas, papelera e historial
+type PropertyStore interface {
+	List(ctx context.Context, q PropertyQuery) ([]Property, error)
+	Count(ctx context.Context, filter PropertyFilter) (int, error)
+	// Search devuelve las coincidencias de Filter.Search por relevancia, o en el orden de Sort si se indica
+	Search(ctx context.Context, q PropertyQuery) ([]PropertyMatch, error)
+	// Suggest completa direcciones y ciudades que empiezan por q o se le parecen
+	Suggest(ctx context.Context, q string, limit int) ([]PropertySuggestion, error)
+	// LookupAddress devuelve las propiedades con esa dirección normalizada, solo de town si no está vacío
+	LookupAddress(ctx context.Context, normalized, town string, limit int) ([]Property, error)
+	// AddressDuplicates agrupa las direcciones normalizadas que se repiten dentro de una ciudad
+	AddressDuplicates(ctx context.Context, town string, limit, offset int) ([]AddressDuplicate, int, error)
+	Get(ctx context.Context, id int64, asOf *time.Time) (Property, error)
+	GetWithVersion(ctx context.Context, id int64) (Property, int64, error)
+	Towns(ctx context.Context) ([]string, error)
+	PropertyTypes(ctx context.Context) ([]string, error)
+	ResidentialTypes(ctx context.Context) ([]string, error)
+	ListYears(ctx context.Context) ([]int, error)
+	Reference(ctx context.Context) (PropertyReference, error)
+
+	Create(ctx context.Context, actor *Actor, p Property) error
+	// Las escrituras con version distinta de 0 fallan con errVersionMismatch si la
+	// fila cambió; Update y Patch devuelven la nueva versión
+	Update(ctx context.Context, actor *Actor, p Property, version int64) (int64, error)
+	// Patch aplica apply sobre la fila actual y escribe solo las columnas que cambian
+	Patch(ctx context.Context, actor *Actor, id int64, version int64, apply func(p *Property) error) (Property, int64, error)
+	Delete(ctx context.Context, actor *Actor, id int64, version int64) error
+	// Bulk ejecuta las operaciones en una transacción. Cada fila que falla se
+	// descarta sola; con atomic un solo fallo deshace todas. Devuelve si se confirmó.
+	Bulk(ctx context.Context, actor *Actor, ops []PropertyOperation, atomic bool) ([]PropertyOperationResult, bool, error)
+
+	ListDeleted(ctx context.Context, limit, offset int) ([]DeletedProperty, int, error)
+	Restore(ctx context.Context, actor *Actor, id int64) (Property, error)
+	History(ctx context.Context, id int64) ([]PropertyRevision, error)
+}
+
+// Datos para crear un usuario
+type NewUser struct {
+	Username     string
+	Email        string
+	PasswordHash string
+	Role         string
+}
+
+// Usuarios: credenciales, perfil, administración y papelera
+type UserStore interface {
+	Credentials(ctx context.Context, username string) (User, string, error)
+	Get(ctx context.Context, id int) (User, error)
+	List(ctx context.Context) ([]User, error)
+	RoleExists(ctx context.Context, role string) (bool, error)
+
+	// Register crea la cuenta sin auditoría (autoregistro); Create la audita
+	Register(ctx context.Context, u NewUser) (User, error)
+	Create(ctx context.Context, actor *Actor, u NewUser) (User, error)
+	UpdateEmail(ctx context.Context, id int, email string) error
+	Update(ctx context.Context, actor *Actor, id int, email, role string) (User, error)
+	Patch(ctx context.Context, actor *Actor, id int, apply func(u *User) error) (User, error)
+	Delete(ctx context.Context, actor *Actor, id int) error
+
+	ListDeleted(ctx context.Context, limit, offset int) ([]DeletedUser, int, error)
+	Restore(ctx context.Context, actor *Actor, id int) (User, error)
+}
+
+// Usuario al que pertenece una sesión
+type SessionOwner struct {
+	SessionID int64
+	UserID    int
+	Username  string
+	Role      string
+}
+
+// Datos para abrir una sesión; solo se guarda el hash del refresh token
+type NewSession struct {
+	UserID      int
+	RefreshHash string
+	UserAgent   string
+	ClientIP    string
+	TTL         time.Duration
+}
+
+// Sesiones persistentes con refresh token rotatorio
+type SessionStore interface {
+	Create(ctx context.Context, s NewSession) (int64, error)
+	// Active devuelve el rol y los permisos actuales del usuario, o errNotFound si la
+	// sesión se revocó o expiró o el usuario fue eliminado
+	Active(ctx context.Context, sessionID int64, userID int) (string, []string, error)
+	// Rotate sustituye el refresh token presentado por newHash. Presentar el token
+	// anterior revoca la sesión y devuelve errRefreshTokenReuse junto con su dueño
+	Rotate(ctx context.Context, presentedHash, newHash string) (SessionOwner, error)
+	// Las revocaciones devuelven cuántas sesiones activas se cerraron
+	RevokeToken(ctx context.Context, refreshHash string) (int64, error)
+	Revoke(ctx context.Context, sessionID int64) (int64, error)
+	RevokeUser(ctx context.Context, userID int) (int64, error)
+}
+
+// Un rol asignado a usuarios no se puede eliminar
+type roleInUseError struct {
+	Users int
+}
+
+func (e *roleInUseError) Error() string {
+	return fmt.Sprintf("role is assigned to %d users", e.Users)
+}
+
+// Roles con sus permisos; las mutaciones se auditan
+type RoleStore interface {
+	List(ctx context.Context) ([]Role, error)
+	Create(ctx context.Context, actor *Actor, role Role) error
+	// Update reemplaza descripción y permisos y devuelve el rol resultante
+	Update(ctx context.Context, actor *Actor, role Role) (Role, error)
+	// Delete falla con *roleInUseError si algún usuario tiene el rol
+	Delete(ctx context.Context, actor *Actor, name string) error
+}
+
+// Filtros del registro de auditoría; los campos vacíos o nil no filtran
+type AuditFilter struct {
+	ActorID       *int
+	ActorUsername string
+	Entity        string
+	EntityID      string
+	Action        string
+	From          *time.Time // occurred_at >= From
+	To            *time.Time // occurred_at < To
+}
+
+// Lectura del registro de auditoría; cada store escribe las entradas de sus mutaciones
+type AuditStore interface {
+	List(ctx context.Context, filter AuditFilter, limit, offset int) ([]AuditEntry, int, error)
+}
+
+// Indicadores principales del dashboard
+type KPIs struct {
+	TotalProperties       int        `json:"total_properties"`
+	AveragePrice          float64    `json:"average_price"`
+	AverageSalesRatio     float64    `json:"average_sales_ratio"`
+	AverageYearsUntilSold float64    `json:"average_years_until_sold"`
+	TopCity               NamedCount `json:"top_city"`
+	TopPropertyType       NamedCount `json:"top_property_type"`
+}
+
+type NamedCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type YearTrend struct {
+	Year              int     `json:"year"`
+	TotalSales        int     `json:"total_sales"`
+	AvgPrice          float64 `json:"avg_price"`
+	AvgSalesRatio     float64 `json:"avg_sales_ratio"`
+	AvgYearsUntilSold float64 `json:"avg_years_until_sold"`
+}
+
+type TownPrice struct {
+	Town         string  `json:"town"`
+	AveragePrice float64 `json:"average_price"`
+	Count        int     `json:"count"`
+}
+
+type PropertyTypeStats struct {
+	PropertyType  string  `json:"property_type"`
+	Count         int     `json:"count"`
+	AveragePrice  float64 `json:"average_price"`
+	AvgSalesRatio float64 `json:"avg_sales_ratio"`
+}
+
+type DistributionBucket struct {
+	Range      string  `json:"range"`
+	Count      int     `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
+
+type TownVolume struct {
+	Town         string  `json:"town"`
+	Count        int     `json:"count"`
+	AveragePrice float64 `json:"average_price"`
+}
+
+// Agregados de solo lectura; separable para usar una réplica o una caché
+type AnalyticsStore interface {
+	KPIs(ctx context.Context) (KPIs, error)
+	TrendsByYear(ctx context.Context) ([]YearTrend, error)
+	AveragePriceByTown(ctx context.Context, limit int) ([]TownPrice, error)
+	PropertyTypeAnalysis(ctx context.Context) ([]PropertyTypeStats, error)
+	SalesRatioDistribution(ctx context.Context) ([]DistributionBucket, error)
+	TimeToSellDistribution(ctx context.Context) ([]DistributionBucket, error)
+	TopCitiesByVolume(ctx context.Context, limit int) ([]TownVolume, error)
+}
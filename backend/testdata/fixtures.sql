@@ -0,0 +1,24 @@
+-- Datos deterministas de las pruebas de integración. Los valores están elegidos
+-- para que los promedios y porcentajes de /analytics sean exactos.
+
+INSERT INTO properties (serial_number, list_year, date_recorded, town, address, assessed_value, sale_amount, sales_ratio, property_type, residential_type, years_until_sold) VALUES
+(1001, 2019, '2020-03-15', 'Hartford', '12 MAIN ST',    150000, 200000, 0.75, 'Residential', 'Single Family', 1),
+(1002, 2019, '2019-11-02', 'Hartford', '40 ELM ST',      90000, 100000, 0.90, 'Residential', 'Condo',         0),
+(1003, 2020, '2021-06-30', 'Ashford',  '7 OAK RD',      210000, 200000, 1.05, 'Residential', 'Single Family', 1),
+(1004, 2020, '2022-01-10', 'Bristol',  '88 PARK AVE',   330000, 300000, 1.10, 'Commercial',  'Nan',           2),
+(1005, 2021, '2021-09-01', 'Bristol',  '5 RIVER RD',    125000, 125000, 1.00, 'Residential', 'Two Family',    0),
+(1006, 2021, '2024-05-20', 'Hartford', '300 STATE ST',  500000, 400000, 1.25, 'Commercial',  'Nan',           3),
+(1007, 2022, '2022-12-12', 'Cheshire', '19 HILL ST',     85000, 100000, 0.85, 'Residential', 'Condo',         0),
+(1008, 2018, '2024-02-01', 'Ashford',  '2 LAKE DR',      40000,      0, 0.00, 'Vacant Land', 'Nan',           6),
+(1009, 2023, '2023-08-08', 'Cheshire', '61 MAPLE AVE',  240000, 200000, 1.20, 'Residential', 'Single Family', 0),
+(1010, 2020, '2024-07-07', 'Nan',      'Nan',            60000,  80000, 0.75, 'Nan',         'Nan',           4);
+
+INSERT INTO towns (name) VALUES ('Ashford'), ('Bristol'), ('Cheshire'), ('Hartford');
+INSERT INTO property_types (name) VALUES ('Commercial'), ('Residential'), ('Vacant Land');
+INSERT INTO residential_types (name) VALUES ('Condo'), ('Single Family'), ('Two Family');
+
+-- Contraseña de todos los usuarios: fixture-password
+INSERT INTO users (username, email, password_hash, role) VALUES
+('admin',   'admin@urbanytics.test',   '$2a$04$7F2uDPN/tDnqoTMWe.lRA.xycNi4Sq43IIoghZFV2PV05kq7R48D6', 'admin'),
+('analyst', 'analyst@urbanytics.test', '$2a$04$7F2uDPN/tDnqoTMWe.lRA.xycNi4Sq43IIoghZFV2PV05kq7R48D6', 'analyst'),
+('viewer',  'viewer@urbanytics.test',  '$2a$04$7F2uDPN/tDnqoTMWe.lRA.xycNi4Sq43IIoghZFV2PV05kq7R48D6', 'user');
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Código no estándar (nginx) para peticiones abandonadas por el cliente
+const statusClientClosedRequest = 499
+
+// Clases de rutas según la duración esperada de sus consultas
+const (
+	timeoutLookup    = "lookup"
+	timeoutAnalytics = "analytics"
+	timeoutBulk      = "bulk"
+)
+
+// Rutas que recorren la tabla completa; el resto de /analytics usa timeoutAnalytics
+// y todas las demás timeoutLookup
+var bulkRoutes = map[string]bool{
+	"/api/v1/properties/export":       true,
+	"/api/v1/admin/properties/import": true,
+	"/api/v1/admin/properties/bulk":   true,
+}
+
+// Clase de timeout de una ruta registrada
+func routeTimeoutClass(route string) string {
+	switch {
+	case bulkRoutes[route]:
+		return timeoutBulk
+	case strings.HasPrefix(route, "/api/v1/analytics/"):
+		return timeoutAnalytics
+	default:
+		return timeoutLookup
+	}
+}
+
+// Duración configurada para una clase de rutas
+func (cfg *Config) routeTimeout(class string) time.Duration {
+	switch class {
+	case timeoutBulk:
+		return cfg.Timeouts.Bulk
+	case timeoutAnalytics:
+		return cfg.Timeouts.Analytics
+	default:
+		return cfg.Timeouts.Lookup
+	}
+}
+
+// Middleware que limita la duración del contexto de la petición. pgx cancela en
+// el servidor la consulta en curso cuando el contexto vence o el cliente se desconecta.
+func (app *App) queryTimeout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		class := routeTimeoutClass(c.FullPath())
+		ctx, cancel := context.WithTimeout(c.Request.Context(), app.config.routeTimeout(class))
+		defer cancel()
+
+		c.Set("timeout_class", class)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// Responder un error interno; si el contexto de la petición venció responde 504
+// y si el cliente se desconectó 499 sin cuerpo
+func internalError(c *gin.Context, message string) {
+	switch c.Request.Context().Err() {
+	case context.DeadlineExceeded:
+		abortWithError(c, &APIError{
+			Status: http.StatusGatewayTimeout,
+			Code:   codeRequestTimeout,
+			Detail: "Request timed out",
+			Extra:  gin.H{"timeout": c.GetString("timeout_class")},
+		})
+	case context.Canceled:
+		c.AbortWithStatus(statusClientClosedRequest)
+	default:
+		abortProblem(c, http.StatusInternalServerError, codeInternalError, message)
+	}
+}
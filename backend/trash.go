@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Propiedad en la papelera
+type DeletedProperty struct {
+	Property
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy *string   `json:"deleted_by"`
+}
+
+// Usuario en la papelera
+type DeletedUser struct {
+	User
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy *string   `json:"deleted_by"`
+}
+
+// Leer page y limit de la papelera
+func trashPage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+	return page, limit
+}
+
+// Listar propiedades eliminadas (admin)
+func (app *App) getDeletedProperties(c *gin.Context) {
+	page, limit := trashPage(c)
+	offset := (page - 1) * limit
+
+	properties, totalCount, err := app.properties.ListDeleted(c.Request.Context(), limit, offset)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to query deleted properties")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    properties,
+		"pagination": gin.H{
+			"current_page": page,
+			"total_pages":  (totalCount + limit - 1) / limit,
+			"total_count":  totalCount,
+			"limit":        limit,
+			"offset":       offset,
+		},
+	})
+}
+
+// Restaurar propiedad eliminada (admin)
+func (app *App) restoreProperty(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		abortProblem(c, http.StatusBadRequest, codeInvalidParameter, "Invalid property ID")
+		return
+	}
+
+	property, err := app.properties.Restore(c.Request.Context(), actorFromContext(c), id)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			abortProblem(c, http.StatusNotFound, codePropertyNotFound, "Deleted property not found")
+			return
+		}
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to restore property")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    property,
+	})
+}
+
+// Listar usuarios eliminados (admin)
+func (app *App) getDeletedUsers(c *gin.Context) {
+	page, limit := trashPage(c)
+	offset := (page - 1) * limit
+
+	users, totalCount, err := app.users.ListDeleted(c.Request.Context(), limit, offset)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to query deleted users")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    users,
+		"pagination": gin.H{
+			"current_page": page,
+			"total_pages":  (totalCount + limit - 1) / limit,
+			"total_count":  totalCount,
+			"limit":        limit,
+			"offset":       offset,
+		},
+	})
+}
+
+// Restaurar usuario eliminado (admin); sus sesiones siguen revocadas
+func (app *App) restoreUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortProblem(c, http.StatusBadRequest, codeInvalidParameter, "Invalid user ID")
+		return
+	}
+
+	user, err := app.users.Restore(c.Request.Context(), actorFromContext(c), id)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			abortProblem(c, http.StatusNotFound, codeUserNotFound, "Deleted user not found")
+			return
+		}
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to restore user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    user,
+	})
+}
+
+// Borrar definitivamente los registros eliminados hace más que la retención
+func (app *App) purgeDeleted(ctx context.Context, retention time.Duration) (int64, int64, error) {
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback(ctx)
+	if err := setChangeActor(ctx, tx, nil); err != nil {
+		return 0, 0, err
+	}
+
+	cutoff := "deleted_at < CURRENT_TIMESTAMP - make_interval(secs => $1)"
+	properties, err := tx.Exec(ctx, "DELETE FROM properties WHERE "+cutoff, retention.Seconds())
+	if err != nil {
+		return 0, 0, err
+	}
+	users, err := tx.Exec(ctx, "DELETE FROM users WHERE "+cutoff, retention.Seconds())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for entity, purged := range map[string]int64{"property": properties.RowsAffected(), "user": users.RowsAffected()} {
+		if purged == 0 {
+			continue
+		}
+		summary := gin.H{"purged": purged, "retention": retention.String()}
+		if err := recordAudit(ctx, tx, nil, auditPurge, entity, "", nil, summary); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, err
+	}
+	return properties.RowsAffected(), users.RowsAffected(), nil
+}
+
+// Ejecutar la purga al iniciar y después en cada intervalo
+func (app *App) startPurgeJob() {
+	retention, interval := app.config.Purge.Retention, app.config.Purge.Interval
+	if retention <= 0 || interval <= 0 {
+		log.Printf("Purge of deleted records disabled")
+		return
+	}
+
+	purge := func() {
+		properties, users, err := app.purgeDeleted(context.Background(), retention)
+		if err != nil {
+			log.Printf("Failed to purge deleted records: %v", err)
+			return
+		}
+		if properties > 0 || users > 0 {
+			log.Printf("Purged %d properties and %d users deleted more than %s ago", properties, users, retention)
+		}
+	}
+
+	go func() {
+		purge()
+		for range time.Tick(interval) {
+			purge()
+		}
+	}()
+}
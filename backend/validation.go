@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Primer año de listado aceptado
+const minListYear = 1900
+
+// Diferencia relativa admitida entre sales_ratio y assessed_value / sale_amount
+const salesRatioTolerance = 0.001
+
+// Reglas de dominio de una propiedad: rangos, campos derivados y valores de referencia
+type propertyValidator struct {
+	towns            map[string]string // nombre en minúsculas -> nombre canónico
+	propertyTypes    map[string]string
+	residentialTypes map[string]string
+	now              time.Time
+}
+
+func newPropertyValidator(ref PropertyReference, now time.Time) *propertyValidator {
+	return &propertyValidator{
+		towns:            canonicalNames(ref.Towns),
+		propertyTypes:    canonicalNames(ref.PropertyTypes),
+		residentialTypes: canonicalNames(ref.ResidentialTypes),
+		now:              now,
+	}
+}
+
+// Índice sin distinción de mayúsculas de los valores de referencia
+func canonicalNames(values []string) map[string]string {
+	names := make(map[string]string, len(values))
+	for _, value := range values {
+		names[strings.ToLower(value)] = value
+	}
+	return names
+}
+
+// Validar y normalizar la propiedad; devuelve todas las infracciones a la vez.
+// Los campos derivados a cero se calculan y los informados deben ser coherentes.
+func (v *propertyValidator) validate(p *Property) []FieldError {
+	var errs []FieldError
+	fail := func(field, rule, message string) {
+		errs = append(errs, FieldError{Field: field, Rule: rule, Message: message})
+	}
+
+	if p.SerialNumber <= 0 {
+		fail("serial_number", "gt", "must be a positive integer")
+	}
+
+	maxYear := v.now.Year()
+	listYearValid := false
+	switch {
+	case p.ListYear < minListYear:
+		fail("list_year", "min", fmt.Sprintf("must be between %d and %d", minListYear, maxYear))
+	case p.ListYear > maxYear:
+		fail("list_year", "max", fmt.Sprintf("must be between %d and %d", minListYear, maxYear))
+	default:
+		listYearValid = true
+	}
+
+	// Fecha de registro: se guarda siempre como YYYY-MM-DD
+	var recorded time.Time
+	if p.DateRecorded == "" {
+		fail("date_recorded", "required", "is required")
+	} else if t, ok := parseDateRecorded(p.DateRecorded); !ok {
+		fail("date_recorded", "date", "must be a date (YYYY-MM-DD or MM/DD/YYYY)")
+	} else if t.After(v.now) {
+		fail("date_recorded", "lte", "must not be in the future")
+	} else {
+		recorded = t
+		p.DateRecorded = t.Format("2006-01-02")
+	}
+
+	// Valores categóricos contra las tablas de referencia
+	if town := strings.TrimSpace(p.Town); town == "" || town == missingValue {
+		fail("town", "required", "is required")
+	} else if canonical, ok := lookupReference(v.towns, town); !ok {
+		fail("town", "exists", fmt.Sprintf("unknown town %q", town))
+	} else {
+		p.Town = canonical
+	}
+	p.Address = textOrMissing(strings.TrimSpace(p.Address))
+	p.PropertyType = v.checkCategory(&errs, "property_type", v.propertyTypes, p.PropertyType)
+	p.ResidentialType = v.checkCategory(&errs, "residential_type", v.residentialTypes, p.ResidentialType)
+
+	// Importes y ratio de venta
+	amountsValid := true
+	if p.AssessedValue < 0 {
+		fail("assessed_value", "gte", "must not be negative")
+		amountsValid = false
+	}
+	if p.SaleAmount < 0 {
+		fail("sale_amount", "gte", "must not be negative")
+		amountsValid = false
+	}
+	switch {
+	case p.SalesRatio < 0:
+		fail("sales_ratio", "gte", "must not be negative")
+	case !amountsValid:
+	case p.SaleAmount == 0:
+		if p.SalesRatio != 0 {
+			fail("sales_ratio", "consistent", "must be 0 when sale_amount is 0")
+		}
+	case p.SalesRatio == 0:
+		p.SalesRatio = p.AssessedValue / p.SaleAmount
+	default:
+		expected := p.AssessedValue / p.SaleAmount
+		if math.Abs(p.SalesRatio-expected) > salesRatioTolerance*math.Max(1, expected) {
+			fail("sales_ratio", "consistent", fmt.Sprintf("must equal assessed_value / sale_amount (%.4f)", expected))
+		}
+	}
+
+	// Años hasta la venta: diferencia entre el año de registro y list_year
+	switch {
+	case p.YearsUntilSold < 0:
+		fail("years_until_sold", "gte", "must not be negative")
+	case !listYearValid || recorded.IsZero():
+	case recorded.Year() < p.ListYear:
+		fail("date_recorded", "gtefield", "must not precede list_year")
+	case p.YearsUntilSold == 0:
+		p.YearsUntilSold = recorded.Year() - p.ListYear
+	case p.YearsUntilSold != recorded.Year()-p.ListYear:
+		fail("years_until_sold", "consistent", fmt.Sprintf("must equal the year of date_recorded minus list_year (%d)", recorded.Year()-p.ListYear))
+	}
+
+	return errs
+}
+
+// Campos de los que depende cada regla cruzada; el resto solo depende del campo que informa
+var propertyRuleFields = map[string][]string{
+	"sales_ratio/consistent":      {"sales_ratio", "assessed_value", "sale_amount"},
+	"years_until_sold/consistent": {"years_until_sold", "date_recorded", "list_year"},
+	"date_recorded/gtefield":      {"date_recorded", "list_year"},
+}
+
+// Validar una modificación parcial de before. Solo se exigen las reglas que
+// dependen de algún campo modificado: los valores heredados que el cambio no
+// toca (town 'Nan', un sales_ratio antiguo) no impiden editar el resto.
+func (v *propertyValidator) validatePatch(before Property, p *Property) []FieldError {
+	changed := changedPropertyFields(before, *p)
+	var errs []FieldError
+	for _, fe := range v.validate(p) {
+		fields, ok := propertyRuleFields[fe.Field+"/"+fe.Rule]
+		if !ok {
+			fields = []string{fe.Field}
+		}
+		if slices.ContainsFunc(fields, func(field string) bool { return changed[field] }) {
+			errs = append(errs, fe)
+		}
+	}
+	return errs
+}
+
+// Campos de Property con distinto valor en before y after
+func changedPropertyFields(before, after Property) map[string]bool {
+	columns := strings.Split(propertyColumns, ", ")
+	old, current := propertyValues(before), propertyValues(after)
+	changed := make(map[string]bool, len(columns))
+	for i, column := range columns {
+		if old[i] != current[i] {
+			changed[column] = true
+		}
+	}
+	return changed
+}
+
+// Comprobar un tipo opcional; vacío se guarda como 'Nan'
+func (v *propertyValidator) checkCategory(errs *[]FieldError, field string, known map[string]string, value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" || value == missingValue {
+		return missingValue
+	}
+	canonical, ok := lookupReference(known, value)
+	if !ok {
+		*errs = append(*errs, FieldError{Field: field, Rule: "exists", Message: fmt.Sprintf("unknown %s %q", strings.ReplaceAll(field, "_", " "), value)})
+		return value
+	}
+	return canonical
+}
+
+// Sin valores de referencia cargados se acepta cualquier valor
+func lookupReference(known map[string]string, value string) (string, bool) {
+	if len(known) == 0 {
+		return value, true
+	}
+	canonical, ok := known[strings.ToLower(value)]
+	return canonical, ok
+}
+
+// Interpretar date_recorded en cualquiera de los formatos aceptados
+func parseDateRecorded(value string) (time.Time, bool) {
+	for _, layout := range dateRecordedLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Comprobar las reglas de dominio; si la propiedad no es válida devuelve un *APIError
+func (app *App) checkProperty(ctx context.Context, p *Property) error {
+	ref, err := app.properties.Reference(ctx)
+	if err != nil {
+		return err
+	}
+	if errs := newPropertyValidator(ref, time.Now()).validate(p); len(errs) > 0 {
+		return validationFailed(errs...)
+	}
+	return nil
+}
+
+// Como checkProperty, pero solo con las reglas afectadas por el cambio sobre before
+func (app *App) checkPropertyPatch(ctx context.Context, before Property, p *Property) error {
+	ref, err := app.properties.Reference(ctx)
+	if err != nil {
+		return err
+	}
+	if errs := newPropertyValidator(ref, time.Now()).validatePatch(before, p); len(errs) > 0 {
+		return validationFailed(errs...)
+	}
+	return nil
+}
+
+// Validar una propiedad antes de escribirla; si no es válida aborta con 422
+func (app *App) validateProperty(c *gin.Context, p *Property) bool {
+	err := app.checkProperty(c.Request.Context(), p)
+	var apiErr *APIError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &apiErr):
+		abortWithError(c, apiErr)
+	default:
+		log.Printf("Database error: %v", err)
+		internalError(c, "Failed to load reference data")
+	}
+	return false
+}
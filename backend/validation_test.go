@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPropertyValidator(t *testing.T) {
+	v := newPropertyValidator(PropertyReference{
+		Towns:         []string{"Hartford"},
+		PropertyTypes: []string{"Residential"},
+	}, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	// Los campos derivados se calculan y los valores se normalizan
+	p := Property{SerialNumber: 1, ListYear: 2022, DateRecorded: "03/15/2023", Town: "hartford",
+		AssessedValue: 50000, SaleAmount: 200000, PropertyType: "residential"}
+	if errs := v.validate(&p); len(errs) > 0 {
+		t.Fatalf("valid property rejected: %+v", errs)
+	}
+	want := Property{SerialNumber: 1, ListYear: 2022, DateRecorded: "2023-03-15", Town: "Hartford", Address: missingValue,
+		AssessedValue: 50000, SaleAmount: 200000, SalesRatio: 0.25, PropertyType: "Residential", ResidentialType: missingValue, YearsUntilSold: 1}
+	if p != want {
+		t.Errorf("normalized property = %+v, want %+v", p, want)
+	}
+
+	tests := []struct {
+		name  string
+		edit  func(p *Property)
+		field string
+		rule  string
+	}{
+		{"serial", func(p *Property) { p.SerialNumber = 0 }, "serial_number", "gt"},
+		{"future list year", func(p *Property) { p.ListYear = 2025 }, "list_year", "max"},
+		{"unparseable date", func(p *Property) { p.DateRecorded = "2023-02-30" }, "date_recorded", "date"},
+		{"future date", func(p *Property) { p.DateRecorded = "2024-06-02" }, "date_recorded", "lte"},
+		{"date before list year", func(p *Property) { p.DateRecorded = "2021-12-31" }, "date_recorded", "gtefield"},
+		{"unknown town", func(p *Property) { p.Town = "Springfield" }, "town", "exists"},
+		{"missing town", func(p *Property) { p.Town = missingValue }, "town", "required"},
+		{"unknown property type", func(p *Property) { p.PropertyType = "Castle" }, "property_type", "exists"},
+		{"negative sale", func(p *Property) { p.SaleAmount = -1 }, "sale_amount", "gte"},
+		{"inconsistent ratio", func(p *Property) { p.SalesRatio = 0.3 }, "sales_ratio", "consistent"},
+		{"ratio without sale", func(p *Property) { p.SaleAmount, p.SalesRatio = 0, 0.25 }, "sales_ratio", "consistent"},
+		{"inconsistent years", func(p *Property) { p.YearsUntilSold = 3 }, "years_until_sold", "consistent"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := want
+			tt.edit(&p)
+			errs := v.validate(&p)
+			if len(errs) != 1 || errs[0].Field != tt.field || errs[0].Rule != tt.rule {
+				t.Errorf("errors = %+v, want one %s/%s", errs, tt.field, tt.rule)
+			}
+		})
+	}
+
+	// Todas las infracciones se devuelven juntas
+	p = Property{ListYear: 1800, DateRecorded: "yesterday", SaleAmount: -1, AssessedValue: -1}
+	if errs := v.validate(&p); len(errs) != 6 {
+		t.Errorf("got %d errors, want 6: %+v", len(errs), errs)
+	}
+}
+
+func TestPropertyValidatorPatch(t *testing.T) {
+	v := newPropertyValidator(PropertyReference{Towns: []string{"Hartford"}}, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	// Fila heredada sin ciudad y con un ratio que no cuadra con los importes
+	legacy := Property{SerialNumber: 1, ListYear: 2022, DateRecorded: "2023-03-15", Town: missingValue, Address: missingValue,
+		AssessedValue: 50000, SaleAmount: 200000, SalesRatio: 0.3, PropertyType: missingValue, ResidentialType: missingValue, YearsUntilSold: 1}
+
+	tests := []struct {
+		name  string
+		edit  func(p *Property)
+		field string
+		rule  string
+	}{
+		{"untouched legacy values", func(p *Property) { p.Address = "12 MAPLE ST" }, "", ""},
+		{"ratio recalculated", func(p *Property) { p.SaleAmount, p.SalesRatio = 250000, 0 }, "", ""},
+		{"amount against stale ratio", func(p *Property) { p.SaleAmount = 250000 }, "sales_ratio", "consistent"},
+		{"changed town", func(p *Property) { p.Town = "Springfield" }, "town", "exists"},
+		{"list year against date", func(p *Property) { p.ListYear = 2024 }, "date_recorded", "gtefield"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := legacy
+			tt.edit(&p)
+			errs := v.validatePatch(legacy, &p)
+			switch {
+			case tt.field == "" && len(errs) > 0:
+				t.Errorf("patch rejected: %+v", errs)
+			case tt.field != "" && (len(errs) != 1 || errs[0].Field != tt.field || errs[0].Rule != tt.rule):
+				t.Errorf("errors = %+v, want one %s/%s", errs, tt.field, tt.rule)
+			}
+		})
+	}
+}
@@ -44,8 +44,10 @@ app.use(helmet({
 app.use(cors({
   origin: process.env.FRONTEND_URL || 'http://localhost:5173',
   credentials: true,
-  methods: ['GET', 'POST', 'PUT', 'DELETE', 'OPTIONS'],
-  allowedHeaders: ['Content-Type', 'Authorization']
+  methods: ['GET', 'POST', 'PUT', 'PATCH', 'DELETE', 'OPTIONS'],
+  allowedHeaders: ['Content-Type', 'Authorization', 'If-Match', 'If-None-Match'],
+  // ETag de las propiedades para editar con If-Match
+  exposedHeaders: ['ETag']
 }));
 
 // Compresión de respuestas
@@ -77,7 +79,8 @@ const limiter = rateLimit({
 app.use('/api/', limiter);
 
 // Parse JSON bodies
-app.use(express.json({ limit: '10mb' }));
+// application/*+json cubre los cuerpos de PATCH (merge-patch+json y json-patch+json)
+app.use(express.json({ limit: '10mb', type: ['application/json', 'application/*+json'] }));
 app.use(express.urlencoded({ extended: true, limit: '10mb' }));
 
 // Middleware de manejo de errores global
@@ -10,6 +10,11 @@ const router = express.Router();
 const backendService = require('../services/backendService');
 const cacheService = require('../services/cacheService');
 
+// Content-Type del PATCH hacia el backend: JSON Patch si el cliente lo envía, si no merge patch
+function patchContentType(req) {
+    return req.is('application/json-patch+json') ? 'application/json-patch+json' : 'application/merge-patch+json';
+}
+
 /**
  * GET /api/admin/users
  * Obtener lista de usuarios (solo admin)
@@ -30,7 +35,9 @@ router.get('/users', async (req, res) => {
         if (!result.success) {
             return res.status(result.status).json({
                 success: false,
-                error: result.error
+                error: result.error,
+                code: result.code,
+                errors: result.errors
             });
         }
 
@@ -85,7 +92,9 @@ router.post('/users', async (req, res) => {
         if (!result.success) {
             return res.status(result.status).json({
                 success: false,
-                error: result.error
+                error: result.error,
+                code: result.code,
+                errors: result.errors
             });
         }
 
@@ -134,7 +143,9 @@ router.put('/users/:id', async (req, res) => {
         if (!result.success) {
             return res.status(result.status).json({
                 success: false,
-                error: result.error
+                error: result.error,
+                code: result.code,
+                errors: result.errors
             });
         }
 
@@ -153,6 +164,48 @@ router.put('/users/:id', async (req, res) => {
     }
 });
 
+/**
+ * PATCH /api/admin/users/:id
+ * Modificar solo los campos enviados de un usuario (solo admin)
+ */
+router.patch('/users/:id', async (req, res) => {
+    try {
+        const { id } = req.params;
+        const token = req.headers.authorization?.replace('Bearer ', '');
+
+        if (!token) {
+            return res.status(401).json({
+                success: false,
+                error: 'Token de autorización requerido'
+            });
+        }
+
+        const result = await backendService.patchUser(id, req.body, patchContentType(req), token);
+
+        if (!result.success) {
+            return res.status(result.status).json({
+                success: false,
+                error: result.error,
+                code: result.code,
+                errors: result.errors
+            });
+        }
+
+        res.json({
+            success: true,
+            data: result.data,
+            message: 'Usuario actualizado exitosamente'
+        });
+
+    } catch (error) {
+        console.error('Error modificando usuario:', error);
+        res.status(500).json({
+            success: false,
+            error: 'Error interno del servidor'
+        });
+    }
+});
+
 /**
  * DELETE /api/admin/users/:id
  * Eliminar usuario (solo admin)
@@ -174,7 +227,9 @@ router.delete('/users/:id', async (req, res) => {
         if (!result.success) {
             return res.status(result.status).json({
                 success: false,
-                error: result.error
+                error: result.error,
+                code: result.code,
+                errors: result.errors
             });
         }
 
@@ -214,7 +269,9 @@ router.post('/properties', async (req, res) => {
         if (!result.success) {
             return res.status(result.status).json({
                 success: false,
-                error: result.error
+                error: result.error,
+                code: result.code,
+                errors: result.errors
             });
         }
 
@@ -236,6 +293,57 @@ router.post('/properties', async (req, res) => {
     }
 });
 
+/**
+ * POST /api/admin/properties/bulk
+ * Crear, modificar y eliminar propiedades en una sola transacción (solo admin)
+ */
+router.post('/properties/bulk', async (req, res) => {
+    try {
+        const token = req.headers.authorization?.replace('Bearer ', '');
+
+        if (!token) {
+            return res.status(401).json({
+                success: false,
+                error: 'Token de autorización requerido'
+            });
+        }
+
+        const result = await backendService.bulkProperties(req.body, token);
+
+        if (!result.success) {
+            return res.status(result.status).json({
+                success: false,
+                error: result.error,
+                code: result.code,
+                errors: result.errors,
+                data: result.report && {
+                    mode: result.report.mode,
+                    committed: result.report.committed,
+                    total: result.report.total,
+                    succeeded: result.report.succeeded,
+                    failed: result.report.failed,
+                    results: result.report.results
+                }
+            });
+        }
+
+        // Limpiar caché de propiedades
+        await cacheService.deletePattern('properties:*');
+
+        res.json({
+            success: result.data.success,
+            data: result.data.data
+        });
+
+    } catch (error) {
+        console.error('Error en operación masiva de propiedades:', error);
+        res.status(500).json({
+            success: false,
+            error: 'Error interno del servidor'
+        });
+    }
+});
+
 /**
  * PUT /api/admin/properties/:id
  * Actualizar propiedad (solo admin)
@@ -253,18 +361,23 @@ router.put('/properties/:id', async (req, res) => {
             });
         }
 
-        const result = await backendService.updateProperty(id, propertyData, token);
+        const result = await backendService.updateProperty(id, propertyData, token, req.get('If-Match'));
 
         if (!result.success) {
             return res.status(result.status).json({
                 success: false,
-                error: result.error
+                error: result.error,
+                code: result.code,
+                errors: result.errors
             });
         }
 
         // Limpiar caché de propiedades
         await cacheService.deletePattern('properties:*');
 
+        if (result.etag) {
+            res.set('ETag', result.etag);
+        }
         res.json({
             success: true,
             data: result.data,
@@ -280,6 +393,54 @@ router.put('/properties/:id', async (req, res) => {
     }
 });
 
+/**
+ * PATCH /api/admin/properties/:id
+ * Modificar solo los campos enviados de una propiedad (solo admin)
+ */
+router.patch('/properties/:id', async (req, res) => {
+    try {
+        const { id } = req.params;
+        const token = req.headers.authorization?.replace('Bearer ', '');
+
+        if (!token) {
+            return res.status(401).json({
+                success: false,
+                error: 'Token de autorización requerido'
+            });
+        }
+
+        const result = await backendService.patchProperty(id, req.body, patchContentType(req), token, req.get('If-Match'));
+
+        if (!result.success) {
+            return res.status(result.status).json({
+                success: false,
+                error: result.error,
+                code: result.code,
+                errors: result.errors
+            });
+        }
+
+        // Limpiar caché de propiedades
+        await cacheService.deletePattern('properties:*');
+
+        if (result.etag) {
+            res.set('ETag', result.etag);
+        }
+        res.json({
+            success: true,
+            data: result.data,
+            message: 'Propiedad actualizada exitosamente'
+        });
+
+    } catch (error) {
+        console.error('Error modificando propiedad:', error);
+        res.status(500).json({
+            success: false,
+            error: 'Error interno del servidor'
+        });
+    }
+});
+
 /**
  * DELETE /api/admin/properties/:id
  * Eliminar propiedad (solo admin)
@@ -296,12 +457,14 @@ router.delete('/properties/:id', async (req, res) => {
             });
         }
 
-        const result = await backendService.deleteProperty(id, token);
+        const result = await backendService.deleteProperty(id, token, req.get('If-Match'));
 
         if (!result.success) {
             return res.status(result.status).json({
                 success: false,
-                error: result.error
+                error: result.error,
+                code: result.code,
+                errors: result.errors
             });
         }
 
@@ -323,4 +486,259 @@ router.delete('/properties/:id', async (req, res) => {
     }
 });
 
+/**
+ * GET /api/admin/users/trash
+ * Obtener usuarios eliminados (solo admin)
+ */
+router.get('/users/trash', async (req, res) => {
+    try {
+        const token = req.headers.authorization?.replace('Bearer ', '');
+
+        if (!token) {
+            return res.status(401).json({
+                success: false,
+                error: 'Token de autorización requerido'
+            });
+        }
+
+        const result = await backendService.getDeletedUsers(req.query, token);
+
+        if (!result.success) {
+            return res.status(result.status).json({
+                success: false,
+                error: result.error,
+                code: result.code,
+                errors: result.errors
+            });
+        }
+
+        res.json({
+            success: true,
+            data: result.data,
+            message: 'Usuarios eliminados obtenidos exitosamente'
+        });
+
+    } catch (error) {
+        console.error('Error obteniendo usuarios eliminados:', error);
+        res.status(500).json({
+            success: false,
+            error: 'Error interno del servidor'
+        });
+    }
+});
+
+/**
+ * POST /api/admin/users/:id/restore
+ * Restaurar usuario eliminado (solo admin)
+ */
+router.post('/users/:id/restore', async (req, res) => {
+    try {
+        const { id } = req.params;
+        const token = req.headers.authorization?.replace('Bearer ', '');
+
+        if (!token) {
+            return res.status(401).json({
+                success: false,
+                error: 'Token de autorización requerido'
+            });
+        }
+
+        const result = await backendService.restoreUser(id, token);
+
+        if (!result.success) {
+            return res.status(result.status).json({
+                success: false,
+                error: result.error,
+                code: result.code,
+                errors: result.errors
+            });
+        }
+
+        res.json({
+            success: true,
+            data: result.data,
+            message: 'Usuario restaurado exitosamente'
+        });
+
+    } catch (error) {
+        console.error('Error restaurando usuario:', error);
+        res.status(500).json({
+            success: false,
+            error: 'Error interno del servidor'
+        });
+    }
+});
+
+/**
+ * GET /api/admin/properties/trash
+ * Obtener propiedades eliminadas (solo admin)
+ */
+router.get('/properties/trash', async (req, res) => {
+    try {
+        const token = req.headers.authorization?.replace('Bearer ', '');
+
+        if (!token) {
+            return res.status(401).json({
+                success: false,
+                error: 'Token de autorización requerido'
+            });
+        }
+
+        const result = await backendService.getDeletedProperties(req.query, token);
+
+        if (!result.success) {
+            return res.status(result.status).json({
+                success: false,
+                error: result.error,
+                code: result.code,
+                errors: result.errors
+            });
+        }
+
+        res.json({
+            success: true,
+            data: result.data,
+            message: 'Propiedades eliminadas obtenidas exitosamente'
+        });
+
+    } catch (error) {
+        console.error('Error obteniendo propiedades eliminadas:', error);
+        res.status(500).json({
+            success: false,
+            error: 'Error interno del servidor'
+        });
+    }
+});
+
+/**
+ * GET /api/admin/properties/duplicates
+ * Direcciones normalizadas repetidas dentro de una ciudad (solo admin)
+ */
+router.get('/properties/duplicates', async (req, res) => {
+    try {
+        const token = req.headers.authorization?.replace('Bearer ', '');
+
+        if (!token) {
+            return res.status(401).json({
+                success: false,
+                error: 'Token de autorización requerido'
+            });
+        }
+
+        const result = await backendService.getAddressDuplicates(req.query, token);
+
+        if (!result.success) {
+            return res.status(result.status).json({
+                success: false,
+                error: result.error,
+                code: result.code,
+                errors: result.errors
+            });
+        }
+
+        res.json({
+            success: true,
+            data: result.data,
+            message: 'Direcciones duplicadas obtenidas exitosamente'
+        });
+
+    } catch (error) {
+        console.error('Error obteniendo direcciones duplicadas:', error);
+        res.status(500).json({
+            success: false,
+            error: 'Error interno del servidor'
+        });
+    }
+});
+
+/**
+ * GET /api/admin/properties/:id
+ * Propiedad sin caché con su ETag, para editarla con If-Match (solo admin).
+ * Va después de /properties/trash y /properties/duplicates para no capturar esas rutas
+ */
+router.get('/properties/:id', async (req, res) => {
+    try {
+        const { id } = req.params;
+        const token = req.headers.authorization?.replace('Bearer ', '');
+
+        if (!token) {
+            return res.status(401).json({
+                success: false,
+                error: 'Token de autorización requerido'
+            });
+        }
+
+        const result = await backendService.getPropertyById(id, token);
+
+        if (!result.success) {
+            return res.status(result.status).json({
+                success: false,
+                error: result.error,
+                code: result.code,
+                errors: result.errors
+            });
+        }
+
+        if (result.etag) {
+            res.set('ETag', result.etag);
+        }
+        res.json({
+            success: true,
+            data: result.data.data
+        });
+
+    } catch (error) {
+        console.error('Error obteniendo propiedad:', error);
+        res.status(500).json({
+            success: false,
+            error: 'Error interno del servidor'
+        });
+    }
+});
+
+/**
+ * POST /api/admin/properties/:id/restore
+ * Restaurar propiedad eliminada (solo admin)
+ */
+router.post('/properties/:id/restore', async (req, res) => {
+    try {
+        const { id } = req.params;
+        const token = req.headers.authorization?.replace('Bearer ', '');
+
+        if (!token) {
+            return res.status(401).json({
+                success: false,
+                error: 'Token de autorización requerido'
+            });
+        }
+
+        const result = await backendService.restoreProperty(id, token);
+
+        if (!result.success) {
+            return res.status(result.status).json({
+                success: false,
+                error: result.error,
+                code: result.code,
+                errors: result.errors
+            });
+        }
+
+        // Limpiar caché de propiedades
+        await cacheService.deletePattern('properties:*');
+
+        res.json({
+            success: true,
+            data: result.data,
+            message: 'Propiedad restaurada exitosamente'
+        });
+
+    } catch (error) {
+        console.error('Error restaurando propiedad:', error);
+        res.status(500).json({
+            success: false,
+            error: 'Error interno del servidor'
+        });
+    }
+});
+
 module.exports = router; 
\ No newline at end of file
@@ -32,7 +32,9 @@ router.post('/login', async (req, res) => {
         if (!result.success) {
             return res.status(result.status).json({
                 success: false,
-                error: result.error
+                error: result.error,
+                code: result.code,
+                errors: result.errors
             });
         }
 
@@ -85,7 +87,9 @@ router.post('/register', async (req, res) => {
         if (!result.success) {
             return res.status(result.status).json({
                 success: false,
-                error: result.error
+                error: result.error,
+                code: result.code,
+                errors: result.errors
             });
         }
 
@@ -125,7 +129,9 @@ router.get('/profile', async (req, res) => {
         if (!result.success) {
             return res.status(result.status).json({
                 success: false,
-                error: result.error
+                error: result.error,
+                code: result.code,
+                errors: result.errors
             });
         }
 
@@ -174,7 +180,9 @@ router.put('/profile', async (req, res) => {
         if (!result.success) {
             return res.status(result.status).json({
                 success: false,
-                error: result.error
+                error: result.error,
+                code: result.code,
+                errors: result.errors
             });
         }
 
@@ -198,18 +206,74 @@ router.put('/profile', async (req, res) => {
     }
 });
 
+/**
+ * POST /api/auth/refresh
+ * Renovar tokens con un refresh token
+ */
+router.post('/refresh', async (req, res) => {
+    try {
+        const { refresh_token: refreshToken } = req.body;
+
+        if (!refreshToken) {
+            return res.status(400).json({
+                success: false,
+                error: 'refresh_token es requerido'
+            });
+        }
+
+        // Llamar al backend
+        const result = await backendService.refreshToken(refreshToken);
+
+        if (!result.success) {
+            return res.status(result.status).json({
+                success: false,
+                error: result.error,
+                code: result.code,
+                errors: result.errors
+            });
+        }
+
+        res.json({
+            success: true,
+            data: result.data,
+            message: 'Tokens renovados exitosamente'
+        });
+
+    } catch (error) {
+        console.error('Error renovando tokens:', error);
+        res.status(500).json({
+            success: false,
+            error: 'Error interno del servidor'
+        });
+    }
+});
+
 /**
  * POST /api/auth/logout
- * Cerrar sesión (invalidar token)
+ * Cerrar sesión (revoca la sesión en el backend)
  */
 router.post('/logout', async (req, res) => {
     try {
         const token = req.headers.authorization?.replace('Bearer ', '');
+        const refreshToken = req.body?.refresh_token;
+
+        if (!token && !refreshToken) {
+            return res.status(401).json({
+                success: false,
+                error: 'Token de autenticación requerido'
+            });
+        }
+
+        // Revocar la sesión en el backend
+        const result = await backendService.logout(token, refreshToken);
 
-        if (token) {
-            // Agregar token a lista negra (implementación básica)
-            const blacklistKey = `blacklist:${token}`;
-            await cacheService.setCache(blacklistKey, true, 86400); // 24 horas
+        if (!result.success) {
+            return res.status(result.status).json({
+                success: false,
+                error: result.error,
+                code: result.code,
+                errors: result.errors
+            });
         }
 
         res.json({
@@ -226,6 +290,48 @@ router.post('/logout', async (req, res) => {
     }
 });
 
+/**
+ * POST /api/auth/logout-all
+ * Cerrar sesión en todos los dispositivos
+ */
+router.post('/logout-all', async (req, res) => {
+    try {
+        const token = req.headers.authorization?.replace('Bearer ', '');
+
+        if (!token) {
+            return res.status(401).json({
+                success: false,
+                error: 'Token de autenticación requerido'
+            });
+        }
+
+        // Llamar al backend
+        const result = await backendService.logoutAll(token);
+
+        if (!result.success) {
+            return res.status(result.status).json({
+                success: false,
+                error: result.error,
+                code: result.code,
+                errors: result.errors
+            });
+        }
+
+        res.json({
+            success: true,
+            data: result.data,
+            message: 'Sesiones cerradas en todos los dispositivos'
+        });
+
+    } catch (error) {
+        console.error('Error en logout-all:', error);
+        res.status(500).json({
+            success: false,
+            error: 'Error interno del servidor'
+        });
+    }
+});
+
 /**
  * GET /api/auth/validate
  * Validar token de autenticación
@@ -258,7 +364,9 @@ router.get('/validate', async (req, res) => {
         if (!result.success) {
             return res.status(result.status).json({
                 success: false,
-                error: result.error
+                error: result.error,
+                code: result.code,
+                errors: result.errors
             });
         }
 
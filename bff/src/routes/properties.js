@@ -18,6 +18,7 @@ const axios = require('axios');
 router.get('/', async (req, res) => {
   try {
     const filters = {
+      q: req.query.q,
       town: req.query.town,
       minPrice: req.query.min_price,
       maxPrice: req.query.max_price,
@@ -36,13 +37,15 @@ router.get('/', async (req, res) => {
       limit: parseInt(req.query.limit) || 10
     };
 
+    // Con búsqueda por texto el backend ordena por relevancia si no se pide otro orden
     const sorting = {
-      sortBy: req.query.sort_by || 'serial_number',
-      sortOrder: req.query.sort_order || 'asc'
+      sortBy: req.query.sort_by || (filters.q ? undefined : 'serial_number'),
+      sortOrder: req.query.sort_order || (filters.q ? undefined : 'asc')
     };
 
     // Mapeo explícito de nombres de parámetros para el backend Go
     const backendParams = {
+      q: filters.q,
       town: filters.town,
       min_price: filters.minPrice,
       max_price: filters.maxPrice,
@@ -87,6 +90,80 @@ router.get('/', async (req, res) => {
   }
 });
 
+/**
+ * GET /api/properties/suggest
+ * Autocompletado de direcciones y ciudades
+ * Va antes de /:id para no capturar esa ruta
+ */
+router.get('/suggest', async (req, res) => {
+  try {
+    const backendParams = { q: req.query.q || '' };
+    if (req.query.limit !== undefined) {
+      backendParams.limit = req.query.limit;
+    }
+
+    const queryString = new URLSearchParams(backendParams).toString();
+    const backendResponse = await axios.get(`http://urbanytics_backend:8080/api/v1/properties/suggest?${queryString}`);
+
+    res.json({
+      success: true,
+      data: backendResponse.data.data,
+      timestamp: new Date().toISOString()
+    });
+  } catch (error) {
+    if (error.response && error.response.status === 400) {
+      return res.status(400).json({
+        success: false,
+        error: error.response.data.detail || 'Parámetros de búsqueda inválidos'
+      });
+    }
+
+    console.error('Error obteniendo sugerencias:', error);
+    res.status(500).json({
+      success: false,
+      error: 'Error interno del servidor',
+      message: error.message
+    });
+  }
+});
+
+/**
+ * GET /api/properties/lookup
+ * Propiedades con la misma dirección normalizada que address (y town si se indica)
+ * Va antes de /:id para no capturar esa ruta
+ */
+router.get('/lookup', async (req, res) => {
+  try {
+    const backendParams = { address: req.query.address || '' };
+    if (req.query.town) {
+      backendParams.town = req.query.town;
+    }
+
+    const queryString = new URLSearchParams(backendParams).toString();
+    const backendResponse = await axios.get(`http://urbanytics_backend:8080/api/v1/properties/lookup?${queryString}`);
+
+    res.json({
+      success: true,
+      data: backendResponse.data.data,
+      timestamp: new Date().toISOString()
+    });
+  } catch (error) {
+    if (error.response && error.response.status === 400) {
+      return res.status(400).json({
+        success: false,
+        error: error.response.data.detail || 'Dirección inválida'
+      });
+    }
+
+    console.error('Error buscando dirección:', error);
+    res.status(500).json({
+      success: false,
+      error: 'Error interno del servidor',
+      message: error.message
+    });
+  }
+});
+
 /**
  * GET /api/properties/:id
  * Obtiene una propiedad específica por ID
@@ -5,6 +5,19 @@
 
 const axios = require('axios');
 
+/**
+ * Extraer el error de una respuesta problem+json del backend
+ * (detail, código estable y errores por campo)
+ */
+function backendError(error) {
+    const problem = error.response?.data || {};
+    return {
+        error: problem.detail || problem.error || error.message,
+        code: problem.code,
+        errors: problem.errors
+    };
+}
+
 class BackendService {
     constructor() {
         this.baseURL = process.env.BACKEND_URL || 'http://urbanytics_backend:8080';
@@ -90,7 +103,7 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -107,7 +120,67 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
+                status: error.response?.status || 500
+            };
+        }
+    }
+
+    async refreshToken(refreshToken) {
+        try {
+            const response = await this.client.post('/api/v1/auth/refresh', { refresh_token: refreshToken });
+            return {
+                success: true,
+                data: response.data,
+                status: response.status
+            };
+        } catch (error) {
+            return {
+                success: false,
+                ...backendError(error),
+                status: error.response?.status || 500
+            };
+        }
+    }
+
+    async logout(token, refreshToken = null) {
+        try {
+            const response = await this.authenticatedRequest({
+                method: 'POST',
+                url: '/api/v1/auth/logout',
+                data: refreshToken ? { refresh_token: refreshToken } : undefined
+            }, token);
+
+            return {
+                success: true,
+                data: response.data,
+                status: response.status
+            };
+        } catch (error) {
+            return {
+                success: false,
+                ...backendError(error),
+                status: error.response?.status || 500
+            };
+        }
+    }
+
+    async logoutAll(token) {
+        try {
+            const response = await this.authenticatedRequest({
+                method: 'POST',
+                url: '/api/v1/auth/logout-all'
+            }, token);
+
+            return {
+                success: true,
+                data: response.data,
+                status: response.status
+            };
+        } catch (error) {
+            return {
+                success: false,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -132,7 +205,7 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -148,12 +221,13 @@ class BackendService {
             return {
                 success: true,
                 data: response.data,
+                etag: response.headers.etag,
                 status: response.status
             };
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -175,39 +249,103 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
     }
 
-    async updateProperty(id, propertyData, token) {
+    /**
+     * ifMatch es el ETag leído al abrir la propiedad; el backend responde 412
+     * si otra petición la modificó entretanto
+     */
+    async updateProperty(id, propertyData, token, ifMatch) {
         try {
             const response = await this.authenticatedRequest({
                 method: 'PUT',
                 url: `/api/v1/admin/properties/${id}`,
-                data: propertyData
+                data: propertyData,
+                headers: ifMatch ? { 'If-Match': ifMatch } : {}
             }, token);
             
             return {
                 success: true,
                 data: response.data,
+                etag: response.headers.etag,
                 status: response.status
             };
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
     }
 
-    async deleteProperty(id, token) {
+    /**
+     * Operaciones masivas sobre propiedades en una transacción (admin)
+     */
+    async bulkProperties(request, token) {
+        try {
+            const response = await this.authenticatedRequest({
+                method: 'POST',
+                url: '/api/v1/admin/properties/bulk',
+                data: request,
+                // Miles de filas pueden tardar más que el timeout general
+                timeout: 10 * 60 * 1000
+            }, token);
+
+            return {
+                success: true,
+                data: response.data,
+                status: response.status
+            };
+        } catch (error) {
+            // Con mode atomic el problem+json incluye el resultado de cada fila
+            return {
+                success: false,
+                ...backendError(error),
+                report: error.response?.data?.results && error.response.data,
+                status: error.response?.status || 500
+            };
+        }
+    }
+
+    /**
+     * Modificar campos sueltos; contentType es application/merge-patch+json
+     * o application/json-patch+json
+     */
+    async patchProperty(id, patch, contentType, token, ifMatch) {
+        try {
+            const response = await this.authenticatedRequest({
+                method: 'PATCH',
+                url: `/api/v1/admin/properties/${id}`,
+                data: patch,
+                headers: { 'Content-Type': contentType, ...(ifMatch && { 'If-Match': ifMatch }) }
+            }, token);
+
+            return {
+                success: true,
+                data: response.data,
+                etag: response.headers.etag,
+                status: response.status
+            };
+        } catch (error) {
+            return {
+                success: false,
+                ...backendError(error),
+                status: error.response?.status || 500
+            };
+        }
+    }
+
+    async deleteProperty(id, token, ifMatch) {
         try {
             const response = await this.authenticatedRequest({
                 method: 'DELETE',
-                url: `/api/v1/admin/properties/${id}`
+                url: `/api/v1/admin/properties/${id}`,
+                headers: ifMatch ? { 'If-Match': ifMatch } : {}
             }, token);
             
             return {
@@ -218,7 +356,7 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -242,7 +380,7 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -263,7 +401,7 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -284,7 +422,7 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -305,7 +443,7 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -329,7 +467,7 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -350,7 +488,7 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -371,7 +509,7 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -392,7 +530,7 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -413,7 +551,7 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -434,7 +572,7 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -455,7 +593,7 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -479,7 +617,7 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -501,7 +639,7 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -525,7 +663,7 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -547,7 +685,7 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -569,7 +707,34 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
+                status: error.response?.status || 500
+            };
+        }
+    }
+
+    /**
+     * Modificar campos sueltos; contentType es application/merge-patch+json
+     * o application/json-patch+json
+     */
+    async patchUser(id, patch, contentType, token) {
+        try {
+            const response = await this.authenticatedRequest({
+                method: 'PATCH',
+                url: `/api/v1/admin/users/${id}`,
+                data: patch,
+                headers: { 'Content-Type': contentType }
+            }, token);
+
+            return {
+                success: true,
+                data: response.data,
+                status: response.status
+            };
+        } catch (error) {
+            return {
+                success: false,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -590,7 +755,115 @@ class BackendService {
         } catch (error) {
             return {
                 success: false,
-                error: error.response?.data?.error || error.message,
+                ...backendError(error),
+                status: error.response?.status || 500
+            };
+        }
+    }
+
+    async getDeletedUsers(params = {}, token) {
+        try {
+            const response = await this.authenticatedRequest({
+                method: 'GET',
+                url: '/api/v1/admin/users/trash',
+                params
+            }, token);
+            
+            return {
+                success: true,
+                data: response.data,
+                status: response.status
+            };
+        } catch (error) {
+            return {
+                success: false,
+                ...backendError(error),
+                status: error.response?.status || 500
+            };
+        }
+    }
+
+    async restoreUser(id, token) {
+        try {
+            const response = await this.authenticatedRequest({
+                method: 'POST',
+                url: `/api/v1/admin/users/${id}/restore`
+            }, token);
+            
+            return {
+                success: true,
+                data: response.data,
+                status: response.status
+            };
+        } catch (error) {
+            return {
+                success: false,
+                ...backendError(error),
+                status: error.response?.status || 500
+            };
+        }
+    }
+
+    async getDeletedProperties(params = {}, token) {
+        try {
+            const response = await this.authenticatedRequest({
+                method: 'GET',
+                url: '/api/v1/admin/properties/trash',
+                params
+            }, token);
+            
+            return {
+                success: true,
+                data: response.data,
+                status: response.status
+            };
+        } catch (error) {
+            return {
+                success: false,
+                ...backendError(error),
+                status: error.response?.status || 500
+            };
+        }
+    }
+
+    async getAddressDuplicates(params = {}, token) {
+        try {
+            const response = await this.authenticatedRequest({
+                method: 'GET',
+                url: '/api/v1/admin/properties/duplicates',
+                params
+            }, token);
+
+            return {
+                success: true,
+                data: response.data,
+                status: response.status
+            };
+        } catch (error) {
+            return {
+                success: false,
+                ...backendError(error),
+                status: error.response?.status || 500
+            };
+        }
+    }
+
+    async restoreProperty(id, token) {
+        try {
+            const response = await this.authenticatedRequest({
+                method: 'POST',
+                url: `/api/v1/admin/properties/${id}/restore`
+            }, token);
+            
+            return {
+                success: true,
+                data: response.data,
+                status: response.status
+            };
+        } catch (error) {
+            return {
+                success: false,
+                ...backendError(error),
                 status: error.response?.status || 500
             };
         }
@@ -69,6 +69,7 @@ const AdminDashboard: React.FC = () => {
   const [isEditing, setIsEditing] = useState(false);
   const [isAdding, setIsAdding] = useState(false);
   const [editingProperty, setEditingProperty] = useState<Property | null>(null);
+  const [editingETag, setEditingETag] = useState<string | null>(null);
   const [editingUser, setEditingUser] = useState<User | null>(null);
   
   // Estados para filtros
@@ -291,8 +292,18 @@ const AdminDashboard: React.FC = () => {
     setIsEditing(false);
   };
 
-  const handleEditProperty = (property: Property) => {
-    setEditingProperty(property);
+  const handleEditProperty = async (property: Property) => {
+    // El formulario se abre con la versión actual y su ETag, que se envía al
+    // guardar para detectar cambios de otro administrador
+    let current = property;
+    let etag: string | null = null;
+    try {
+      ({ data: current, etag } = await apiService.getPropertyForEdit(property.serial_number));
+    } catch {
+      // Sin ETag se guarda sin comprobar la versión
+    }
+    setEditingProperty(current);
+    setEditingETag(etag);
     setIsEditing(true);
     setIsAdding(false);
   };
@@ -318,7 +329,7 @@ const AdminDashboard: React.FC = () => {
       if (isAdding) {
         await apiService.createProperty(propertyData);
       } else if (editingProperty) {
-        await apiService.updateProperty(editingProperty.serial_number, propertyData);
+        await apiService.updateProperty(editingProperty.serial_number, propertyData, editingETag);
       }
       
       setIsAdding(false);
@@ -329,6 +340,10 @@ const AdminDashboard: React.FC = () => {
       await loadKPIs();
     } catch (err) {
       console.error('Error saving property:', err);
+      if (err instanceof Error && err.message.includes('status: 412')) {
+        setError('Otro administrador modificó esta propiedad. Vuelve a abrirla para ver los cambios.');
+        return;
+      }
       setError('Error al guardar la propiedad. Verifica que todos los campos sean válidos.');
     }
   };
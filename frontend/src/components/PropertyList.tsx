@@ -30,6 +30,7 @@ function PropertyList() {
 
   // Estados para los filtros de búsqueda
   const [filters, setFilters] = useState({
+    q: '',
     town: '',
     min_price: '',
     max_price: '',
@@ -48,6 +49,9 @@ function PropertyList() {
   const [residentialTypes, setResidentialTypes] = useState<string[]>([]);
   const [listYears, setListYears] = useState<number[]>([]);
 
+  // Sugerencias de direcciones y ciudades para la búsqueda por texto
+  const [suggestions, setSuggestions] = useState<{ type: string; value: string; town?: string }[]>([]);
+
   // Estado para mostrar/ocultar filtros en móvil
   const [showFilters, setShowFilters] = useState(false);
 
@@ -77,8 +81,10 @@ function PropertyList() {
       const params = {
         page,
         limit: 12,
-        sort_by: sortBy,
-        sort_order: sortOrder,
+        // Con búsqueda por texto y el orden por defecto, el backend ordena por relevancia
+        sort_by: searchFilters.q && sortBy === 'serial_number' ? '' : sortBy,
+        sort_order: searchFilters.q && sortBy === 'serial_number' ? '' : sortOrder,
+        q: searchFilters.q.trim(),
         town: searchFilters.town,
         min_price: searchFilters.min_price,
         max_price: searchFilters.max_price,
@@ -114,6 +120,24 @@ function PropertyList() {
     fetchProperties(currentPage, filters);
   }, [currentPage, filters, fetchProperties]);
 
+  // Pedir sugerencias mientras se escribe, con una pequeña espera entre teclas
+  useEffect(() => {
+    const q = filters.q.trim();
+    if (q.length < 2) {
+      setSuggestions([]);
+      return;
+    }
+    const timer = setTimeout(async () => {
+      try {
+        const result = await apiService.getPropertySuggestions(q);
+        setSuggestions(result.data || []);
+      } catch (err) {
+        console.error('Error cargando sugerencias:', err);
+      }
+    }, 250);
+    return () => clearTimeout(timer);
+  }, [filters.q]);
+
   // Función para manejar cambios en los filtros
   const handleFilterChange = (e: React.ChangeEvent<HTMLInputElement | HTMLSelectElement>) => {
     const { name, value } = e.target;
@@ -140,6 +164,7 @@ function PropertyList() {
   // Función para limpiar filtros
   const handleClearFilters = () => {
     const clearedFilters = {
+      q: '',
       town: '',
       min_price: '',
       max_price: '',
@@ -291,6 +316,43 @@ function PropertyList() {
           gap: '1rem',
           alignItems: 'end'
         }}>
+          {/* Búsqueda por dirección o ciudad */}
+          <div>
+            <label style={{
+              display: 'block',
+              marginBottom: '0.5rem',
+              color: 'var(--text-primary)',
+              fontWeight: '500'
+            }}>
+              Dirección
+            </label>
+            <input
+              type="search"
+              name="q"
+              value={filters.q}
+              onChange={handleFilterChange}
+              list="property-suggestions"
+              placeholder="Calle o ciudad"
+              maxLength={200}
+              style={{
+                width: '100%',
+                padding: '0.75rem',
+                border: '1px solid var(--border-color)',
+                borderRadius: '8px',
+                fontSize: '1rem',
+                backgroundColor: 'var(--background-color)',
+                color: 'var(--text-primary)'
+              }}
+            />
+            <datalist id="property-suggestions">
+              {suggestions.map((suggestion) => (
+                <option key={`${suggestion.type}-${suggestion.value}-${suggestion.town ?? ''}`} value={suggestion.value}>
+                  {suggestion.town ? `${suggestion.value}, ${suggestion.town}` : suggestion.value}
+                </option>
+              ))}
+            </datalist>
+          </div>
+
           {/* Filtro por ciudad */}
           <div>
             <label style={{
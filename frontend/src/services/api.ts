@@ -13,13 +13,14 @@ const apiRequest = async (endpoint: string, options: RequestInit = {}): Promise<
   // Obtener token del localStorage
   const token = localStorage.getItem('token');
   
+  // Las cabeceras propias de la petición se suman a las comunes, no las reemplazan
   const config: RequestInit = {
+    ...options,
     headers: {
       'Content-Type': 'application/json',
       ...(token && { 'Authorization': `Bearer ${token}` }),
       ...options.headers,
     },
-    ...options,
   };
 
   try {
@@ -119,6 +120,12 @@ export const apiService = {
     return apiRequest(`/properties/${id}`);
   },
   
+  // Autocompletado de direcciones y ciudades
+  getPropertySuggestions: (q: string, limit: number = 8) => {
+    const queryString = new URLSearchParams({ q, limit: String(limit) }).toString();
+    return apiRequest(`/properties/suggest?${queryString}`);
+  },
+
   getPropertyFilters: () => {
     return apiRequest('/properties/filters/all');
   },
@@ -209,10 +216,25 @@ export const apiService = {
     });
   },
 
-  updateProperty: (id: string | number, propertyData: any) => {
+  // Propiedad con su ETag; se envía como If-Match al guardar
+  getPropertyForEdit: async (id: string | number): Promise<{ data: any; etag: string | null }> => {
+    const token = localStorage.getItem('token');
+    const response = await fetch(`${API_BASE_URL}/admin/properties/${id}`, {
+      headers: token ? { 'Authorization': `Bearer ${token}` } : {},
+    });
+    if (!response.ok) {
+      throw new Error(`HTTP error! status: ${response.status}`);
+    }
+    const body = await response.json();
+    return { data: body.data, etag: response.headers.get('ETag') };
+  },
+
+  // Con etag el backend responde 412 si otro administrador la modificó entretanto
+  updateProperty: (id: string | number, propertyData: any, etag?: string | null) => {
     return apiRequest(`/admin/properties/${id}`, {
       method: 'PUT',
       body: JSON.stringify(propertyData),
+      headers: etag ? { 'If-Match': etag } : {},
     });
   },
 